@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yenc.v0"
+)
+
+func decode(args []string, stdout, stderr io.Writer) (err error) {
+	fs := newFlagSet("decode", "file.ntx...", stderr)
+	out := fs.String("o", ".", "output directory")
+	if err = parseFlags(fs, args, 1); err != nil {
+		return
+	}
+	var files []*partFile
+	if files, err = scanParts(fs.Args()); err != nil {
+		return
+	}
+	failed := false
+	for _, f := range files {
+		var ok bool
+		if ok, err = decodeFile(f, *out, stdout); err != nil {
+			return
+		}
+		if !ok {
+			failed = true
+		}
+	}
+	if failed {
+		err = errFailed
+	}
+	return
+}
+
+// Join the parts of a file into the output directory under its sanitized name, each at its offset. Parts failing to
+// decode are written as far as they go. Reports the result to w, and only returns output errors.
+func decodeFile(f *partFile, dir string, w io.Writer) (ok bool, err error) {
+	name, _ := yenc.SanitizeName(f.name)
+	path := filepath.Join(dir, name)
+	var out *os.File
+	if out, err = os.Create(path); err != nil {
+		return
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	if err = out.Truncate(int64(f.size)); err != nil {
+		return
+	}
+	var (
+		j   *yenc.Joiner
+		crc uint32
+	)
+	if j, crc, err = joinParts(f, out, w); err != nil {
+		return
+	}
+	ok = report(w, f, path, j, crc)
+	return
+}
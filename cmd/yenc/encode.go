@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yenc.v0"
+)
+
+// Default size of the data of each part, as posted by most posting tools.
+const defaultPartSize = 700 * 1024
+
+func encode(args []string, stdout, stderr io.Writer) (err error) {
+	fs := newFlagSet("encode", "file", stderr)
+	var (
+		out      = fs.String("o", ".", "output directory")
+		name     = fs.String("name", "", "name written in the =ybegin line (default the base name of the file)")
+		partSize = fs.Int64("part-size", defaultPartSize, "size of the data of each part in bytes")
+		line     = fs.Uint64("line", yenc.LineLimit, "max length of the encoded lines")
+		eol      = fs.String("eol", "crlf", "line ending, crlf or lf")
+		escape   = fs.String("escape", "default", "characters escaped, default (NUL, CR, LF and =) or extended (also TAB and .)")
+	)
+	if err = parseFlags(fs, args, 1); err != nil {
+		return
+	}
+	options := []yenc.EncodeOption{yenc.EncodeWithLineMax(*line), yenc.EncodeWithPartCrc32ForLastPart()}
+	switch *eol {
+	case "crlf":
+		options = append(options, yenc.EncodeWithEOL("\r\n"))
+	case "lf":
+		options = append(options, yenc.EncodeWithLF())
+	default:
+		err = fmt.Errorf("invalid -eol %s", *eol)
+		return
+	}
+	switch *escape {
+	case "default":
+		options = append(options, yenc.EncodeWithCriticalChars(yenc.DefaultCriticalChars))
+	case "extended":
+		options = append(options, yenc.EncodeWithCriticalChars(yenc.ExtendedCriticalChars))
+	default:
+		err = fmt.Errorf("invalid -escape %s", *escape)
+		return
+	}
+	if *partSize <= 0 {
+		err = fmt.Errorf("invalid -part-size %d", *partSize)
+		return
+	}
+	if *line == 0 {
+		err = fmt.Errorf("invalid -line %d", *line)
+		return
+	}
+
+	path := fs.Arg(0)
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+	var fi os.FileInfo
+	if fi, err = f.Stat(); err != nil {
+		return
+	}
+	if *name == "" {
+		*name = filepath.Base(path)
+	}
+	size := fi.Size()
+	total := (size + *partSize - 1) / *partSize
+	if total == 0 {
+		total = 1
+	}
+	base, _ := yenc.SanitizeName(*name)
+	for part := int64(1); part <= total; part++ {
+		begin := (part - 1) * *partSize
+		end := begin + *partSize
+		if end > size {
+			end = size
+		}
+		partOptions := options
+		if total > 1 {
+			partOptions = append(partOptions[:len(partOptions):len(partOptions)], yenc.EncodeWithPart(uint64(part), uint64(total), uint64(begin), uint64(end)))
+		}
+		output := filepath.Join(*out, fmt.Sprintf("%s-%03d.ntx", base, part))
+		if err = encodePart(output, *name, uint64(size), io.NewSectionReader(f, begin, end-begin), partOptions); err != nil {
+			return
+		}
+		fmt.Fprintln(stdout, output)
+	}
+	return
+}
+
+func encodePart(output, name string, size uint64, r io.Reader, options []yenc.EncodeOption) (err error) {
+	var w *os.File
+	if w, err = os.Create(output); err != nil {
+		return
+	}
+	defer func() {
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	var e *yenc.Encoder
+	if e, err = yenc.Encode(w, name, size, options...); err != nil {
+		return
+	}
+	if _, err = io.Copy(e, r); err != nil {
+		return
+	}
+	return e.Close()
+}
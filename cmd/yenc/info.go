@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yenc.v0"
+)
+
+type partInfo struct {
+	File    string        `json:"file"`
+	Header  *yenc.Header  `json:"header,omitempty"`
+	Trailer *yenc.Trailer `json:"trailer,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+func info(args []string, stdout, stderr io.Writer) (err error) {
+	fs := newFlagSet("info", "file.ntx...", stderr)
+	asJSON := fs.Bool("json", false, "print one JSON object per file")
+	if err = parseFlags(fs, args, 1); err != nil {
+		return
+	}
+	failed := false
+	enc := json.NewEncoder(stdout)
+	for _, path := range fs.Args() {
+		i := partInfo{File: path}
+		var derr error
+		if i.Header, i.Trailer, derr = decodePart(path, io.Discard); derr != nil {
+			i.Error = derr.Error()
+			failed = true
+		}
+		if *asJSON {
+			if err = enc.Encode(i); err != nil {
+				return
+			}
+			continue
+		}
+		printInfo(stdout, &i)
+	}
+	if failed {
+		err = errFailed
+	}
+	return
+}
+
+func printInfo(w io.Writer, i *partInfo) {
+	fmt.Fprintf(w, "%s:\n", i.File)
+	if h := i.Header; h != nil {
+		fmt.Fprintf(w, "  name=%s size=%d line=%d", h.Name, h.Size, h.Line)
+		if h.RawName != h.Name {
+			fmt.Fprintf(w, " raw-name=%q", h.RawName)
+		}
+		if h.Part > 0 {
+			fmt.Fprintf(w, " part=%d total=%d begin=%d end=%d", h.Part, h.Total, h.Begin+1, h.End)
+		}
+		fmt.Fprintln(w)
+	}
+	if t := i.Trailer; t != nil {
+		fmt.Fprintf(w, "  trailer size=%d", t.Size)
+		if t.Part > 0 {
+			fmt.Fprintf(w, " part=%d", t.Part)
+		}
+		if t.Total > 0 {
+			fmt.Fprintf(w, " total=%d", t.Total)
+		}
+		if t.HasPCRC32 {
+			fmt.Fprintf(w, " pcrc32=%08x", t.PCRC32)
+		}
+		if t.HasCRC32 {
+			fmt.Fprintf(w, " crc32=%08x", t.CRC32)
+		}
+		fmt.Fprintln(w)
+	}
+	if i.Error != "" {
+		fmt.Fprintf(w, "  error: %s\n", i.Error)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yenc.v0"
+)
+
+type lintFinding struct {
+	File     string        `json:"file"`
+	Line     int           `json:"line"`
+	Severity yenc.Severity `json:"severity"`
+	Check    string        `json:"check"`
+	Message  string        `json:"message"`
+}
+
+func lint(args []string, stdout, stderr io.Writer) (err error) {
+	fs := newFlagSet("lint", "file.ntx...", stderr)
+	asJSON := fs.Bool("json", false, "print one JSON object per finding")
+	if err = parseFlags(fs, args, 1); err != nil {
+		return
+	}
+	failed := false
+	enc := json.NewEncoder(stdout)
+	for _, path := range fs.Args() {
+		var findings []yenc.Finding
+		if findings, err = lintFile(path); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		for _, f := range findings {
+			if f.Severity == yenc.SeverityError {
+				failed = true
+			}
+			if *asJSON {
+				if err = enc.Encode(lintFinding{path, f.Line, f.Severity, f.Check, f.Message}); err != nil {
+					return
+				}
+				continue
+			}
+			fmt.Fprintf(stdout, "%s:%d: %s: %s [%s]\n", path, f.Line, f.Severity, f.Message, f.Check)
+		}
+	}
+	if failed {
+		err = errFailed
+	}
+	return
+}
+
+func lintFile(path string) (findings []yenc.Finding, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+	findings = yenc.Lint(f)
+	return
+}
@@ -0,0 +1,96 @@
+// Command yenc encodes files into yEnc articles (.ntx files, one article body each), and decodes, inspects and verifies
+// them.
+//
+//	yenc encode [-o dir] [-part-size n] [-line n] [-eol crlf|lf] [-escape default|extended] file
+//	yenc decode [-o dir] file.ntx...
+//	yenc info [-json] file.ntx...
+//	yenc verify file.ntx...
+//	yenc lint [-json] file.ntx...
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type command struct {
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"encode": {"encode a file into .ntx parts", encode},
+	"decode": {"decode and join .ntx parts in any order", decode},
+	"info":   {"print the header and trailer of each part", info},
+	"lint":   {"report deviations from the yEnc specification, line by line", lint},
+	"verify": {"check the part and file CRC32 values without writing output", verify},
+}
+
+// Returned by commands that reported their failures already, to exit with status 1 without printing more.
+var errFailed = errors.New("failed")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return 2
+	}
+	c, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "yenc: unknown command %s\n", args[0])
+		printUsage(stderr)
+		return 2
+	}
+	if err := c.run(args[1:], stdout, stderr); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		if !errors.Is(err, errFailed) {
+			fmt.Fprintf(stderr, "yenc %s: %v\n", args[0], err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: yenc <command> [flags] [files]")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].usage)
+	}
+}
+
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("yenc "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: yenc %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// Parse the flags, requiring at least min arguments. Usage errors are reported by the flag set and returned as
+// flag.ErrHelp.
+func parseFlags(fs *flag.FlagSet, args []string, min int) error {
+	if err := fs.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+	if fs.NArg() < min {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yenc.v0"
+)
+
+func runCommand(t *testing.T, args ...string) (code int, stdout string) {
+	var out, errOut bytes.Buffer
+	code = run(args, &out, &errOut)
+	t.Logf("yenc %s: %d\n%s%s", strings.Join(args, " "), code, out.String(), errOut.String())
+	return code, out.String()
+}
+
+func TestEncode(t *testing.T) {
+	dir := t.TempDir()
+	if code, _ := runCommand(t, "encode", "-o", dir, "-part-size", "512", "-eol", "lf", "../../fixture/encode-raw.bin"); code != 0 {
+		t.Fatalf("encode exited with %d", code)
+	}
+	for i := 1; i <= 10; i++ {
+		b, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("encode-raw.bin-%03d.ntx", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect, err := os.ReadFile(fmt.Sprintf("../../fixture/encode-%03d.ntx", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, expect) {
+			t.Errorf("part %d differs from the fixture", i)
+		}
+	}
+	for _, flag := range [][]string{{"-eol", "cr"}, {"-line", "0"}, {"-part-size", "0"}} {
+		if code, _ := runCommand(t, "encode", "-o", dir, flag[0], flag[1], "../../fixture/encode-raw.bin"); code != 1 {
+			t.Errorf("expect %s %s to fail but exited with %d", flag[0], flag[1], code)
+		}
+	}
+	if code, _ := runCommand(t, "encode"); code != 2 {
+		t.Errorf("expect a missing argument to be a usage error but exited with %d", code)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	dir := t.TempDir()
+	// parts in any order, of several files
+	args := []string{"decode", "-o", dir, "../../fixture/JBinUp-001.ntx"}
+	for _, i := range []int{7, 3, 10, 1, 2, 9, 4, 8, 6, 5} {
+		args = append(args, fmt.Sprintf("../../fixture/yenc32-%03d.ntx", i))
+	}
+	code, out := runCommand(t, args...)
+	if code != 0 || strings.Count(out, ": OK") != 2 {
+		t.Fatalf("decode exited with %d", code)
+	}
+	for _, name := range []string{"JBinUp-raw.bin", "yenc32-raw.bin"} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect, err := os.ReadFile("../../fixture/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, expect) {
+			t.Errorf("%s differs from the fixture", name)
+		}
+	}
+	// a missing part
+	code, out = runCommand(t, "decode", "-o", dir, "../../fixture/ngPost-001.ntx", "../../fixture/ngPost-003.ntx")
+	if code != 1 || !strings.Contains(out, "[{512 1024} {1536 4682}]") {
+		t.Errorf("expect the missing ranges to be reported but exited with %d", code)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	code, out := runCommand(t, "verify", "../../fixture/yenc32-001.ntx", "../../fixture/yenc32-002.ntx", "../../fixture/yenc32-003.ntx",
+		"../../fixture/yenc32-004.ntx", "../../fixture/yenc32-005.ntx", "../../fixture/yenc32-006.ntx", "../../fixture/yenc32-007.ntx",
+		"../../fixture/yenc32-008.ntx", "../../fixture/yenc32-009.ntx", "../../fixture/yenc32-010.ntx")
+	if code != 0 || !strings.Contains(out, "yenc32-raw.bin: OK, 10 parts, 4682 bytes, crc32 5b0acdc1\n") {
+		t.Errorf("unexpected verify result %d", code)
+	}
+	// corrupt data of the single part, detected by its CRC32
+	b, err := os.ReadFile("../../fixture/JBinUp-001.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := bytes.IndexByte(b, '\n')
+	i += bytes.IndexByte(b[i+1:], '\n') + 10
+	b[i]++
+	path := filepath.Join(t.TempDir(), "JBinUp-001.ntx")
+	if err = os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, out = runCommand(t, "verify", path); code != 1 || !strings.Contains(out, "FAILED") {
+		t.Errorf("expect corruption to be detected but exited with %d", code)
+	}
+	// intact parts, but a file CRC32 that does not match them
+	args := []string{"verify"}
+	for i := 1; i <= 10; i++ {
+		args = append(args, fmt.Sprintf("../../fixture/yenc32-%03d.ntx", i))
+	}
+	if b, err = os.ReadFile(args[10]); err != nil {
+		t.Fatal(err)
+	}
+	args[10] = filepath.Join(t.TempDir(), "yenc32-010.ntx")
+	if err = os.WriteFile(args[10], bytes.Replace(b, []byte("crc32=5B0ACDC1"), []byte("crc32=00000000"), 1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, out = runCommand(t, args...); code != 1 || !strings.Contains(out, "has CRC32 5b0acdc1 but the trailer says 00000000") {
+		t.Errorf("expect the file CRC32 mismatch to be detected but exited with %d", code)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	code, out := runCommand(t, "info", "-json", "../../fixture/yenc32-010.ntx", "../../fixture/missing.ntx")
+	if code != 1 {
+		t.Errorf("expect a missing file to fail but exited with %d", code)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expect 2 JSON lines but got %d", len(lines))
+	}
+	var i partInfo
+	if err := json.Unmarshal([]byte(lines[0]), &i); err != nil {
+		t.Fatal(err)
+	}
+	if i.Header == nil || i.Header.Part != 10 || i.Trailer == nil || !i.Trailer.HasCRC32 || i.Trailer.CRC32 != 0x5b0acdc1 {
+		t.Errorf("unexpected info %+v", i)
+	}
+	if code, out = runCommand(t, "info", "../../fixture/ngPost-002.ntx"); code != 0 || !strings.Contains(out, "pcrc32=6029ca7b") {
+		t.Errorf("unexpected info exit code %d", code)
+	}
+}
+
+func TestLint(t *testing.T) {
+	if code, out := runCommand(t, "lint", "../../fixture/encode-001.ntx", "../../fixture/ngPost-001.ntx"); code != 0 {
+		t.Errorf("expect the fixtures to pass but exited with %d: %s", code, out)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.ntx")
+	if err := os.WriteFile(path, []byte("=ybegin line=128 size=3 name=a\nKLM\n=yend size=4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, out := runCommand(t, "lint", "-json", path)
+	if code != 1 {
+		t.Errorf("expect a size mismatch to fail but exited with %d", code)
+	}
+	var f lintFinding
+	if err := json.Unmarshal([]byte(strings.SplitN(out, "\n", 2)[0]), &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.File != path || f.Line != 3 || f.Check != yenc.LintMismatch || f.Severity != yenc.SeverityError {
+		t.Errorf("unexpected finding %+v", f)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+
+	"gopkg.in/yenc.v0"
+)
+
+// Parts of one file given on the command line, ordered by offset.
+type partFile struct {
+	name  string
+	size  uint64
+	parts []part
+}
+
+type part struct {
+	path string
+	h    yenc.Header
+}
+
+// Read the header of each input and group the parts by file name and size, in the order of their first part given.
+func scanParts(paths []string) (files []*partFile, err error) {
+	type key struct {
+		name string
+		size uint64
+	}
+	byKey := make(map[key]*partFile)
+	for _, path := range paths {
+		var h *yenc.Header
+		if h, err = readHeader(path); err != nil {
+			err = fmt.Errorf("%s: %w", path, err)
+			return
+		}
+		k := key{h.Name, h.Size}
+		f, ok := byKey[k]
+		if !ok {
+			f = &partFile{name: h.Name, size: h.Size}
+			byKey[k] = f
+			files = append(files, f)
+		}
+		f.parts = append(f.parts, part{path, *h})
+	}
+	for _, f := range files {
+		sort.SliceStable(f.parts, func(i, j int) bool { return f.parts[i].h.Begin < f.parts[j].h.Begin })
+	}
+	return
+}
+
+func readHeader(path string) (h *yenc.Header, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+	var d *yenc.Decoder
+	if d, err = yenc.Decode(f, yenc.DecodeWithPrefixData()); err != nil {
+		return
+	}
+	h = d.Header()
+	return
+}
+
+// Decode a part in full, verifying its size and part CRC32, and feed its data to w.
+func decodePart(path string, w io.Writer) (h *yenc.Header, t *yenc.Trailer, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+	var d *yenc.Decoder
+	if d, err = yenc.Decode(f, yenc.DecodeWithPrefixData()); err != nil {
+		return
+	}
+	h = d.Header()
+	if _, err = io.Copy(w, d); err != nil {
+		return
+	}
+	if t = d.Trailer(); t == nil {
+		err = fmt.Errorf("no =yend line: %w", yenc.ErrInvalidFormat)
+	}
+	return
+}
+
+// Join the parts of a file in order with a yenc.Joiner writing to w, or only verifying them if w is nil, and report the
+// parts that fail to decode to out. Returns the Joiner, the CRC32 of the data in file order, only valid if the file is
+// complete, and the first error writing to w.
+func joinParts(f *partFile, w io.WriterAt, out io.Writer) (j *yenc.Joiner, crc uint32, err error) {
+	cw := &crcWriterAt{w: w, hash: crc32.NewIEEE()}
+	j = yenc.NewJoiner(cw, yenc.DecodeWithPrefixData())
+	for _, p := range f.parts {
+		if jerr := joinPart(j, p.path); jerr != nil {
+			if err = cw.err; err != nil {
+				return
+			}
+			fmt.Fprintf(out, "%s: part %d (%s): %v\n", f.name, p.h.Part, p.path, jerr)
+		}
+	}
+	crc = cw.hash.Sum32()
+	return
+}
+
+func joinPart(j *yenc.Joiner, path string) (err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+	_, err = j.Join(f)
+	return
+}
+
+// Writes to an io.WriterAt, or nowhere if nil, computing the CRC32 of the data written in order from offset 0 and
+// keeping the first write error.
+type crcWriterAt struct {
+	w    io.WriterAt
+	hash hash.Hash32
+	next int64
+	err  error
+}
+
+func (c *crcWriterAt) WriteAt(b []byte, off int64) (n int, err error) {
+	n = len(b)
+	if c.w != nil {
+		if n, err = c.w.WriteAt(b, off); err != nil && c.err == nil {
+			c.err = err
+		}
+	}
+	if off == c.next {
+		c.hash.Write(b[:n])
+		c.next += int64(n)
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yenc.v0"
+)
+
+func verify(args []string, stdout, stderr io.Writer) (err error) {
+	fs := newFlagSet("verify", "file.ntx...", stderr)
+	if err = parseFlags(fs, args, 1); err != nil {
+		return
+	}
+	var files []*partFile
+	if files, err = scanParts(fs.Args()); err != nil {
+		return
+	}
+	failed := false
+	for _, f := range files {
+		j, crc, _ := joinParts(f, nil, stdout)
+		if !report(stdout, f, f.name, j, crc) {
+			failed = true
+		}
+	}
+	if failed {
+		err = errFailed
+	}
+	return
+}
+
+// Report the result of joining a file to w, checking crc, the CRC32 of the whole file, against the trailers if the
+// parts cover it. Returns whether the file is complete and intact.
+func report(w io.Writer, f *partFile, path string, j *yenc.Joiner, crc uint32) (ok bool) {
+	bad := j.Bad()
+	if j.Header() == nil {
+		// no part could be joined
+		bad = []yenc.Range{{Begin: 0, End: f.size}}
+	}
+	if len(bad) > 0 {
+		fmt.Fprintf(w, "%s: FAILED, %d bytes, bad ranges %v\n", path, f.size, bad)
+		return
+	}
+	expect, checked := j.FileCRC32()
+	switch {
+	case checked && expect != crc:
+		fmt.Fprintf(w, "%s: FAILED, %s has CRC32 %08x but the trailer says %08x: %v\n", path, f.name, crc, expect, yenc.ErrDataCorruption)
+		return
+	case checked:
+		fmt.Fprintf(w, "%s: OK, %d parts, %d bytes, crc32 %08x\n", path, len(f.parts), f.size, crc)
+	default:
+		fmt.Fprintf(w, "%s: OK, %d parts, %d bytes, crc32 %08x (no file CRC32 to check)\n", path, len(f.parts), f.size, crc)
+	}
+	ok = true
+	return
+}
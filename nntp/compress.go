@@ -0,0 +1,186 @@
+package nntp
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+)
+
+// Compression methods as returned by Conn.Compression.
+const (
+	CompressDeflate = "DEFLATE" // RFC 8054 COMPRESS DEFLATE, the whole session in both directions is compressed
+	CompressGzip    = "GZIP"    // XFEATURE COMPRESS GZIP, multi-line responses of some commands are compressed
+)
+
+// Marker ending the status line of a multi-line response compressed with XFEATURE COMPRESS GZIP. Servers send other
+// responses, like article bodies or small responses, uncompressed and without it.
+const gzipMarker = "[COMPRESS=GZIP]"
+
+// Negotiate compression after authentication. COMPRESS DEFLATE is preferred if the server advertises it, otherwise
+// XFEATURE COMPRESS GZIP is attempted. Servers supporting neither are used uncompressed.
+func (c *Conn) negotiateCompression() (err error) {
+	var caps map[string][]string
+	if caps, err = c.Capabilities(); err != nil {
+		// servers predating RFC 3977 may not know CAPABILITIES
+		if _, ok := err.(*textproto.Error); !ok {
+			return
+		}
+		err = nil
+	}
+	for _, method := range caps["COMPRESS"] {
+		if strings.EqualFold(method, CompressDeflate) {
+			return c.compressDeflate()
+		}
+	}
+	var code int
+	if code, _, err = c.cmd(0, "XFEATURE COMPRESS GZIP TERMINATOR"); err != nil {
+		if _, ok := err.(*textproto.Error); ok {
+			err = nil
+		}
+		return
+	}
+	if code == 290 {
+		c.compression = CompressGzip
+	}
+	return
+}
+
+func (c *Conn) compressDeflate() (err error) {
+	if _, _, err = c.cmd(206, "COMPRESS DEFLATE"); err != nil {
+		err = fmt.Errorf("[NNTP] %s failed to activate compression: %w", c.addr, err)
+		return
+	}
+	// the server sends nothing more until the next command, so no uncompressed data is left in the read buffer
+	c.text = textproto.NewConn(newDeflateConn(c.conn))
+	c.compression = CompressDeflate
+	return
+}
+
+// Compression method in use, one of CompressDeflate, CompressGzip or empty if none.
+func (c *Conn) Compression() string {
+	return c.compression
+}
+
+// Number of bytes read from and written to the network so far, i.e. after compression and encryption.
+func (c *Conn) WireBytes() (read, written int64) {
+	return atomic.LoadInt64(&c.counter.read), atomic.LoadInt64(&c.counter.written)
+}
+
+// Return a reader for the multi-line response whose status line message msg has just been read. With XFEATURE
+// COMPRESS GZIP, a response whose status line ends with the [COMPRESS=GZIP] marker is a zlib or gzip stream holding
+// the dot-terminated block, followed by a terminating dot line of its own.
+func (c *Conn) dotReader(msg string) (r io.Reader, err error) {
+	if c.compression != CompressGzip || !strings.HasSuffix(strings.TrimSpace(msg), gzipMarker) {
+		r = c.text.DotReader()
+		return
+	}
+	var magic []byte
+	if magic, err = c.text.R.Peek(2); err != nil {
+		return
+	}
+	var zr io.ReadCloser
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		var gr *gzip.Reader
+		if gr, err = gzip.NewReader(c.text.R); err != nil {
+			return
+		}
+		gr.Multistream(false)
+		zr = gr
+	} else if zr, err = zlib.NewReader(c.text.R); err != nil {
+		return
+	}
+	r = &gzipDotReader{c: c, z: zr, r: textproto.NewReader(bufio.NewReader(zr)).DotReader()}
+	return
+}
+
+type gzipDotReader struct {
+	c    *Conn
+	z    io.ReadCloser
+	r    io.Reader
+	done bool
+}
+
+func (g *gzipDotReader) Read(b []byte) (n int, err error) {
+	if g.done {
+		return 0, io.EOF
+	}
+	if n, err = g.r.Read(b); err == io.EOF {
+		g.done = true
+		// consume the rest of the compressed stream including its checksum, then the terminating dot line
+		if _, err = io.Copy(io.Discard, g.z); err != nil {
+			return
+		}
+		if err = g.z.Close(); err != nil {
+			return
+		}
+		var line string
+		if line, err = g.c.text.ReadLine(); err != nil {
+			return
+		}
+		if line != "." {
+			err = fmt.Errorf("[NNTP] expect terminating dot after compressed data but got %#v: %w", line, ErrProtocol)
+			return
+		}
+		err = io.EOF
+	}
+	return
+}
+
+// A connection compressed with raw DEFLATE in both directions. Every write is flushed so that each command and
+// response is sent immediately.
+type deflateConn struct {
+	net.Conn
+	r io.Reader
+	w *flate.Writer
+}
+
+func newDeflateConn(conn net.Conn) *deflateConn {
+	w, _ := flate.NewWriter(conn, flate.DefaultCompression)
+	return &deflateConn{Conn: conn, r: flate.NewReader(conn), w: w}
+}
+
+func (d *deflateConn) Read(b []byte) (int, error) {
+	return d.r.Read(b)
+}
+
+func (d *deflateConn) Write(b []byte) (n int, err error) {
+	if n, err = d.w.Write(b); err != nil {
+		return
+	}
+	err = d.w.Flush()
+	return
+}
+
+// Counts bytes transferred over the network.
+type countingConn struct {
+	net.Conn
+	read    int64
+	written int64
+}
+
+func (c *countingConn) Read(b []byte) (n int, err error) {
+	n, err = c.Conn.Read(b)
+	atomic.AddInt64(&c.read, int64(n))
+	return
+}
+
+func (c *countingConn) Write(b []byte) (n int, err error) {
+	n, err = c.Conn.Write(b)
+	atomic.AddInt64(&c.written, int64(n))
+	return
+}
+
+// Negotiate compression after connecting and authenticating, see CompressDeflate and CompressGzip. Servers supporting
+// neither are used uncompressed.
+func DialWithCompression() DialOption {
+	return func(c *Conn) {
+		c.useCompression = true
+	}
+}
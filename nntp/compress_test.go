@@ -0,0 +1,111 @@
+package nntp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"gopkg.in/yenc.v0/nntp/nntptest"
+)
+
+// The dot reader normalizes line endings to LF, and the server terminates the last line if it is not.
+func readArticleBodies(t *testing.T, c *Conn, articles map[string][]byte) {
+	for id, raw := range articles {
+		r, err := c.Body(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := bytes.TrimSuffix(bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n")), []byte("\n"))
+		if !bytes.Equal(expect, bytes.TrimSuffix(body, []byte("\n"))) {
+			t.Errorf("%s body mismatch", id)
+		}
+	}
+}
+
+func TestCompressDeflate(t *testing.T) {
+	articles := loadArticles(t, "ngPost", 10)
+	srv := nntptest.NewServer(
+		nntptest.ServerWithArticles(articles),
+		nntptest.ServerWithCompressDeflate(),
+		nntptest.ServerWithAuth("user", "pass"))
+	defer srv.Close()
+
+	plain, err := Dial(context.Background(), srv.Addr, DialWithAuth("user", "pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Quit()
+	readArticleBodies(t, plain, articles)
+
+	c, err := Dial(context.Background(), srv.Addr, DialWithAuth("user", "pass"), DialWithCompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit()
+	if c.Compression() != CompressDeflate {
+		t.Fatalf("expect deflate compression but got %#v", c.Compression())
+	}
+	readArticleBodies(t, c, articles)
+
+	plainRead, _ := plain.WireBytes()
+	compressedRead, _ := c.WireBytes()
+	if compressedRead >= plainRead {
+		t.Errorf("expect compression to reduce transfer but read %d bytes compressed vs %d bytes plain", compressedRead, plainRead)
+	}
+
+	p, err := NewPool([]Server{{Addr: srv.Addr, Username: "user", Password: "pass", Options: []DialOption{DialWithCompression()}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	compareRaw(t, "ngPost", fetchAll(t, p, "ngPost", 10))
+}
+
+func TestCompressXFeatureGzip(t *testing.T) {
+	articles := loadArticles(t, "yenc32", 10)
+	articles["head@nntptest"] = []byte("From: poster@example.com\r\nSubject: yenc32-raw.bin yEnc (1/10)\r\nMessage-ID: <head@nntptest>\r\n\r\nbody\r\n")
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(articles), nntptest.ServerWithXFeatureGzip())
+	defer srv.Close()
+
+	c, err := Dial(context.Background(), srv.Addr, DialWithCompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit()
+	if c.Compression() != CompressGzip {
+		t.Fatalf("expect gzip compression but got %#v", c.Compression())
+	}
+	for i := 0; i < 2; i++ {
+		h, err := c.Head("head@nntptest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Get("Subject") != "yenc32-raw.bin yEnc (1/10)" {
+			t.Errorf("unexpected subject %#v", h.Get("Subject"))
+		}
+	}
+	delete(articles, "head@nntptest")
+	readArticleBodies(t, c, articles)
+}
+
+func TestCompressUnsupported(t *testing.T) {
+	srv := nntptest.NewServer()
+	defer srv.Close()
+
+	c, err := Dial(context.Background(), srv.Addr, DialWithCompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit()
+	if c.Compression() != "" {
+		t.Errorf("expect no compression but got %#v", c.Compression())
+	}
+	if _, err = c.Date(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,305 @@
+package nntp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"gopkg.in/option.v0"
+	"gopkg.in/yenc.v0"
+)
+
+// A single client connection to an NNTP server. A Conn is not safe for concurrent use, and any multi-line response
+// reader returned by it must be drained before the next command is issued.
+type Conn struct {
+	addr     string
+	conn     net.Conn
+	text     *textproto.Conn
+	lastUsed time.Time
+
+	dialer   func(ctx context.Context, network, addr string) (net.Conn, error)
+	username string
+	password string
+	timeout  time.Duration
+
+	tlsConfig   *tls.Config
+	useTLS      bool // Set by DialWithTLS or DialWithStartTLS, even with a nil config
+	useStartTLS bool
+	pins        [][]byte
+
+	useCompression bool
+	compression    string
+	counter        *countingConn
+
+	overviewFormat []string
+	legacy         map[string]bool // Standard commands the server answered 500 to
+}
+
+func Dial(ctx context.Context, addr string, options ...DialOption) (c *Conn, err error) {
+	c = option.New(options)
+	c.addr = addr
+	if c.dialer == nil {
+		c.dialer = (&net.Dialer{}).DialContext
+	}
+	var raw net.Conn
+	if raw, err = c.dialer(ctx, "tcp", addr); err != nil {
+		c = nil
+		err = fmt.Errorf("[NNTP] failed to connect to %s: %w", addr, err)
+		return
+	}
+	c.counter = &countingConn{Conn: raw}
+	c.conn = c.counter
+	if err = c.handshake(ctx); err != nil {
+		c.conn.Close()
+		c = nil
+		return
+	}
+	return
+}
+
+func (c *Conn) handshake(ctx context.Context) (err error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+		defer c.conn.SetDeadline(time.Time{})
+	}
+	if c.useTLS && !c.useStartTLS {
+		if err = c.handshakeTLS(ctx); err != nil {
+			return
+		}
+	} else {
+		c.text = textproto.NewConn(c.conn)
+	}
+	if _, _, err = c.text.ReadCodeLine(20); err != nil {
+		err = fmt.Errorf("[NNTP] %s rejected connection: %w", c.addr, err)
+		return
+	}
+	if c.useTLS && c.useStartTLS {
+		if err = c.startTLS(ctx); err != nil {
+			return
+		}
+	}
+	if c.username != "" {
+		if err = c.authenticate(); err != nil {
+			return
+		}
+	}
+	if c.useCompression {
+		if err = c.negotiateCompression(); err != nil {
+			return
+		}
+	}
+	c.lastUsed = time.Now()
+	return
+}
+
+// AUTHINFO USER/PASS as specified in RFC 4643.
+func (c *Conn) authenticate() (err error) {
+	var code int
+	if code, _, err = c.cmd(0, "AUTHINFO USER %s", c.username); err != nil {
+		err = fmt.Errorf("[NNTP] authentication failed on %s: %w", c.addr, ErrAuthentication)
+		return
+	}
+	if code == 281 {
+		return
+	}
+	if code != 381 {
+		err = fmt.Errorf("[NNTP] unexpected AUTHINFO USER response %d on %s: %w", code, c.addr, ErrAuthentication)
+		return
+	}
+	if _, _, err = c.cmd(281, "AUTHINFO PASS %s", c.password); err != nil {
+		err = fmt.Errorf("[NNTP] authentication failed on %s: %w", c.addr, ErrAuthentication)
+		return
+	}
+	return
+}
+
+// Send a command and read its status line. If expectCode is 0, any 1xx, 2xx or 3xx status is accepted.
+func (c *Conn) cmd(expectCode int, format string, args ...any) (code int, msg string, err error) {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	var id uint
+	if id, err = c.text.Cmd(format, args...); err != nil {
+		return
+	}
+	c.text.StartResponse(id)
+	defer c.text.EndResponse(id)
+	if expectCode == 0 {
+		if code, msg, err = c.text.ReadCodeLine(0); err == nil && code >= 400 {
+			err = &textproto.Error{Code: code, Msg: msg}
+		}
+	} else {
+		code, msg, err = c.text.ReadCodeLine(expectCode)
+	}
+	c.lastUsed = time.Now()
+	return
+}
+
+// Issue the CAPABILITIES command and return the capabilities keyed by their upper case label, with their arguments.
+func (c *Conn) Capabilities() (caps map[string][]string, err error) {
+	if _, _, err = c.cmd(101, "CAPABILITIES"); err != nil {
+		return
+	}
+	var lines []string
+	if lines, err = c.text.ReadDotLines(); err != nil {
+		return
+	}
+	caps = make(map[string][]string, len(lines))
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			caps[strings.ToUpper(fields[0])] = fields[1:]
+		}
+	}
+	return
+}
+
+// Issue the BODY command for a message-ID and return a reader of the dot-decoded body. The reader must be read until
+// io.EOF before the next command on this connection.
+func (c *Conn) Body(messageID string) (r io.Reader, err error) {
+	return c.multiline(222, "BODY", messageID)
+}
+
+// Issue the ARTICLE command for a message-ID and return a reader of the dot-decoded article, headers included.
+func (c *Conn) Article(messageID string) (r io.Reader, err error) {
+	return c.multiline(220, "ARTICLE", messageID)
+}
+
+// Issue the HEAD command for a message-ID and return the parsed article headers.
+func (c *Conn) Head(messageID string) (h textproto.MIMEHeader, err error) {
+	var r io.Reader
+	if r, err = c.multiline(221, "HEAD", messageID); err != nil {
+		return
+	}
+	h, err = textproto.NewReader(bufio.NewReader(r)).ReadMIMEHeader()
+	if err == io.EOF {
+		err = nil
+	}
+	// drain anything left in case the header block is malformed
+	_, _ = io.Copy(io.Discard, r)
+	return
+}
+
+// Issue the POST command and return a writer for the article, headers included. Line endings are normalized to CRLF
+// and leading dots are stuffed by the writer. The article is submitted on Close, which returns an error if the server
+// rejects it.
+func (c *Conn) Post() (w io.WriteCloser, err error) {
+	if _, _, err = c.cmd(340, "POST"); err != nil {
+		err = fmt.Errorf("[NNTP] %s refused posting: %w", c.addr, err)
+		return
+	}
+	w = &postWriter{c: c, w: c.text.DotWriter()}
+	return
+}
+
+type postWriter struct {
+	c *Conn
+	w io.WriteCloser
+}
+
+func (p *postWriter) Write(b []byte) (int, error) {
+	return p.w.Write(b)
+}
+
+func (p *postWriter) Close() (err error) {
+	if err = p.w.Close(); err != nil {
+		return
+	}
+	if _, _, err = p.c.text.ReadCodeLine(240); err != nil {
+		err = fmt.Errorf("[NNTP] %s rejected the article: %w", p.c.addr, err)
+	}
+	return
+}
+
+// Issue the STAT command to check for existence of a message-ID without transferring it.
+func (c *Conn) Stat(messageID string) (err error) {
+	messageID = FormatMessageID(messageID)
+	if _, _, err = c.cmd(223, "STAT %s", messageID); err != nil {
+		err = c.articleError(messageID, err)
+	}
+	return
+}
+
+// Issue the DATE command and return the server time. This is a cheap command that is used as a connection health
+// check.
+func (c *Conn) Date() (t time.Time, err error) {
+	var msg string
+	if _, msg, err = c.cmd(111, "DATE"); err != nil {
+		return
+	}
+	if t, err = time.Parse("20060102150405", strings.TrimSpace(msg)); err != nil {
+		err = fmt.Errorf("[NNTP] invalid DATE response %#v: %w", msg, ErrProtocol)
+	}
+	return
+}
+
+func (c *Conn) multiline(expectCode int, command, messageID string) (r io.Reader, err error) {
+	messageID = FormatMessageID(messageID)
+	var msg string
+	if _, msg, err = c.cmd(expectCode, "%s %s", command, messageID); err != nil {
+		err = c.articleError(messageID, err)
+		return
+	}
+	r, err = c.dotReader(msg)
+	return
+}
+
+func (c *Conn) articleError(messageID string, err error) error {
+	if e, ok := err.(*textproto.Error); ok && e.Code == 430 {
+		return fmt.Errorf("[NNTP] article %s not found on %s: %w", messageID, c.addr, ErrArticleNotFound)
+	}
+	return fmt.Errorf("[NNTP] failed to retrieve article %s from %s: %w", messageID, c.addr, err)
+}
+
+// Address of the server this connection is connected to.
+func (c *Conn) Addr() string {
+	return c.addr
+}
+
+// Politely end the session with the QUIT command, then close the connection.
+func (c *Conn) Quit() (err error) {
+	_, _, err = c.cmd(205, "QUIT")
+	if cerr := c.text.Close(); err == nil {
+		err = cerr
+	}
+	return
+}
+
+// Close the underlying connection without the QUIT command.
+func (c *Conn) Close() error {
+	return c.text.Close()
+}
+
+// Add the enclosing angle brackets to a message-ID if they are missing.
+func FormatMessageID(messageID string) string {
+	return yenc.FormatMessageID(messageID)
+}
+
+type DialOption func(*Conn)
+
+func DialWithAuth(username, password string) DialOption {
+	return func(c *Conn) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// Deadline of each command, counted from the time the command is sent until its response, multi-line data included,
+// is completely read.
+func DialWithTimeout(timeout time.Duration) DialOption {
+	return func(c *Conn) {
+		c.timeout = timeout
+	}
+}
+
+// Use a custom dial function instead of net.Dialer, e.g. for proxies.
+func DialWithDialer(dialer func(ctx context.Context, network, addr string) (net.Conn, error)) DialOption {
+	return func(c *Conn) {
+		c.dialer = dialer
+	}
+}
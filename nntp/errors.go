@@ -0,0 +1,14 @@
+package nntp
+
+import "errors"
+
+var ErrArticleNotFound = errors.New("no such article")
+var ErrAuthentication = errors.New("authentication failed")
+var ErrProtocol = errors.New("unexpected NNTP response")
+var ErrPoolClosed = errors.New("connection pool closed")
+var ErrNoServers = errors.New("no usable server")
+var ErrTLSUnavailable = errors.New("STARTTLS not supported")
+var ErrTLSHandshake = errors.New("TLS handshake failed")
+var ErrCertificateVerification = errors.New("certificate verification failed")
+var ErrCertificatePin = errors.New("certificate public key not pinned")
+var ErrGroupNotFound = errors.New("no such newsgroup")
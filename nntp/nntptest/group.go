@@ -0,0 +1,204 @@
+package nntptest
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Overview format, the standard fields followed by Xref.
+var overviewFormat = []string{"Subject:", "From:", "Date:", "Message-ID:", "References:", ":bytes", ":lines", "Xref:full"}
+
+// Message-IDs of a group by article number - 1, or false if the group does not exist.
+func (s *Server) group(name string) (ids []string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, ok = s.groups[name]
+	return append([]string(nil), ids...), ok
+}
+
+// Handle the GROUP command.
+func (c *conn) selectGroup(args []string) {
+	if len(args) != 1 {
+		c.reply(501, "syntax error")
+		return
+	}
+	ids, ok := c.s.group(args[0])
+	if !ok {
+		c.reply(411, "no such newsgroup")
+		return
+	}
+	c.group = args[0]
+	c.reply(211, groupStatus(args[0], ids))
+}
+
+// Handle the LISTGROUP command, for the selected group if none is given.
+func (c *conn) listGroup(args []string) {
+	name := c.group
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if name == "" {
+		c.reply(412, "no newsgroup selected")
+		return
+	}
+	ids, ok := c.s.group(name)
+	if !ok {
+		c.reply(411, "no such newsgroup")
+		return
+	}
+	low, high := int64(1), int64(len(ids))
+	if len(args) > 1 {
+		if low, high, ok = parseRange(args[1], high); !ok {
+			c.reply(501, "syntax error")
+			return
+		}
+	}
+	c.group = name
+	var b bytes.Buffer
+	c.each(ids, low, high, func(n int64, _ []byte) {
+		fmt.Fprintf(&b, "%d\n", n)
+	})
+	c.block(211, groupStatus(name, ids)+" list follows", "LISTGROUP", b.Bytes())
+}
+
+func groupStatus(name string, ids []string) string {
+	if len(ids) == 0 {
+		return "0 0 0 " + name
+	}
+	return fmt.Sprintf("%d 1 %d %s", len(ids), len(ids), name)
+}
+
+// Handle LIST OVERVIEW.FMT. Other lists are not supported.
+func (c *conn) list(args []string) {
+	if len(args) != 1 || !strings.EqualFold(args[0], "OVERVIEW.FMT") {
+		c.reply(501, "list not supported")
+		return
+	}
+	c.block(215, "order of fields in overview database", "LIST", []byte(strings.Join(overviewFormat, "\n")+"\n"))
+}
+
+// Handle OVER and XOVER for an article number range of the selected group.
+func (c *conn) over(command string, args []string) {
+	ids, low, high, ok := c.selectRange(command, args)
+	if !ok {
+		return
+	}
+	var b bytes.Buffer
+	c.each(ids, low, high, func(n int64, raw []byte) {
+		head, body := splitArticle(raw)
+		h := parseHead(head)
+		fields := []string{
+			strconv.FormatInt(n, 10),
+			h.Get("Subject"),
+			h.Get("From"),
+			h.Get("Date"),
+			h.Get("Message-ID"),
+			h.Get("References"),
+			strconv.Itoa(len(raw)),
+			strconv.Itoa(bytes.Count(body, []byte("\n"))),
+			"Xref: nntptest " + c.group + ":" + strconv.FormatInt(n, 10),
+		}
+		for i, field := range fields {
+			fields[i] = strings.NewReplacer("\t", " ", "\r", "", "\n", "").Replace(field)
+		}
+		b.WriteString(strings.Join(fields, "\t"))
+		b.WriteByte('\n')
+	})
+	if b.Len() == 0 {
+		c.reply(423, "no articles in that range")
+		return
+	}
+	c.block(224, "overview information follows", command, b.Bytes())
+}
+
+// Handle HDR and XHDR for an article number range of the selected group, including the :bytes and :lines items.
+func (c *conn) hdr(command string, args []string) {
+	if len(args) == 0 {
+		c.reply(501, "syntax error")
+		return
+	}
+	field := args[0]
+	ids, low, high, ok := c.selectRange(command, args[1:])
+	if !ok {
+		return
+	}
+	var b bytes.Buffer
+	c.each(ids, low, high, func(n int64, raw []byte) {
+		head, body := splitArticle(raw)
+		var value string
+		switch strings.ToLower(field) {
+		case ":bytes":
+			value = strconv.Itoa(len(raw))
+		case ":lines":
+			value = strconv.Itoa(bytes.Count(body, []byte("\n")))
+		default:
+			value = parseHead(head).Get(field)
+		}
+		fmt.Fprintf(&b, "%d %s\n", n, value)
+	})
+	code := 225
+	if command == "XHDR" {
+		code = 221
+	}
+	c.block(code, "headers follow", command, b.Bytes())
+}
+
+// Parse the range argument of a command on the selected group, replying with an error if there is none.
+func (c *conn) selectRange(command string, args []string) (ids []string, low, high int64, ok bool) {
+	if c.s.noOver && (command == "OVER" || command == "HDR") {
+		c.reply(500, "unknown command")
+		return
+	}
+	if c.group == "" {
+		c.reply(412, "no newsgroup selected")
+		return
+	}
+	ids, _ = c.s.group(c.group)
+	high = int64(len(ids))
+	low = high
+	ok = true
+	if len(args) > 0 {
+		if low, high, ok = parseRange(args[0], high); !ok {
+			c.reply(501, "syntax error")
+		}
+	}
+	return
+}
+
+// Call fn for every existing article with a number in the range.
+func (c *conn) each(ids []string, low, high int64, fn func(n int64, raw []byte)) {
+	if low < 1 {
+		low = 1
+	}
+	for n := low; n <= high && n <= int64(len(ids)); n++ {
+		c.s.mu.Lock()
+		raw, ok := c.s.articles[ids[n-1]]
+		c.s.mu.Unlock()
+		if ok {
+			fn(n, raw)
+		}
+	}
+}
+
+// Parse "n", "n-" or "n-m". The open form extends up to last.
+func parseRange(arg string, last int64) (low, high int64, ok bool) {
+	from, to, isRange := strings.Cut(arg, "-")
+	var err error
+	if low, err = strconv.ParseInt(from, 10, 64); err != nil {
+		return
+	}
+	switch {
+	case !isRange:
+		high = low
+	case to == "":
+		high = last
+	default:
+		if high, err = strconv.ParseInt(to, 10, 64); err != nil {
+			return
+		}
+	}
+	ok = true
+	return
+}
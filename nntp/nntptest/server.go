@@ -0,0 +1,532 @@
+// Package nntptest provides a fake NNTP server listening on a local port, for testing NNTP clients.
+package nntptest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/option.v0"
+)
+
+// A fake NNTP server serving articles from memory.
+type Server struct {
+	Addr string // host:port the server listens on
+
+	l  net.Listener
+	wg sync.WaitGroup
+
+	mu        sync.Mutex
+	articles  map[string][]byte
+	groups    map[string][]string // Message-IDs by article number - 1
+	conns     map[net.Conn]bool
+	accepted  int
+	maxActive int
+	commands  map[string]int
+
+	username string
+	password string
+	greeting string
+
+	tlsConfig   *tls.Config
+	useStartTLS bool
+
+	useDeflate    bool
+	useGzip       bool
+	gzipThreshold int
+	noOver        bool
+}
+
+// Start a fake NNTP server on a random local port. It panics if it fails to listen, like httptest.NewServer.
+func NewServer(options ...ServerOption) *Server {
+	s := option.New(options)
+	articles := s.articles
+	s.articles = make(map[string][]byte, len(articles))
+	s.groups = make(map[string][]string)
+	ids := make([]string, 0, len(articles))
+	for id := range articles {
+		ids = append(ids, id)
+	}
+	// number the articles in a deterministic order
+	sort.Strings(ids)
+	for _, id := range ids {
+		s.AddArticle(id, articles[id])
+	}
+	s.conns = make(map[net.Conn]bool)
+	s.commands = make(map[string]int)
+	if s.greeting == "" {
+		s.greeting = "200 nntptest ready"
+	}
+	var err error
+	if s.l, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		panic(fmt.Sprintf("nntptest: failed to listen on a port: %v", err))
+	}
+	s.Addr = s.l.Addr().String()
+	if s.tlsConfig != nil && !s.useStartTLS {
+		s.l = tls.NewListener(s.l, s.tlsConfig)
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		nc, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[nc] = true
+		s.accepted++
+		if len(s.conns) > s.maxActive {
+			s.maxActive = len(s.conns)
+		}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			c := &conn{s: s, nc: nc, text: textproto.NewConn(nc)}
+			c.serve()
+			nc.Close()
+			s.mu.Lock()
+			delete(s.conns, nc)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Store an article under a message-ID. The raw article may include a header block separated from the body by an
+// empty line. Otherwise the whole content is used as the body. A new article is numbered in each group of its
+// Newsgroups header.
+func (s *Server) AddArticle(messageID string, raw []byte) {
+	messageID = formatMessageID(messageID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.articles[messageID]; !ok {
+		head, _ := splitArticle(raw)
+		for _, group := range strings.Split(parseHead(head).Get("Newsgroups"), ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				s.groups[group] = append(s.groups[group], messageID)
+			}
+		}
+	}
+	s.articles[messageID] = raw
+}
+
+func (s *Server) RemoveArticle(messageID string) {
+	s.mu.Lock()
+	delete(s.articles, formatMessageID(messageID))
+	s.mu.Unlock()
+}
+
+func (s *Server) article(messageID string) (head, body []byte, ok bool) {
+	s.mu.Lock()
+	raw, ok := s.articles[formatMessageID(messageID)]
+	s.mu.Unlock()
+	if ok {
+		head, body = splitArticle(raw)
+	}
+	return
+}
+
+func splitArticle(raw []byte) (head, body []byte) {
+	body = raw
+	for _, sep := range []string{"\r\n\r\n", "\n\n"} {
+		if i := bytes.Index(raw, []byte(sep)); i >= 0 && bytes.IndexByte(raw[:i], ':') > 0 {
+			head, body = raw[:i+len(sep)/2], raw[i+len(sep):]
+			break
+		}
+	}
+	return
+}
+
+func parseHead(head []byte) textproto.MIMEHeader {
+	h, _ := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(head), strings.NewReader("\r\n")))).ReadMIMEHeader()
+	return h
+}
+
+// Number of connections accepted so far.
+func (s *Server) Accepted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+// Max number of connections seen open at the same time.
+func (s *Server) MaxActive() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxActive
+}
+
+// Number of times a command (upper case keyword, e.g. "BODY") has been received.
+func (s *Server) Count(command string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commands[command]
+}
+
+// Abruptly close every open connection while keeping the server listening, to simulate dropped connections.
+func (s *Server) CloseConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for nc := range s.conns {
+		nc.Close()
+	}
+}
+
+// Stop listening, close every open connection and wait for them to finish.
+func (s *Server) Close() {
+	s.l.Close()
+	s.CloseConnections()
+	s.wg.Wait()
+}
+
+type conn struct {
+	s      *Server
+	nc     net.Conn
+	text   *textproto.Conn
+	authed bool
+	user   string
+	tls    bool
+	gzip   bool
+	group  string
+}
+
+func (c *conn) serve() {
+	if c.text.PrintfLine("%s", c.s.greeting) != nil {
+		return
+	}
+	for {
+		line, err := c.text.ReadLine()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			c.reply(500, "empty command")
+			continue
+		}
+		command := strings.ToUpper(fields[0])
+		args := fields[1:]
+		c.s.mu.Lock()
+		c.s.commands[command]++
+		c.s.mu.Unlock()
+		if command == "QUIT" {
+			c.reply(205, "bye")
+			return
+		}
+		if c.s.username != "" && !c.authed && command != "AUTHINFO" && command != "CAPABILITIES" && command != "STARTTLS" {
+			c.reply(480, "authentication required")
+			continue
+		}
+		switch command {
+		case "CAPABILITIES":
+			c.text.PrintfLine("101 capability list follows")
+			c.lines(c.capabilities()...)
+		case "STARTTLS":
+			if !c.starttls() {
+				return
+			}
+		case "COMPRESS":
+			c.compress(args)
+		case "XFEATURE":
+			c.xfeature(args)
+		case "MODE":
+			c.reply(200, "reader mode")
+		case "AUTHINFO":
+			c.authinfo(args)
+		case "DATE":
+			c.reply(111, time.Now().UTC().Format("20060102150405"))
+		case "ARTICLE", "BODY", "HEAD", "STAT":
+			c.retrieve(command, args)
+		case "POST":
+			c.post()
+		case "GROUP":
+			c.selectGroup(args)
+		case "LISTGROUP":
+			c.listGroup(args)
+		case "LIST":
+			c.list(args)
+		case "OVER", "XOVER":
+			c.over(command, args)
+		case "HDR", "XHDR":
+			c.hdr(command, args)
+		default:
+			c.reply(500, "unknown command")
+		}
+	}
+}
+
+func (c *conn) capabilities() []string {
+	caps := []string{"VERSION 2", "READER", "AUTHINFO USER", "LIST OVERVIEW.FMT"}
+	if !c.s.noOver {
+		caps = append(caps, "OVER", "HDR")
+	}
+	if c.s.useStartTLS && !c.tls {
+		caps = append(caps, "STARTTLS")
+	}
+	if c.s.useDeflate {
+		caps = append(caps, "COMPRESS DEFLATE")
+	}
+	return caps
+}
+
+// Handle COMPRESS DEFLATE as specified in RFC 8054.
+func (c *conn) compress(args []string) {
+	if !c.s.useDeflate || len(args) != 1 || !strings.EqualFold(args[0], "DEFLATE") {
+		c.reply(503, "compression not supported")
+		return
+	}
+	c.reply(206, "compression active")
+	c.text = textproto.NewConn(newDeflateConn(c.nc))
+}
+
+// Handle XFEATURE COMPRESS GZIP [TERMINATOR].
+func (c *conn) xfeature(args []string) {
+	if !c.s.useGzip || len(args) < 2 || !strings.EqualFold(args[0], "COMPRESS") || !strings.EqualFold(args[1], "GZIP") {
+		c.reply(500, "unknown command")
+		return
+	}
+	c.gzip = true
+	c.reply(290, "feature enabled")
+}
+
+// Reply with the status line and write data as a dot-terminated multi-line block. If XFEATURE COMPRESS GZIP is
+// enabled, the block of some commands is zlib compressed and followed by a terminating dot line, and the status line
+// ends with the [COMPRESS=GZIP] marker.
+func (c *conn) block(code int, msg, command string, data []byte) {
+	if !c.gzip || !gzipCommands[command] || len(data) < c.s.gzipThreshold {
+		c.reply(code, msg)
+		w := c.text.DotWriter()
+		w.Write(data)
+		w.Close()
+		return
+	}
+	var plain, compressed bytes.Buffer
+	bw := bufio.NewWriter(&plain)
+	w := textproto.NewWriter(bw).DotWriter()
+	w.Write(data)
+	w.Close()
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(plain.Bytes())
+	zw.Close()
+	c.reply(code, msg+" [COMPRESS=GZIP]")
+	c.text.W.Write(compressed.Bytes())
+	c.text.W.WriteString(".\r\n")
+	c.text.W.Flush()
+}
+
+// Handle the STARTTLS command. Returns false if the connection is unusable afterwards.
+func (c *conn) starttls() bool {
+	if !c.s.useStartTLS {
+		c.reply(580, "can not initiate TLS negotiation")
+		return true
+	}
+	if c.tls {
+		c.reply(502, "already using TLS")
+		return true
+	}
+	c.reply(382, "continue with TLS negotiation")
+	tc := tls.Server(c.nc, c.s.tlsConfig)
+	if tc.Handshake() != nil {
+		return false
+	}
+	c.nc = tc
+	c.text = textproto.NewConn(tc)
+	c.tls = true
+	return true
+}
+
+func (c *conn) reply(code int, msg string) {
+	c.text.PrintfLine("%d %s", code, msg)
+}
+
+// Write lines of a multi-line block and the terminating dot.
+func (c *conn) lines(lines ...string) {
+	w := c.text.DotWriter()
+	for _, line := range lines {
+		fmt.Fprintf(w, "%s\n", line)
+	}
+	w.Close()
+}
+
+func (c *conn) authinfo(args []string) {
+	if len(args) != 2 {
+		c.reply(501, "syntax error")
+		return
+	}
+	switch strings.ToUpper(args[0]) {
+	case "USER":
+		c.user = args[1]
+		c.reply(381, "password required")
+	case "PASS":
+		if c.user == c.s.username && args[1] == c.s.password {
+			c.authed = true
+			c.reply(281, "authentication accepted")
+		} else {
+			c.reply(481, "authentication failed")
+		}
+	default:
+		c.reply(501, "syntax error")
+	}
+}
+
+func (c *conn) retrieve(command string, args []string) {
+	if len(args) != 1 {
+		c.reply(501, "syntax error")
+		return
+	}
+	head, body, ok := c.s.article(args[0])
+	if !ok {
+		c.reply(430, "no such article")
+		return
+	}
+	switch command {
+	case "ARTICLE":
+		c.block(220, "0 "+args[0], command, append(append(append([]byte(nil), head...), '\n'), body...))
+	case "HEAD":
+		c.block(221, "0 "+args[0], command, head)
+	case "BODY":
+		c.block(222, "0 "+args[0], command, body)
+	case "STAT":
+		c.reply(223, "0 "+args[0])
+	}
+}
+
+var gzipCommands = map[string]bool{
+	"HEAD":      true,
+	"LIST":      true,
+	"LISTGROUP": true,
+	"OVER":      true,
+	"XOVER":     true,
+	"HDR":       true,
+	"XHDR":      true,
+}
+
+type deflateConn struct {
+	net.Conn
+	r io.Reader
+	w *flate.Writer
+}
+
+func newDeflateConn(conn net.Conn) *deflateConn {
+	w, _ := flate.NewWriter(conn, flate.DefaultCompression)
+	return &deflateConn{Conn: conn, r: flate.NewReader(conn), w: w}
+}
+
+func (d *deflateConn) Read(b []byte) (int, error) {
+	return d.r.Read(b)
+}
+
+func (d *deflateConn) Write(b []byte) (n int, err error) {
+	if n, err = d.w.Write(b); err != nil {
+		return
+	}
+	err = d.w.Flush()
+	return
+}
+
+// Accept a posted article and store it under its Message-ID header.
+func (c *conn) post() {
+	c.reply(340, "send article to be posted")
+	raw, err := c.text.ReadDotBytes()
+	if err != nil {
+		return
+	}
+	h, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw))).ReadMIMEHeader()
+	if err != nil || h.Get("Message-ID") == "" {
+		c.reply(441, "posting failed")
+		return
+	}
+	c.s.AddArticle(h.Get("Message-ID"), raw)
+	c.reply(240, "article received "+h.Get("Message-ID"))
+}
+
+func formatMessageID(messageID string) string {
+	return "<" + strings.Trim(messageID, "<>") + ">"
+}
+
+type ServerOption func(*Server)
+
+// Require AUTHINFO USER/PASS with the given credentials.
+func ServerWithAuth(username, password string) ServerOption {
+	return func(s *Server) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// Serve the given articles, keyed by message-ID.
+func ServerWithArticles(articles map[string][]byte) ServerOption {
+	return func(s *Server) {
+		s.articles = make(map[string][]byte, len(articles))
+		for id, raw := range articles {
+			s.articles[formatMessageID(id)] = raw
+		}
+	}
+}
+
+// Serve with implicit TLS.
+func ServerWithTLS(config *tls.Config) ServerOption {
+	return func(s *Server) {
+		s.tlsConfig = config
+		s.useStartTLS = false
+	}
+}
+
+// Serve in plain text, and support upgrading to TLS with the STARTTLS command.
+func ServerWithStartTLS(config *tls.Config) ServerOption {
+	return func(s *Server) {
+		s.tlsConfig = config
+		s.useStartTLS = true
+	}
+}
+
+// Advertise and support COMPRESS DEFLATE (RFC 8054).
+func ServerWithCompressDeflate() ServerOption {
+	return func(s *Server) {
+		s.useDeflate = true
+	}
+}
+
+// Support XFEATURE COMPRESS GZIP, compressing the multi-line responses of overview and header commands with zlib.
+func ServerWithXFeatureGzip() ServerOption {
+	return func(s *Server) {
+		s.useGzip = true
+	}
+}
+
+// With XFEATURE COMPRESS GZIP, send blocks of less than size bytes uncompressed, as servers that only compress large
+// responses do.
+func ServerWithGzipThreshold(size int) ServerOption {
+	return func(s *Server) {
+		s.gzipThreshold = size
+	}
+}
+
+// Answer 500 to OVER and HDR like servers predating RFC 3977, which only support XOVER and XHDR.
+func ServerWithoutOver() ServerOption {
+	return func(s *Server) {
+		s.noOver = true
+	}
+}
+
+// Use a custom greeting line, e.g. "502 service unavailable" to reject every connection.
+func ServerWithGreeting(greeting string) ServerOption {
+	return func(s *Server) {
+		s.greeting = greeting
+	}
+}
@@ -0,0 +1,317 @@
+package nntp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status of a newsgroup as returned by the GROUP and LISTGROUP commands.
+type Group struct {
+	Name  string
+	Count int64 // Estimated number of articles
+	Low   int64 // Lowest article number
+	High  int64 // Highest article number
+}
+
+// An overview record as returned by the OVER/XOVER command.
+type Overview struct {
+	Number     int64
+	Subject    string
+	From       string
+	Date       time.Time // Zero if the date can not be parsed
+	MessageID  string
+	References string
+	Bytes      int64
+	Lines      int64
+
+	// Fields following the standard ones keyed by canonical header name, e.g. "Xref". They are named after the
+	// format given by OverviewFormat if it has been retrieved, or else after the "Name: value" form of the field.
+	Extra map[string]string
+}
+
+// Number of the standard overview fields, always in the order Subject, From, Date, Message-ID, References, :bytes,
+// :lines (RFC 3977 section 8.4).
+const overviewFields = 7
+
+// Issue the GROUP command to select a newsgroup.
+func (c *Conn) Group(name string) (g Group, err error) {
+	var msg string
+	if _, msg, err = c.cmd(211, "GROUP %s", name); err != nil {
+		err = c.groupError(name, err)
+		return
+	}
+	g, err = parseGroup(msg)
+	return
+}
+
+// Issue the LISTGROUP command to select a newsgroup and iterate over the article numbers in the range. A high value of
+// zero or less means up to the last article.
+func (c *Conn) ListGroup(name string, low, high int64) (g Group, r *NumberReader, err error) {
+	var msg string
+	if _, msg, err = c.cmd(211, "LISTGROUP %s %s", name, formatRange(low, high)); err != nil {
+		err = c.groupError(name, err)
+		return
+	}
+	r = &NumberReader{}
+	if err = r.init(c, msg); err != nil {
+		return
+	}
+	if g, err = parseGroup(msg); err != nil {
+		_ = r.Close()
+		r = nil
+	}
+	return
+}
+
+func parseGroup(msg string) (g Group, err error) {
+	fields := strings.Fields(msg)
+	if len(fields) >= 4 {
+		g.Name = fields[3]
+		if g.Count, err = strconv.ParseInt(fields[0], 10, 64); err == nil {
+			if g.Low, err = strconv.ParseInt(fields[1], 10, 64); err == nil {
+				if g.High, err = strconv.ParseInt(fields[2], 10, 64); err == nil {
+					return
+				}
+			}
+		}
+	}
+	err = fmt.Errorf("[NNTP] invalid group response %#v: %w", msg, ErrProtocol)
+	return
+}
+
+func (c *Conn) groupError(name string, err error) error {
+	if e, ok := err.(*textproto.Error); ok && e.Code == 411 {
+		return fmt.Errorf("[NNTP] group %s not found on %s: %w", name, c.addr, ErrGroupNotFound)
+	}
+	return fmt.Errorf("[NNTP] failed to select group %s on %s: %w", name, c.addr, err)
+}
+
+// Issue the LIST OVERVIEW.FMT command and return the overview fields, e.g. "Subject:" or "Xref:full". The result is
+// cached and names the extra fields of the overview records read afterwards.
+func (c *Conn) OverviewFormat() (format []string, err error) {
+	if c.overviewFormat != nil {
+		return c.overviewFormat, nil
+	}
+	var msg string
+	if _, msg, err = c.cmd(215, "LIST OVERVIEW.FMT"); err != nil {
+		return
+	}
+	var r io.Reader
+	if r, err = c.dotReader(msg); err != nil {
+		return
+	}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			format = append(format, line)
+		}
+	}
+	if err = s.Err(); err != nil {
+		return
+	}
+	if len(format) < overviewFields {
+		err = fmt.Errorf("[NNTP] overview format has only %d fields: %w", len(format), ErrProtocol)
+		return
+	}
+	c.overviewFormat = format
+	return
+}
+
+// Issue the OVER command, or XOVER for servers predating RFC 3977, and iterate over the overview records of the
+// article number range in the selected group. A high value of zero or less means up to the last article.
+func (c *Conn) Over(low, high int64) (r *OverviewReader, err error) {
+	var msg string
+	if msg, err = c.rangeCommand(224, 224, "OVER", "XOVER", "", low, high); err != nil {
+		return
+	}
+	r = &OverviewReader{format: c.overviewFormat}
+	err = r.init(c, msg)
+	return
+}
+
+// Issue the HDR command, or XHDR for servers predating RFC 3977, and iterate over the values of a header of the article
+// number range in the selected group. HDR also accepts the metadata items ":bytes" and ":lines".
+func (c *Conn) Hdr(field string, low, high int64) (r *HeaderReader, err error) {
+	var msg string
+	if msg, err = c.rangeCommand(225, 221, "HDR", "XHDR", field+" ", low, high); err != nil {
+		return
+	}
+	r = &HeaderReader{}
+	err = r.init(c, msg)
+	return
+}
+
+// Issue a range command, falling back to its legacy form once if the server does not know the standard one. Returns
+// the message of the status line.
+func (c *Conn) rangeCommand(expectCode, legacyCode int, command, legacy, args string, low, high int64) (msg string, err error) {
+	used := command
+	if c.legacy[command] {
+		used, expectCode = legacy, legacyCode
+	}
+	if _, msg, err = c.cmd(expectCode, "%s %s%s", used, args, formatRange(low, high)); err == nil {
+		return
+	}
+	if e, ok := err.(*textproto.Error); ok && e.Code == 500 && used == command {
+		if c.legacy == nil {
+			c.legacy = make(map[string]bool)
+		}
+		c.legacy[command] = true
+		return c.rangeCommand(expectCode, legacyCode, command, legacy, args, low, high)
+	}
+	if e, ok := err.(*textproto.Error); ok && e.Code == 423 {
+		err = fmt.Errorf("[NNTP] no articles in range %s on %s: %w", formatRange(low, high), c.addr, ErrArticleNotFound)
+	} else {
+		err = fmt.Errorf("[NNTP] %s failed on %s: %w", used, c.addr, err)
+	}
+	return
+}
+
+func formatRange(low, high int64) string {
+	if high <= 0 {
+		return strconv.FormatInt(low, 10) + "-"
+	}
+	return strconv.FormatInt(low, 10) + "-" + strconv.FormatInt(high, 10)
+}
+
+// Reads the lines of a multi-line response one at a time.
+type lineReader struct {
+	r    *bufio.Reader
+	done bool
+}
+
+func (l *lineReader) init(c *Conn, msg string) (err error) {
+	var r io.Reader
+	if r, err = c.dotReader(msg); err == nil {
+		l.r = bufio.NewReader(r)
+	}
+	return
+}
+
+func (l *lineReader) next() (line string, err error) {
+	for !l.done {
+		line, err = l.r.ReadString('\n')
+		if err == io.EOF {
+			l.done = true
+			err = nil
+		} else if err != nil {
+			return
+		}
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			return
+		}
+	}
+	err = io.EOF
+	return
+}
+
+// Consume the rest of the response, so that the next command can be issued without reading every record.
+func (l *lineReader) Close() (err error) {
+	if !l.done {
+		_, err = io.Copy(io.Discard, l.r)
+		l.done = true
+	}
+	return
+}
+
+// Iterates over the records of an OVER/XOVER response. Records must be read until io.EOF, or the reader closed, before
+// the next command on the connection.
+type OverviewReader struct {
+	lineReader
+	format []string
+}
+
+// Return the next overview record, or io.EOF after the last one.
+func (r *OverviewReader) Next() (o Overview, err error) {
+	var line string
+	if line, err = r.next(); err != nil {
+		return
+	}
+	fields := strings.Split(line, "\t")
+	if len(fields) < 1+overviewFields {
+		err = fmt.Errorf("[NNTP] overview record has only %d fields: %w", len(fields), ErrProtocol)
+		return
+	}
+	if o.Number, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+		err = fmt.Errorf("[NNTP] invalid article number %#v in overview: %w", fields[0], ErrProtocol)
+		return
+	}
+	o.Subject = fields[1]
+	o.From = fields[2]
+	if date, err := mail.ParseDate(fields[3]); err == nil {
+		o.Date = date
+	}
+	o.MessageID = fields[4]
+	o.References = fields[5]
+	o.Bytes, _ = strconv.ParseInt(strings.TrimSpace(fields[6]), 10, 64)
+	o.Lines, _ = strconv.ParseInt(strings.TrimSpace(fields[7]), 10, 64)
+	for i, value := range fields[1+overviewFields:] {
+		name, full := "", true
+		if j := overviewFields + i; j < len(r.format) {
+			name = r.format[j]
+			if full = strings.HasSuffix(strings.ToLower(name), ":full"); full {
+				name = name[:len(name)-len("full")]
+			}
+			name = strings.TrimSuffix(name, ":")
+		}
+		// full fields carry the header name as well, e.g. "Xref: server group:1"
+		if full {
+			if k := strings.IndexByte(value, ':'); k > 0 && !strings.ContainsAny(value[:k], " \t") {
+				if name == "" {
+					name = value[:k]
+				}
+				value = strings.TrimSpace(value[k+1:])
+			}
+		}
+		if name == "" || value == "" {
+			continue
+		}
+		if o.Extra == nil {
+			o.Extra = make(map[string]string)
+		}
+		o.Extra[textproto.CanonicalMIMEHeaderKey(name)] = value
+	}
+	return
+}
+
+// Iterates over the values of an HDR/XHDR response. Values must be read until io.EOF, or the reader closed, before the
+// next command on the connection.
+type HeaderReader struct {
+	lineReader
+}
+
+// Return the article number and header value of the next article, or io.EOF after the last one.
+func (r *HeaderReader) Next() (number int64, value string, err error) {
+	var line string
+	if line, err = r.next(); err != nil {
+		return
+	}
+	n, value, _ := strings.Cut(line, " ")
+	if number, err = strconv.ParseInt(n, 10, 64); err != nil {
+		err = fmt.Errorf("[NNTP] invalid article number %#v in header response: %w", n, ErrProtocol)
+	}
+	return
+}
+
+// Iterates over the article numbers of a LISTGROUP response. Numbers must be read until io.EOF, or the reader closed,
+// before the next command on the connection.
+type NumberReader struct {
+	lineReader
+}
+
+// Return the next article number, or io.EOF after the last one.
+func (r *NumberReader) Next() (number int64, err error) {
+	var line string
+	if line, err = r.next(); err != nil {
+		return
+	}
+	if number, err = strconv.ParseInt(strings.TrimSpace(line), 10, 64); err != nil {
+		err = fmt.Errorf("[NNTP] invalid article number %#v in group listing: %w", line, ErrProtocol)
+	}
+	return
+}
@@ -0,0 +1,288 @@
+package nntp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"gopkg.in/yenc.v0/nntp/nntptest"
+)
+
+const testGroup = "alt.binaries.test"
+
+var testDate = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// Articles numbered 1 to count in testGroup, with a yEnc subject of a multipart post.
+func groupArticles(count int) map[string][]byte {
+	articles := make(map[string][]byte, count)
+	for i := 1; i <= count; i++ {
+		id := fmt.Sprintf("part%03d@nntptest", i)
+		articles[id] = []byte(fmt.Sprintf("From: poster <poster@example.com>\r\n"+
+			"Newsgroups: %s\r\n"+
+			"Subject: [1/1] - \"file.bin\" yEnc (%d/%d) 1000\r\n"+
+			"Message-ID: <%s>\r\n"+
+			"Date: %s\r\n"+
+			"\r\n"+
+			"=ybegin part=%d total=%d line=128 size=1000 name=file.bin\r\n"+
+			"data\r\n"+
+			"=yend\r\n", testGroup, i, count, id, testDate.Add(time.Duration(i)*time.Minute).Format(time.RFC1123Z), i, count))
+	}
+	return articles
+}
+
+func readOverviews(t *testing.T, r *OverviewReader) (overviews []Overview) {
+	for {
+		o, err := r.Next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		overviews = append(overviews, o)
+	}
+}
+
+func TestOver(t *testing.T) {
+	articles := groupArticles(20)
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer srv.Close()
+	c, err := Dial(context.Background(), srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit()
+
+	g, err := c.Group(testGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g != (Group{Name: testGroup, Count: 20, Low: 1, High: 20}) {
+		t.Fatalf("unexpected group status %+v", g)
+	}
+	format, err := c.OverviewFormat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(format) != 8 || format[7] != "Xref:full" {
+		t.Fatalf("unexpected overview format %#v", format)
+	}
+
+	r, err := c.Over(5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overviews := readOverviews(t, r)
+	if len(overviews) != 16 {
+		t.Fatalf("expect 16 records but got %d", len(overviews))
+	}
+	for i, o := range overviews {
+		n := int64(5 + i)
+		id := fmt.Sprintf("part%03d@nntptest", n)
+		if o.Number != n || o.MessageID != "<"+id+">" {
+			t.Errorf("record %d is article %d %s", n, o.Number, o.MessageID)
+		}
+		if expect := fmt.Sprintf(`[1/1] - "file.bin" yEnc (%d/20) 1000`, n); o.Subject != expect {
+			t.Errorf("expect subject %#v but got %#v", expect, o.Subject)
+		}
+		if !o.Date.Equal(testDate.Add(time.Duration(n) * time.Minute)) {
+			t.Errorf("unexpected date %v of article %d", o.Date, n)
+		}
+		if o.From != "poster <poster@example.com>" || o.Bytes != int64(len(articles[id])) || o.Lines != 3 {
+			t.Errorf("unexpected record %+v", o)
+		}
+		if xref := o.Extra["Xref"]; xref != fmt.Sprintf("nntptest %s:%d", testGroup, n) {
+			t.Errorf("unexpected Xref %#v", xref)
+		}
+	}
+
+	// abandon a listing halfway, then issue the next command
+	if r, err = c.Over(1, 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Next(); err != io.EOF {
+		t.Fatalf("expect io.EOF after Close but got %v", err)
+	}
+	if _, err = c.Over(30, 40); !errors.Is(err, ErrArticleNotFound) {
+		t.Fatalf("expect ErrArticleNotFound for an empty range but got %v", err)
+	}
+	if _, err = c.Group("alt.binaries.none"); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("expect ErrGroupNotFound but got %v", err)
+	}
+}
+
+func TestHdrAndListGroup(t *testing.T) {
+	articles := groupArticles(10)
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer srv.Close()
+	srv.RemoveArticle("part004@nntptest")
+	c, err := Dial(context.Background(), srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit()
+
+	g, numbers, err := c.ListGroup(testGroup, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.High != 10 {
+		t.Errorf("unexpected group status %+v", g)
+	}
+	var listed []int64
+	for {
+		n, err := numbers.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		listed = append(listed, n)
+	}
+	if fmt.Sprint(listed) != "[1 2 3 5 6 7 8 9 10]" {
+		t.Errorf("unexpected article numbers %v", listed)
+	}
+
+	r, err := c.Hdr("Message-ID", 3, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for {
+		n, value, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, fmt.Sprintf("%d %s", n, value))
+	}
+	if fmt.Sprint(values) != "[3 <part003@nntptest> 5 <part005@nntptest> 6 <part006@nntptest>]" {
+		t.Errorf("unexpected header values %v", values)
+	}
+}
+
+func TestXOverFallback(t *testing.T) {
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(groupArticles(5)), nntptest.ServerWithoutOver())
+	defer srv.Close()
+	c, err := Dial(context.Background(), srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit()
+	if _, err = c.Group(testGroup); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		r, err := c.Over(1, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// without the format, the Xref field is named after its content
+		if overviews := readOverviews(t, r); len(overviews) != 5 || overviews[4].Extra["Xref"] == "" {
+			t.Fatalf("unexpected records %+v", overviews)
+		}
+		h, err := c.Hdr(":bytes", 1, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, value, err := h.Next(); err != nil || value == "" {
+			t.Fatalf("unexpected :bytes value %#v: %v", value, err)
+		}
+		h.Close()
+	}
+	if srv.Count("OVER") != 1 || srv.Count("XOVER") != 2 || srv.Count("HDR") != 1 || srv.Count("XHDR") != 2 {
+		t.Errorf("expect one OVER and HDR attempt and then XOVER and XHDR only, but got %d OVER, %d XOVER, %d HDR, %d XHDR",
+			srv.Count("OVER"), srv.Count("XOVER"), srv.Count("HDR"), srv.Count("XHDR"))
+	}
+}
+
+func TestOverGzip(t *testing.T) {
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(groupArticles(50)), nntptest.ServerWithXFeatureGzip())
+	defer srv.Close()
+	c, err := Dial(context.Background(), srv.Addr, DialWithCompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit()
+	if c.Compression() != CompressGzip {
+		t.Fatalf("expect gzip compression but got %#v", c.Compression())
+	}
+	if _, err = c.Group(testGroup); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.OverviewFormat(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.Over(1, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overviews := readOverviews(t, r); len(overviews) != 50 || overviews[49].Number != 50 {
+		t.Fatalf("unexpected records %+v", overviews)
+	}
+	if _, err = c.Date(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Servers may send small responses uncompressed even with XFEATURE COMPRESS GZIP, without the [COMPRESS=GZIP] marker.
+// Text like "80" or "XG" passes the zlib header check bits, so it must not be mistaken for compressed data.
+func TestOverGzipUncompressed(t *testing.T) {
+	articles := groupArticles(85)
+	articles["xg@nntptest"] = []byte("XGuard: on\r\nMessage-ID: <xg@nntptest>\r\n\r\nbody\r\n")
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(articles),
+		nntptest.ServerWithXFeatureGzip(), nntptest.ServerWithGzipThreshold(1<<20))
+	defer srv.Close()
+	c, err := Dial(context.Background(), srv.Addr, DialWithCompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit()
+	if c.Compression() != CompressGzip {
+		t.Fatalf("expect gzip compression but got %#v", c.Compression())
+	}
+	_, numbers, err := c.ListGroup(testGroup, 80, 85)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(80); i <= 85; i++ {
+		if n, err := numbers.Next(); err != nil || n != i {
+			t.Fatalf("expect article number %d but got %d: %v", i, n, err)
+		}
+	}
+	if _, err = numbers.Next(); err != io.EOF {
+		t.Fatalf("expect end of article numbers but got %v", err)
+	}
+	if _, err = c.OverviewFormat(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.Over(80, 85)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overviews := readOverviews(t, r); len(overviews) != 6 || overviews[0].Number != 80 {
+		t.Fatalf("unexpected records %+v", overviews)
+	}
+	h, err := c.Head("xg@nntptest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Get("XGuard") != "on" {
+		t.Errorf("unexpected header %+v", h)
+	}
+	if _, err = c.Date(); err != nil {
+		t.Fatal(err)
+	}
+}
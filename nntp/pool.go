@@ -0,0 +1,486 @@
+package nntp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/option.v0"
+	"gopkg.in/yenc.v0"
+)
+
+// Connection settings of one NNTP server (or provider account) in a Pool.
+type Server struct {
+	Addr     string
+	Username string
+	Password string
+	MaxConns int // Max number of concurrent connections to this server. Zero means 1.
+
+	// Servers with a lower Priority value are tried first. Servers sharing the same value form a tier and share the
+	// load. Servers of the next tier are only used if the article is missing or broken on every server of the
+	// previous tiers.
+	Priority int
+
+	Options []DialOption // Additional options used when dialing this server
+}
+
+// A decoded article body fetched from the pool.
+type Part struct {
+	MessageID string
+	Server    string // Address of the server the article was fetched from
+	yenc.Segment
+}
+
+// A pool of NNTP connections spread over multiple servers with priority tiers and failover. A Pool is safe for
+// concurrent use.
+type Pool struct {
+	tiers [][]*server
+
+	mu     sync.Mutex
+	notify chan struct{} // closed and replaced whenever a connection slot is released
+	closed chan struct{}
+	wg     sync.WaitGroup
+
+	dialOptions    []DialOption
+	decodeOptions  []yenc.DecodeOption
+	idleTimeout    time.Duration
+	healthInterval time.Duration
+	retryBackoff   time.Duration
+}
+
+type server struct {
+	Server
+	pool *Pool
+
+	mu        sync.Mutex
+	idle      []*Conn
+	active    int // connections checked out or being dialed
+	downUntil time.Time
+}
+
+func NewPool(servers []Server, options ...PoolOption) (p *Pool, err error) {
+	if len(servers) == 0 {
+		err = fmt.Errorf("[NNTP] empty server list: %w", ErrNoServers)
+		return
+	}
+	p = option.New(options,
+		PoolWithIdleTimeout(DefaultIdleTimeout),
+		PoolWithHealthCheck(DefaultHealthCheckInterval),
+		PoolWithRetryBackoff(DefaultRetryBackoff))
+	p.notify = make(chan struct{})
+	p.closed = make(chan struct{})
+	sorted := make([]*server, 0, len(servers))
+	for _, cfg := range servers {
+		if cfg.MaxConns <= 0 {
+			cfg.MaxConns = 1
+		}
+		sorted = append(sorted, &server{Server: cfg, pool: p})
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+	for i, s := range sorted {
+		if i == 0 || s.Priority != sorted[i-1].Priority {
+			p.tiers = append(p.tiers, nil)
+		}
+		p.tiers[len(p.tiers)-1] = append(p.tiers[len(p.tiers)-1], s)
+	}
+	if p.healthInterval > 0 || p.idleTimeout > 0 {
+		p.wg.Add(1)
+		go p.maintain()
+	}
+	return
+}
+
+// Fetch an article body by its message-ID and decode it. If the article is missing (430), fails to download or fails
+// to decode on a server, it is retried on the other servers of the same tier, then on the servers of the next tiers.
+// If every server fails, the error of the last attempt is returned, which wraps ErrArticleNotFound if the article is
+// missing everywhere.
+func (p *Pool) Fetch(ctx context.Context, messageID string) (part *Part, err error) {
+	var addr string
+	addr, err = p.Do(ctx, func(c *Conn) (err error) {
+		var body []byte
+		if body, err = readBody(ctx, c, messageID); err != nil {
+			return
+		}
+		var segment *yenc.Segment
+		if segment, err = yenc.DecodeSegment(bytes.NewReader(body), p.decodeOptions...); err != nil {
+			return
+		}
+		part = &Part{Segment: *segment}
+		return
+	})
+	if err != nil {
+		part = nil
+		return
+	}
+	part.MessageID = FormatMessageID(messageID)
+	part.Server = addr
+	return
+}
+
+// Run fn with a pooled connection, retrying on the next server in priority order if it fails. Errors wrapping
+// ErrArticleNotFound, yenc.ErrInvalidFormat or yenc.ErrDataCorruption are considered article errors and the connection
+// is reused; any other error closes the connection. If that connection was an idle one, which may have gone stale,
+// fn is retried on the same server with a new connection first. Returns the address of the server where fn succeeded.
+func (p *Pool) Do(ctx context.Context, fn func(c *Conn) error) (addr string, err error) {
+	var (
+		s       *server
+		c       *Conn
+		reused  bool
+		lastErr error
+		tried   = make(map[*server]bool)
+		redial  = make(map[*server]bool) // servers whose idle connections are not to be trusted
+	)
+	for {
+		if s, c, reused, err = p.get(ctx, tried, redial); err != nil {
+			if errors.Is(err, ErrNoServers) && lastErr != nil {
+				err = lastErr
+			}
+			return
+		}
+		err = fn(c)
+		if err == nil {
+			s.put(c)
+			addr = s.Addr
+			return
+		}
+		if ctx.Err() != nil {
+			s.drop(c)
+			err = ctx.Err()
+			return
+		}
+		lastErr = err
+		if errors.Is(err, ErrArticleNotFound) || errors.Is(err, yenc.ErrInvalidFormat) || errors.Is(err, yenc.ErrDataCorruption) {
+			s.put(c)
+		} else if s.drop(c); reused {
+			redial[s] = true
+			continue
+		}
+		tried[s] = true
+	}
+}
+
+// Get a connection from the first tier that has a server not yet tried and not backing off. If every such server in
+// that tier is at its connection limit, wait for a slot rather than spilling over to the next tier. Servers in redial
+// get a new connection rather than an idle one. Returns whether the connection was an idle one.
+func (p *Pool) get(ctx context.Context, tried, redial map[*server]bool) (s *server, c *Conn, reused bool, err error) {
+	var dialErr error
+	for {
+		p.mu.Lock()
+		notify := p.notify
+		p.mu.Unlock()
+		select {
+		case <-p.closed:
+			err = ErrPoolClosed
+			return
+		default:
+		}
+		var candidates []*server
+		now := time.Now()
+		for _, tier := range p.tiers {
+			for _, t := range tier {
+				if !tried[t] && !t.isDown(now) {
+					candidates = append(candidates, t)
+				}
+			}
+			if len(candidates) > 0 {
+				break
+			}
+		}
+		if len(candidates) == 0 {
+			if err = dialErr; err == nil {
+				err = fmt.Errorf("[NNTP] all servers tried or unavailable: %w", ErrNoServers)
+			}
+			return
+		}
+		if s = reserve(candidates); s == nil {
+			select {
+			case <-notify:
+				continue
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			case <-p.closed:
+				err = ErrPoolClosed
+				return
+			}
+		}
+		if c, reused, err = s.conn(ctx, !redial[s]); err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+				return
+			}
+			// the server is now backing off, try the others
+			dialErr = err
+			continue
+		}
+		return
+	}
+}
+
+// Reserve a connection slot on a candidate, preferring those with idle connections, then the least busy ones.
+func reserve(candidates []*server) *server {
+	type load struct {
+		s    *server
+		idle bool
+		free int
+	}
+	loads := make([]load, 0, len(candidates))
+	for _, t := range candidates {
+		t.mu.Lock()
+		loads = append(loads, load{t, len(t.idle) > 0, t.MaxConns - t.active})
+		t.mu.Unlock()
+	}
+	sort.SliceStable(loads, func(i, j int) bool {
+		if loads[i].idle != loads[j].idle {
+			return loads[i].idle
+		}
+		return loads[i].free > loads[j].free
+	})
+	for _, l := range loads {
+		l.s.mu.Lock()
+		if l.s.active < l.s.MaxConns {
+			l.s.active++
+			l.s.mu.Unlock()
+			return l.s
+		}
+		l.s.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *server) isDown(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Before(s.downUntil)
+}
+
+// Get an idle connection if reuse is true, or dial a new one. The connection slot must have been reserved. On failure
+// the slot is released. Idle connections count toward MaxConns, so the oldest ones are closed to make room for a new
+// connection if needed.
+func (s *server) conn(ctx context.Context, reuse bool) (c *Conn, reused bool, err error) {
+	for reuse {
+		s.mu.Lock()
+		if n := len(s.idle); n > 0 {
+			c = s.idle[n-1]
+			s.idle = s.idle[:n-1]
+		}
+		s.mu.Unlock()
+		if c == nil {
+			break
+		}
+		if s.pool.healthInterval <= 0 || time.Since(c.lastUsed) < s.pool.healthInterval {
+			reused = true
+			return
+		}
+		// the connection has been idle for a while, make sure it is still alive before handing it out
+		if _, err = c.Date(); err == nil {
+			reused = true
+			return
+		}
+		c.Close()
+		c = nil
+	}
+	var surplus []*Conn
+	s.mu.Lock()
+	if n := s.active + len(s.idle) - s.MaxConns; n > 0 {
+		surplus = append(surplus, s.idle[:n]...)
+		s.idle = s.idle[n:]
+	}
+	s.mu.Unlock()
+	for _, c := range surplus {
+		c.Close()
+	}
+	options := append(append([]DialOption(nil), s.pool.dialOptions...), s.Options...)
+	if s.Username != "" {
+		options = append(options, DialWithAuth(s.Username, s.Password))
+	}
+	if c, err = Dial(ctx, s.Addr, options...); err != nil {
+		s.mu.Lock()
+		s.downUntil = time.Now().Add(s.pool.retryBackoff)
+		s.mu.Unlock()
+		s.release()
+	}
+	return
+}
+
+// Return a healthy connection to the idle list and release its slot.
+func (s *server) put(c *Conn) {
+	select {
+	case <-s.pool.closed:
+		c.Quit()
+	default:
+		s.mu.Lock()
+		s.idle = append(s.idle, c)
+		s.mu.Unlock()
+	}
+	s.release()
+}
+
+// Close a broken connection and release its slot.
+func (s *server) drop(c *Conn) {
+	c.Close()
+	s.release()
+}
+
+func (s *server) release() {
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	p := s.pool
+	p.mu.Lock()
+	close(p.notify)
+	p.notify = make(chan struct{})
+	p.mu.Unlock()
+}
+
+// Periodically close connections idle for too long and health check the others.
+func (p *Pool) maintain() {
+	defer p.wg.Done()
+	interval := p.healthInterval
+	if interval <= 0 || (p.idleTimeout > 0 && p.idleTimeout < interval) {
+		interval = p.idleTimeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.closed:
+			return
+		case <-ticker.C:
+		}
+		for _, tier := range p.tiers {
+			for _, s := range tier {
+				s.check()
+			}
+		}
+	}
+}
+
+func (s *server) check() {
+	s.mu.Lock()
+	idle := s.idle
+	s.idle = nil
+	s.active += len(idle)
+	s.mu.Unlock()
+	for _, c := range idle {
+		idleFor := time.Since(c.lastUsed)
+		if s.pool.idleTimeout > 0 && idleFor >= s.pool.idleTimeout {
+			c.Quit()
+			s.release()
+			continue
+		}
+		if s.pool.healthInterval > 0 && idleFor >= s.pool.healthInterval {
+			if _, err := c.Date(); err != nil {
+				s.drop(c)
+				continue
+			}
+		}
+		s.put(c)
+	}
+}
+
+// Close all idle connections and stop the pool. Connections in use are closed when they are returned.
+func (p *Pool) Close() (err error) {
+	select {
+	case <-p.closed:
+		return
+	default:
+	}
+	close(p.closed)
+	p.wg.Wait()
+	for _, tier := range p.tiers {
+		for _, s := range tier {
+			s.mu.Lock()
+			idle := s.idle
+			s.idle = nil
+			s.mu.Unlock()
+			for _, c := range idle {
+				c.Quit()
+			}
+		}
+	}
+	return
+}
+
+// Read the whole article body, aborting the transfer if ctx is done. The watcher is stopped before returning, so that
+// it cannot set a deadline on the connection once it is back in the pool.
+func readBody(ctx context.Context, c *Conn, messageID string) (body []byte, err error) {
+	stop, done := make(chan struct{}), make(chan struct{})
+	aborted := false
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			c.conn.SetDeadline(time.Unix(1, 0))
+			aborted = true
+		case <-stop:
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-done
+		if aborted && err == nil {
+			// ctx was done right as the body was read in full, the connection is still usable
+			c.conn.SetDeadline(time.Time{})
+		}
+	}()
+	var r io.Reader
+	if r, err = c.Body(messageID); err != nil {
+		return
+	}
+	if body, err = io.ReadAll(r); err != nil {
+		err = fmt.Errorf("[NNTP] failed to read article %s from %s: %w", FormatMessageID(messageID), c.addr, err)
+	}
+	return
+}
+
+// Default duration after which an unused connection is closed.
+var DefaultIdleTimeout = 5 * time.Minute
+
+// Default duration after which an unused connection is checked with the DATE command before reuse.
+var DefaultHealthCheckInterval = 30 * time.Second
+
+// Default duration a server is skipped for after failing to connect.
+var DefaultRetryBackoff = 10 * time.Second
+
+type PoolOption func(*Pool)
+
+// Options applied to every connection, before the per-server options.
+func PoolWithDialOptions(options ...DialOption) PoolOption {
+	return func(p *Pool) {
+		p.dialOptions = options
+	}
+}
+
+func PoolWithDecodeOptions(options ...yenc.DecodeOption) PoolOption {
+	return func(p *Pool) {
+		p.decodeOptions = options
+	}
+}
+
+// Close connections that are idle for longer than d. Zero disables idle timeout.
+func PoolWithIdleTimeout(d time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.idleTimeout = d
+	}
+}
+
+// Check connections that are idle for longer than d with the DATE command, both periodically and before reuse. Zero
+// disables health checks.
+func PoolWithHealthCheck(d time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.healthInterval = d
+	}
+}
+
+// Skip a server for d after it fails to connect.
+func PoolWithRetryBackoff(d time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.retryBackoff = d
+	}
+}
@@ -0,0 +1,358 @@
+package nntp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yenc.v0/nntp/nntptest"
+)
+
+func loadArticles(t *testing.T, prefix string, count int) map[string][]byte {
+	articles := make(map[string][]byte)
+	for i := 1; i <= count; i++ {
+		b, err := os.ReadFile(fmt.Sprintf("../fixture/%s-%03d.ntx", prefix, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		articles[fmt.Sprintf("%s-%03d@nntptest", prefix, i)] = b
+	}
+	return articles
+}
+
+func fetchAll(t *testing.T, p *Pool, prefix string, count int) (parts []*Part) {
+	for i := 1; i <= count; i++ {
+		part, err := p.Fetch(context.Background(), fmt.Sprintf("%s-%03d@nntptest", prefix, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, part)
+	}
+	return
+}
+
+func compareRaw(t *testing.T, prefix string, parts []*Part) {
+	raw, err := os.ReadFile(fmt.Sprintf("../fixture/%s-raw.bin", prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	for _, part := range parts {
+		if part.Header.Begin != uint64(b.Len()) {
+			t.Errorf("part %d begins at %d but %d bytes were decoded before it", part.Header.Part, part.Header.Begin, b.Len())
+		}
+		b.Write(part.Data)
+	}
+	if !bytes.Equal(raw, b.Bytes()) {
+		t.Errorf("%s fetch output mismatch!", prefix)
+	}
+}
+
+func TestPoolFetch(t *testing.T) {
+	srv := nntptest.NewServer(
+		nntptest.ServerWithArticles(loadArticles(t, "ngPost", 10)),
+		nntptest.ServerWithAuth("user", "pass"))
+	defer srv.Close()
+
+	p, err := NewPool([]Server{{Addr: srv.Addr, Username: "user", Password: "pass", MaxConns: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	parts := fetchAll(t, p, "ngPost", 10)
+	compareRaw(t, "ngPost", parts)
+	if parts[9].Trailer.Size != parts[9].Header.End-parts[9].Header.Begin {
+		t.Errorf("trailer size %d mismatch with header", parts[9].Trailer.Size)
+	}
+	if srv.Accepted() != 1 {
+		t.Errorf("expect sequential fetches to reuse 1 idle connection but %d were made", srv.Accepted())
+	}
+}
+
+func TestPoolAuthFailure(t *testing.T) {
+	srv := nntptest.NewServer(nntptest.ServerWithAuth("user", "pass"))
+	defer srv.Close()
+
+	p, err := NewPool([]Server{{Addr: srv.Addr, Username: "user", Password: "wrong"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if _, err = p.Fetch(context.Background(), "x@nntptest"); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("expect authentication error but got %v", err)
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	articles := loadArticles(t, "yenc32", 10)
+	primary := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer primary.Close()
+	primary.RemoveArticle("yenc32-003@nntptest")
+	backup := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer backup.Close()
+
+	p, err := NewPool([]Server{
+		{Addr: backup.Addr, MaxConns: 2, Priority: 1},
+		{Addr: primary.Addr, MaxConns: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	parts := fetchAll(t, p, "yenc32", 10)
+	compareRaw(t, "yenc32", parts)
+	for i, part := range parts {
+		expect := primary.Addr
+		if i == 2 {
+			expect = backup.Addr
+		}
+		if part.Server != expect {
+			t.Errorf("part %d: expect to be fetched from %s but got %s", i+1, expect, part.Server)
+		}
+	}
+	if backup.Count("BODY") != 1 {
+		t.Errorf("expect backup server to be asked only once but got %d", backup.Count("BODY"))
+	}
+
+	backup.RemoveArticle("yenc32-003@nntptest")
+	if _, err = p.Fetch(context.Background(), "yenc32-003@nntptest"); !errors.Is(err, ErrArticleNotFound) {
+		t.Errorf("expect article not found but got %v", err)
+	}
+}
+
+func TestPoolUnreachableServer(t *testing.T) {
+	articles := loadArticles(t, "ngPost", 1)
+	down := nntptest.NewServer(nntptest.ServerWithGreeting("502 service permanently unavailable"))
+	defer down.Close()
+	up := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer up.Close()
+
+	p, err := NewPool([]Server{{Addr: down.Addr}, {Addr: up.Addr, Priority: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		part, err := p.Fetch(context.Background(), "ngPost-001@nntptest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.Server != up.Addr {
+			t.Errorf("expect part to be fetched from %s but got %s", up.Addr, part.Server)
+		}
+	}
+	if down.Accepted() != 1 {
+		t.Errorf("expect unavailable server to be backed off after 1 attempt but got %d", down.Accepted())
+	}
+}
+
+func TestPoolConnectionLimit(t *testing.T) {
+	articles := loadArticles(t, "encode", 10)
+	a := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer a.Close()
+	b := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer b.Close()
+
+	p, err := NewPool([]Server{{Addr: a.Addr, MaxConns: 2}, {Addr: b.Addr, MaxConns: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for n := 0; n < 10; n++ {
+		for i := 1; i <= 10; i++ {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				if _, err := p.Fetch(context.Background(), id); err != nil {
+					errs <- err
+				}
+			}(fmt.Sprintf("encode-%03d@nntptest", i))
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if a.Accepted() > 2 || b.Accepted() > 3 {
+		t.Errorf("connection limit exceeded: %d and %d connections made", a.Accepted(), b.Accepted())
+	}
+	if a.Count("BODY") == 0 || b.Count("BODY") == 0 {
+		t.Errorf("expect servers of the same tier to share the load but got %d and %d", a.Count("BODY"), b.Count("BODY"))
+	}
+}
+
+// A new connection dialed rather than taken from the idle ones, like one replacing a stale connection, must not exceed
+// the limit together with the idle ones.
+func TestPoolConnectionLimitIdle(t *testing.T) {
+	srv := nntptest.NewServer()
+	defer srv.Close()
+
+	p, err := NewPool([]Server{{Addr: srv.Addr, MaxConns: 2}}, PoolWithHealthCheck(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	s := p.tiers[0][0]
+	var conns []*Conn
+	for i := 0; i < 2; i++ {
+		if reserve([]*server{s}) != s {
+			t.Fatal("expect a free connection slot")
+		}
+		c, _, err := s.conn(context.Background(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+	}
+	for _, c := range conns {
+		s.put(c)
+	}
+	if reserve([]*server{s}) != s {
+		t.Fatal("expect a free connection slot")
+	}
+	c, _, err := s.conn(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.put(c)
+	s.mu.Lock()
+	open := s.active + len(s.idle)
+	s.mu.Unlock()
+	if open != 2 || c == conns[0] || c == conns[1] {
+		t.Errorf("expect a new connection and one idle connection closed but got %d open connections", open)
+	}
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(loadArticles(t, "ngPost", 2)))
+	defer srv.Close()
+
+	p, err := NewPool([]Server{{Addr: srv.Addr}}, PoolWithHealthCheck(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if _, err = p.Fetch(context.Background(), "ngPost-001@nntptest"); err != nil {
+		t.Fatal(err)
+	}
+	srv.CloseConnections()
+	time.Sleep(10 * time.Millisecond)
+	if _, err = p.Fetch(context.Background(), "ngPost-002@nntptest"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Accepted() != 2 {
+		t.Errorf("expect the dropped connection to be replaced but got %d connections", srv.Accepted())
+	}
+}
+
+func TestPoolStaleConnection(t *testing.T) {
+	articles := loadArticles(t, "ngPost", 2)
+	primary := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer primary.Close()
+	backup := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer backup.Close()
+
+	p, err := NewPool([]Server{{Addr: primary.Addr}, {Addr: backup.Addr, Priority: 1}}, PoolWithHealthCheck(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if _, err = p.Fetch(context.Background(), "ngPost-001@nntptest"); err != nil {
+		t.Fatal(err)
+	}
+	// without health checks the idle connection is handed out dead, and must be replaced rather than failed over
+	primary.CloseConnections()
+	part, err := p.Fetch(context.Background(), "ngPost-002@nntptest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.Server != primary.Addr || backup.Accepted() != 0 {
+		t.Errorf("expect a new connection to %s but got the part from %s", primary.Addr, part.Server)
+	}
+}
+
+func TestPoolContextCancel(t *testing.T) {
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(loadArticles(t, "ngPost", 1)))
+	defer srv.Close()
+
+	p, err := NewPool([]Server{{Addr: srv.Addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// hold the only connection slot so the next fetch has to wait
+	_, err = p.Do(context.Background(), func(c *Conn) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := p.Fetch(ctx, "ngPost-001@nntptest")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expect deadline exceeded but got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolClosed(t *testing.T) {
+	p, err := NewPool([]Server{{Addr: "127.0.0.1:1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	if _, err = p.Fetch(context.Background(), "x@nntptest"); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("expect pool closed error but got %v", err)
+	}
+}
+
+func TestFormatMessageID(t *testing.T) {
+	for _, id := range []string{"abc@example", "<abc@example>", " <abc@example"} {
+		if FormatMessageID(id) != "<abc@example>" {
+			t.Errorf("%#v formatted as %#v", id, FormatMessageID(id))
+		}
+	}
+}
+
+func TestFetchRejectsCorruption(t *testing.T) {
+	articles := loadArticles(t, "ngPost", 1)
+	corrupt := map[string][]byte{"ngPost-001@nntptest": bytes.Replace(articles["ngPost-001@nntptest"],
+		[]byte("pcrc32=798b8081"), []byte("pcrc32=00000000"), 1)}
+	primary := nntptest.NewServer(nntptest.ServerWithArticles(corrupt))
+	defer primary.Close()
+	backup := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer backup.Close()
+
+	p, err := NewPool([]Server{{Addr: primary.Addr}, {Addr: backup.Addr, Priority: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	// the yenc package reports CRC mismatch with ErrInvalidFormat, which makes the pool retry elsewhere
+	part, err := p.Fetch(context.Background(), "ngPost-001@nntptest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.Server != backup.Addr {
+		t.Errorf("expect the corrupted part to be fetched from %s but got it from %s", backup.Addr, part.Server)
+	}
+	if _, err = p.Fetch(context.Background(), "ngPost-001@nntptest"); err != nil || primary.Accepted() != 1 {
+		t.Errorf("expect the connection to be reused after a corrupted part but got %d connections: %v", primary.Accepted(), err)
+	}
+}
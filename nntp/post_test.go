@@ -0,0 +1,66 @@
+package nntp
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"gopkg.in/yenc.v0"
+	"gopkg.in/yenc.v0/nntp/nntptest"
+)
+
+func TestPostArticle(t *testing.T) {
+	raw, err := os.ReadFile("../fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := nntptest.NewServer()
+	defer srv.Close()
+
+	c, err := Dial(context.Background(), srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Quit()
+	w, err := c.Post()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := yenc.EncodeArticle(w, yenc.Article{
+		From:       "poster@example.com",
+		Newsgroups: []string{"alt.binaries.test"},
+		Domain:     "example.com",
+	}, "ngPost-raw.bin", uint64(len(raw)), yenc.EncodeWithPart(1, 1, 0, uint64(len(raw))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Write(append([]byte(nil), raw...)); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := c.Head(a.Article().MessageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Get("Subject") != a.Article().Subject {
+		t.Errorf("unexpected subject %#v", h.Get("Subject"))
+	}
+	p, err := NewPool([]Server{{Addr: srv.Addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	part, err := p.Fetch(context.Background(), a.Article().MessageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(part.Data) != string(raw) {
+		t.Error("posted article decode mismatch")
+	}
+}
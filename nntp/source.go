@@ -0,0 +1,31 @@
+package nntp
+
+import (
+	"context"
+
+	"gopkg.in/yenc.v0"
+)
+
+// Source of the parts of a file posted as the given articles, in part order, fetched through the pool with failover
+// as by Fetch. Typically given the message-IDs of the segments of an NZB file, to read it with yenc.MultipartFile.
+func (p *Pool) Source(messageIDs []string) yenc.SegmentSource {
+	return &source{pool: p, messageIDs: messageIDs}
+}
+
+type source struct {
+	pool       *Pool
+	messageIDs []string
+}
+
+func (s *source) Count() int {
+	return len(s.messageIDs)
+}
+
+func (s *source) Fetch(ctx context.Context, i int) (segment *yenc.Segment, err error) {
+	var part *Part
+	if part, err = s.pool.Fetch(ctx, s.messageIDs[i]); err != nil {
+		return
+	}
+	segment = &part.Segment
+	return
+}
@@ -0,0 +1,47 @@
+package nntp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"gopkg.in/yenc.v0"
+	"gopkg.in/yenc.v0/nntp/nntptest"
+)
+
+func TestPoolSource(t *testing.T) {
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(loadArticles(t, "ngPost", 10)))
+	defer srv.Close()
+	p, err := NewPool([]Server{{Addr: srv.Addr, MaxConns: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	var messageIDs []string
+	for i := 1; i <= 10; i++ {
+		messageIDs = append(messageIDs, fmt.Sprintf("ngPost-%03d@nntptest", i))
+	}
+	f, err := yenc.NewMultipartFile(context.Background(), p.Source(messageIDs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	raw, err := os.ReadFile("../fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1000)
+	if _, err = f.ReadAt(b, 3000); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, raw[3000:4000]) {
+		t.Error("data mismatch")
+	}
+	all, err := io.ReadAll(f)
+	if err != nil || !bytes.Equal(all, raw) {
+		t.Errorf("unexpected read of %d bytes: %v", len(all), err)
+	}
+}
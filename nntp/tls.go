@@ -0,0 +1,118 @@
+package nntp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+)
+
+// Upgrade the connection to TLS, either right after connecting (implicit TLS, usually port 563) or after the STARTTLS
+// command (RFC 4642).
+func (c *Conn) handshakeTLS(ctx context.Context) (err error) {
+	config := &tls.Config{}
+	if c.tlsConfig != nil {
+		config = c.tlsConfig.Clone()
+	}
+	if config.ServerName == "" {
+		if config.ServerName, _, err = net.SplitHostPort(c.addr); err != nil {
+			config.ServerName = c.addr
+			err = nil
+		}
+	}
+	tc := tls.Client(c.conn, config)
+	if err = tc.HandshakeContext(ctx); err != nil {
+		var (
+			unknownAuthority x509.UnknownAuthorityError
+			hostname         x509.HostnameError
+			invalid          x509.CertificateInvalidError
+		)
+		if errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid) {
+			err = fmt.Errorf("[NNTP] certificate of %s: %v: %w", c.addr, err, ErrCertificateVerification)
+		} else {
+			err = fmt.Errorf("[NNTP] TLS handshake with %s failed: %v: %w", c.addr, err, ErrTLSHandshake)
+		}
+		return
+	}
+	if len(c.pins) > 0 {
+		if err = c.verifyPins(tc.ConnectionState()); err != nil {
+			tc.Close()
+			return
+		}
+	}
+	c.conn = tc
+	c.text = textproto.NewConn(tc)
+	return
+}
+
+func (c *Conn) verifyPins(state tls.ConnectionState) (err error) {
+	if len(state.PeerCertificates) == 0 {
+		err = fmt.Errorf("[NNTP] %s presented no certificate: %w", c.addr, ErrCertificatePin)
+		return
+	}
+	pin := PublicKeyPin(state.PeerCertificates[0])
+	for _, p := range c.pins {
+		if bytes.Equal(p, pin) {
+			return
+		}
+	}
+	err = fmt.Errorf("[NNTP] public key of %s with pin %x is not pinned: %w", c.addr, pin, ErrCertificatePin)
+	return
+}
+
+func (c *Conn) startTLS(ctx context.Context) (err error) {
+	if _, _, err = c.cmd(382, "STARTTLS"); err != nil {
+		err = fmt.Errorf("[NNTP] %s refused STARTTLS: %v: %w", c.addr, err, ErrTLSUnavailable)
+		return
+	}
+	return c.handshakeTLS(ctx)
+}
+
+// The TLS connection state, or false if the connection is not encrypted.
+func (c *Conn) TLSConnectionState() (state tls.ConnectionState, ok bool) {
+	var tc *tls.Conn
+	if tc, ok = c.conn.(*tls.Conn); ok {
+		state = tc.ConnectionState()
+	}
+	return
+}
+
+// SHA-256 hash of the DER encoded SubjectPublicKeyInfo of a certificate, as used by DialWithPinnedKeys.
+func PublicKeyPin(cert *x509.Certificate) []byte {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return sum[:]
+}
+
+// Connect with implicit TLS, as NNTPS servers on port 563 expect. If config.ServerName is empty, the host part of the
+// server address is used. A nil config verifies the server against the system roots.
+func DialWithTLS(config *tls.Config) DialOption {
+	return func(c *Conn) {
+		c.tlsConfig = config
+		c.useTLS = true
+		c.useStartTLS = false
+	}
+}
+
+// Connect in plain text, then upgrade to TLS with the STARTTLS command before authenticating. Dial fails with
+// ErrTLSUnavailable if the server does not support it. A nil config is handled as by DialWithTLS.
+func DialWithStartTLS(config *tls.Config) DialOption {
+	return func(c *Conn) {
+		c.tlsConfig = config
+		c.useTLS = true
+		c.useStartTLS = true
+	}
+}
+
+// Only accept servers whose certificate public key matches one of the pins, see PublicKeyPin. Pinning is done in
+// addition to the certificate verification of the tls.Config. For servers with self-signed certificates, set
+// InsecureSkipVerify of the tls.Config to rely on pinning alone.
+func DialWithPinnedKeys(pins ...[]byte) DialOption {
+	return func(c *Conn) {
+		c.pins = pins
+	}
+}
@@ -0,0 +1,155 @@
+package nntp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"gopkg.in/yenc.v0/nntp/nntptest"
+)
+
+func newTestCertificate(t *testing.T, hosts ...string) (cert tls.Certificate, roots *x509.CertPool) {
+	cert, err := nntptest.NewCertificate(hosts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots = x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+	return
+}
+
+func TestDialTLS(t *testing.T) {
+	cert, roots := newTestCertificate(t, "127.0.0.1")
+	srv := nntptest.NewServer(
+		nntptest.ServerWithTLS(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		nntptest.ServerWithArticles(loadArticles(t, "ngPost", 10)),
+		nntptest.ServerWithAuth("user", "pass"))
+	defer srv.Close()
+
+	c, err := Dial(context.Background(), srv.Addr,
+		DialWithTLS(&tls.Config{RootCAs: roots}),
+		DialWithAuth("user", "pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.TLSConnectionState(); !ok {
+		t.Error("expect connection to be encrypted")
+	}
+	if err = c.Quit(); err != nil {
+		t.Error(err)
+	}
+
+	p, err := NewPool([]Server{{
+		Addr:     srv.Addr,
+		Username: "user",
+		Password: "pass",
+		MaxConns: 2,
+		Options:  []DialOption{DialWithTLS(&tls.Config{RootCAs: roots})},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	compareRaw(t, "ngPost", fetchAll(t, p, "ngPost", 10))
+}
+
+func TestDialTLSVerificationFailure(t *testing.T) {
+	cert, roots := newTestCertificate(t, "nntp.example.com")
+	srv := nntptest.NewServer(nntptest.ServerWithTLS(&tls.Config{Certificates: []tls.Certificate{cert}}))
+	defer srv.Close()
+
+	// self-signed certificate not trusted
+	if _, err := Dial(context.Background(), srv.Addr, DialWithTLS(&tls.Config{})); !errors.Is(err, ErrCertificateVerification) {
+		t.Errorf("expect certificate verification error but got %v", err)
+	}
+	// trusted, but the certificate is not for this host
+	if _, err := Dial(context.Background(), srv.Addr, DialWithTLS(&tls.Config{RootCAs: roots})); !errors.Is(err, ErrCertificateVerification) {
+		t.Errorf("expect certificate verification error but got %v", err)
+	}
+	// trusted and for the expected host name
+	c, err := Dial(context.Background(), srv.Addr, DialWithTLS(&tls.Config{RootCAs: roots, ServerName: "nntp.example.com"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestDialTLSPinnedKeys(t *testing.T) {
+	cert, _ := newTestCertificate(t, "127.0.0.1")
+	other, _ := newTestCertificate(t, "127.0.0.1")
+	srv := nntptest.NewServer(nntptest.ServerWithTLS(&tls.Config{Certificates: []tls.Certificate{cert}}))
+	defer srv.Close()
+
+	insecure := &tls.Config{InsecureSkipVerify: true}
+	c, err := Dial(context.Background(), srv.Addr, DialWithTLS(insecure),
+		DialWithPinnedKeys(PublicKeyPin(other.Leaf), PublicKeyPin(cert.Leaf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	if _, err = Dial(context.Background(), srv.Addr, DialWithTLS(insecure),
+		DialWithPinnedKeys(PublicKeyPin(other.Leaf))); !errors.Is(err, ErrCertificatePin) {
+		t.Errorf("expect certificate pin error but got %v", err)
+	}
+}
+
+func TestDialStartTLS(t *testing.T) {
+	cert, roots := newTestCertificate(t, "127.0.0.1")
+	srv := nntptest.NewServer(
+		nntptest.ServerWithStartTLS(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		nntptest.ServerWithArticles(loadArticles(t, "yenc32", 10)),
+		nntptest.ServerWithAuth("user", "pass"))
+	defer srv.Close()
+
+	p, err := NewPool([]Server{{
+		Addr:     srv.Addr,
+		Username: "user",
+		Password: "pass",
+		Options:  []DialOption{DialWithStartTLS(&tls.Config{RootCAs: roots})},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	compareRaw(t, "yenc32", fetchAll(t, p, "yenc32", 10))
+	if srv.Count("STARTTLS") != 1 {
+		t.Errorf("expect 1 STARTTLS command but got %d", srv.Count("STARTTLS"))
+	}
+
+	plain := nntptest.NewServer()
+	defer plain.Close()
+	if _, err = Dial(context.Background(), plain.Addr, DialWithStartTLS(&tls.Config{RootCAs: roots})); !errors.Is(err, ErrTLSUnavailable) {
+		t.Errorf("expect STARTTLS unavailable error but got %v", err)
+	}
+}
+
+// A nil config still means TLS, verified against the system roots, and never plain text.
+func TestDialTLSNilConfig(t *testing.T) {
+	cert, _ := newTestCertificate(t, "127.0.0.1")
+	srv := nntptest.NewServer(nntptest.ServerWithTLS(&tls.Config{Certificates: []tls.Certificate{cert}}))
+	defer srv.Close()
+	if _, err := Dial(context.Background(), srv.Addr, DialWithTLS(nil)); !errors.Is(err, ErrCertificateVerification) {
+		t.Errorf("expect certificate verification error but got %v", err)
+	}
+
+	starttls := nntptest.NewServer(
+		nntptest.ServerWithStartTLS(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		nntptest.ServerWithAuth("user", "pass"))
+	defer starttls.Close()
+	if _, err := Dial(context.Background(), starttls.Addr, DialWithStartTLS(nil), DialWithAuth("user", "pass")); !errors.Is(err, ErrCertificateVerification) {
+		t.Errorf("expect certificate verification error but got %v", err)
+	}
+	if starttls.Count("STARTTLS") != 1 || starttls.Count("AUTHINFO") != 0 {
+		t.Errorf("expect STARTTLS and no credentials sent but got %d STARTTLS and %d AUTHINFO",
+			starttls.Count("STARTTLS"), starttls.Count("AUTHINFO"))
+	}
+
+	plain := nntptest.NewServer()
+	defer plain.Close()
+	if _, err := Dial(context.Background(), plain.Addr, DialWithStartTLS(nil)); !errors.Is(err, ErrTLSUnavailable) {
+		t.Errorf("expect STARTTLS unavailable error but got %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package nzb
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/option.v0"
+	"gopkg.in/yenc.v0"
+)
+
+// An article overview record, as returned by the NNTP OVER/XOVER command.
+type Record struct {
+	Group     string
+	Number    int64 // Article number in Group
+	Subject   string
+	Poster    string
+	Date      time.Time
+	MessageID string
+	Bytes     int64
+}
+
+// A file reconstructed from the overview records of its parts.
+type CollectedFile struct {
+	Subject yenc.Subject // Parsed subject of the first part seen, with the part number zeroed
+	Poster  string
+	Date    time.Time // Date of the earliest part
+	Groups  []string
+	Parts   map[uint64]Record // Records keyed by part number
+
+	firstSubject string
+	first, last  time.Time
+}
+
+// Name of the file as given by the subject.
+func (f *CollectedFile) Name() string {
+	return f.Subject.Name
+}
+
+// Number of parts the file was posted in, or 1 for a single-part post without part counter.
+func (f *CollectedFile) Total() uint64 {
+	if f.Subject.Total == 0 {
+		return 1
+	}
+	return f.Subject.Total
+}
+
+// Part numbers from 1 to Total that have not been seen.
+func (f *CollectedFile) Missing() (missing []uint64) {
+	for i := uint64(1); i <= f.Total(); i++ {
+		if _, ok := f.Parts[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	return
+}
+
+// Whether every part from 1 to Total has been seen.
+func (f *CollectedFile) Complete() bool {
+	return len(f.Missing()) == 0
+}
+
+// Sum of the article sizes of the parts seen.
+func (f *CollectedFile) Bytes() (n int64) {
+	for _, r := range f.Parts {
+		n += r.Bytes
+	}
+	return
+}
+
+// Convert to an NZB file entry, with segments ordered by part number.
+func (f *CollectedFile) File() File {
+	file := File{Poster: f.Poster, Date: f.Date.Unix(), Subject: f.firstSubject, Groups: f.Groups}
+	numbers := make([]uint64, 0, len(f.Parts))
+	for n := range f.Parts {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	for _, n := range numbers {
+		r := f.Parts[n]
+		file.Segments = append(file.Segments, Segment{Bytes: r.Bytes, Number: int(n), MessageID: strings.Trim(r.MessageID, "<>")})
+	}
+	return file
+}
+
+// The files of one post, e.g. the RAR volumes, PAR2 files and NFO of a release.
+type Set struct {
+	Name   string // Subject prefix or common base name of the files
+	Poster string
+	Files  []*CollectedFile // Ordered by file index, then name
+
+	fileCount   uint64
+	first, last time.Time
+}
+
+// Number of files of the set, as given by the subject file counters, or else the number of files seen.
+func (s *Set) FileCount() uint64 {
+	if s.fileCount > 0 {
+		return s.fileCount
+	}
+	return uint64(len(s.Files))
+}
+
+// Whether every file of the set has been seen and every file is complete.
+func (s *Set) Complete() bool {
+	if uint64(len(s.Files)) < s.FileCount() {
+		return false
+	}
+	for _, f := range s.Files {
+		if !f.Complete() {
+			return false
+		}
+	}
+	return true
+}
+
+// Convert the set to an NZB document, with the set name as title.
+func (s *Set) NZB() *NZB {
+	n := &NZB{}
+	if s.Name != "" {
+		n.Meta = append(n.Meta, Meta{Type: "title", Value: s.Name})
+	}
+	for _, f := range s.Files {
+		n.Files = append(n.Files, f.File())
+	}
+	return n
+}
+
+// Groups overview records into files and files into post sets, the way a binary newsreader does. Parts of a file are
+// matched by poster and by their subject with the part counter removed. Files of a set are matched by poster and by
+// either the subject prefix and file counter, or by the base name of the files (e.g. "name" of "name.part01.rar").
+// Records of the same file or set posted further apart than the time window are kept separate, so that reposts are
+// not merged with the original post.
+type Collector struct {
+	window time.Duration
+	files  map[fileKey][]*CollectedFile
+	sets   map[setKey][]*Set
+	order  []*Set
+}
+
+type fileKey struct {
+	poster, prefix, name string
+	fileIndex, fileCount uint64
+	total                uint64
+}
+
+type setKey struct {
+	poster, prefix, base string
+	fileCount            uint64
+}
+
+func NewCollector(options ...CollectorOption) *Collector {
+	c := option.New(options, CollectorWithWindow(DefaultWindow))
+	c.files = make(map[fileKey][]*CollectedFile)
+	c.sets = make(map[setKey][]*Set)
+	return c
+}
+
+// Add an overview record. Returns false if its subject does not look like a binary post, in which case it is ignored.
+func (c *Collector) Add(r Record) bool {
+	s, ok := yenc.ParseSubject(r.Subject)
+	if !ok || s.Name == "" {
+		return false
+	}
+	part := s.Part
+	if s.Total == 0 {
+		part = 1
+	}
+	s.Part = 0
+	key := fileKey{r.Poster, s.Prefix, s.Name, s.FileIndex, s.FileCount, s.Total}
+	f := c.file(key, r.Date)
+	if f == nil {
+		f = &CollectedFile{Subject: s, Poster: r.Poster, Parts: make(map[uint64]Record), firstSubject: r.Subject, first: r.Date, last: r.Date}
+		c.files[key] = append(c.files[key], f)
+		c.addToSet(f, r.Date)
+	}
+	if r.Date.Before(f.first) {
+		f.first = r.Date
+	}
+	if r.Date.After(f.last) {
+		f.last = r.Date
+	}
+	f.Date = f.first
+	if part == 1 || len(f.Parts) == 0 {
+		f.firstSubject = r.Subject
+	}
+	if r.Group != "" && !contains(f.Groups, r.Group) {
+		f.Groups = append(f.Groups, r.Group)
+	}
+	if _, dup := f.Parts[part]; !dup {
+		f.Parts[part] = r
+	}
+	return true
+}
+
+func (c *Collector) file(key fileKey, date time.Time) *CollectedFile {
+	for _, f := range c.files[key] {
+		if withinWindow(f.first, f.last, date, c.window) {
+			return f
+		}
+	}
+	return nil
+}
+
+func (c *Collector) addToSet(f *CollectedFile, date time.Time) {
+	key := setKey{poster: f.Poster, prefix: f.Subject.Prefix, fileCount: f.Subject.FileCount}
+	if f.Subject.FileCount == 0 {
+		key.base = BaseName(f.Subject.Name)
+	}
+	var set *Set
+	for _, s := range c.sets[key] {
+		if withinWindow(s.first, s.last, date, c.window) {
+			set = s
+			break
+		}
+	}
+	if set == nil {
+		set = &Set{Name: f.Subject.Prefix, Poster: f.Poster, fileCount: f.Subject.FileCount, first: date, last: date}
+		if set.Name == "" {
+			set.Name = key.base
+		}
+		c.sets[key] = append(c.sets[key], set)
+		c.order = append(c.order, set)
+	}
+	if date.Before(set.first) {
+		set.first = date
+	}
+	if date.After(set.last) {
+		set.last = date
+	}
+	set.Files = append(set.Files, f)
+	sort.SliceStable(set.Files, func(i, j int) bool {
+		if set.Files[i].Subject.FileIndex != set.Files[j].Subject.FileIndex {
+			return set.Files[i].Subject.FileIndex < set.Files[j].Subject.FileIndex
+		}
+		return set.Files[i].Subject.Name < set.Files[j].Subject.Name
+	})
+}
+
+// Every file seen so far, in the order of their sets.
+func (c *Collector) Files() (files []*CollectedFile) {
+	for _, s := range c.order {
+		files = append(files, s.Files...)
+	}
+	return
+}
+
+// Every post set seen so far, in the order they were first seen.
+func (c *Collector) Sets() []*Set {
+	return c.order
+}
+
+func withinWindow(first, last, date time.Time, window time.Duration) bool {
+	if window <= 0 {
+		return true
+	}
+	return !date.Before(first.Add(-window)) && !date.After(last.Add(window))
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var volumeSuffix = regexp.MustCompile(`(?i)(\.part\d+\.rar|\.r\d{2,3}|\.rar|\.vol\d+[+-]\d+\.par2|\.par2|\.\d{3}|\.(7z|zip|sfv|nfo|nzb|srr|md5|txt|jpg|png))+$`)
+
+// Base name shared by the files of a release, e.g. "name" for "name.part01.rar", "name.vol03+04.par2" or "name.nfo".
+func BaseName(name string) string {
+	if base := volumeSuffix.ReplaceAllString(name, ""); base != "" {
+		return base
+	}
+	return name
+}
+
+// Default time window within which records of the same file or set are grouped together.
+var DefaultWindow = 48 * time.Hour
+
+type CollectorOption func(*Collector)
+
+// Group records of the same file or set only if they were posted within d of each other. Zero disables the check.
+func CollectorWithWindow(d time.Duration) CollectorOption {
+	return func(c *Collector) {
+		c.window = d
+	}
+}
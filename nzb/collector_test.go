@@ -0,0 +1,141 @@
+package nzb
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var postDate = time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func records(subject func(part int) string, poster string, date time.Time, parts ...int) (records []Record) {
+	for _, part := range parts {
+		records = append(records, Record{
+			Group:     "alt.binaries.test",
+			Subject:   subject(part),
+			Poster:    poster,
+			Date:      date.Add(time.Duration(part) * time.Second),
+			MessageID: fmt.Sprintf("<%s-%d@test>", subject(0), part),
+			Bytes:     int64(700 + part),
+		})
+	}
+	return
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	var all []Record
+	// Nyuu style set of 3 files, the last one missing a part
+	for i, name := range []string{"release.part1.rar", "release.part2.rar", "release.par2"} {
+		name := name
+		subject := func(part int) string {
+			return fmt.Sprintf(`[%d/3] - "%s" yEnc (%d/4) 2048`, i+1, name, part)
+		}
+		if i == 2 {
+			all = append(all, records(subject, "nyuu@example.com", postDate, 1, 2, 4)...)
+		} else {
+			all = append(all, records(subject, "nyuu@example.com", postDate, 4, 3, 2, 1)...)
+		}
+	}
+	// files without file counter, grouped by base name
+	for _, name := range []string{"ngPost-raw.bin", "ngPost-raw.bin.vol00+01.par2"} {
+		name := name
+		all = append(all, records(func(part int) string { return fmt.Sprintf(`"%s" yEnc (%d/2)`, name, part) }, "ngpost@example.com", postDate, 1, 2)...)
+	}
+	// a repost of the same file ten days later
+	all = append(all, records(func(part int) string { return fmt.Sprintf(`"ngPost-raw.bin" yEnc (%d/2)`, part) }, "ngpost@example.com", postDate.Add(240*time.Hour), 1)...)
+	// single-part file without part counter and noise
+	all = append(all, Record{Subject: `"single.nfo" yEnc`, Poster: "other@example.com", Date: postDate, MessageID: "<single@test>", Bytes: 10})
+	all = append(all, Record{Subject: "Re: where is part 3?", Poster: "chatter@example.com", Date: postDate})
+
+	ignored := 0
+	for _, r := range all {
+		if !c.Add(r) {
+			ignored++
+		}
+	}
+	if ignored != 1 {
+		t.Errorf("expect 1 record to be ignored but got %d", ignored)
+	}
+
+	sets := c.Sets()
+	if len(sets) != 4 {
+		for _, s := range sets {
+			t.Logf("%#v", s)
+		}
+		t.Fatalf("expect 4 sets but got %d", len(sets))
+	}
+
+	nyuu := sets[0]
+	if nyuu.FileCount() != 3 || len(nyuu.Files) != 3 {
+		t.Fatalf("expect nyuu set to have 3 files but got %d", len(nyuu.Files))
+	}
+	for i, name := range []string{"release.part1.rar", "release.part2.rar", "release.par2"} {
+		if nyuu.Files[i].Name() != name {
+			t.Errorf("file %d: expect %s but got %s", i, name, nyuu.Files[i].Name())
+		}
+	}
+	if nyuu.Complete() {
+		t.Error("expect nyuu set to be incomplete")
+	}
+	if !reflect.DeepEqual(nyuu.Files[2].Missing(), []uint64{3}) {
+		t.Errorf("unexpected missing parts %v", nyuu.Files[2].Missing())
+	}
+	if !nyuu.Files[0].Complete() || nyuu.Files[0].Date != postDate.Add(time.Second) {
+		t.Errorf("unexpected first file %#v", nyuu.Files[0])
+	}
+
+	ngpost := sets[1]
+	if ngpost.Name != "ngPost-raw.bin" || len(ngpost.Files) != 2 || !ngpost.Complete() {
+		t.Errorf("unexpected ngPost set %#v", ngpost)
+	}
+	repost := sets[2]
+	if len(repost.Files) != 1 || repost.Files[0].Date.Before(postDate.Add(240*time.Hour)) || repost.Complete() {
+		t.Errorf("expect repost to be a separate incomplete set but got %#v", repost)
+	}
+	single := sets[3]
+	if !single.Complete() || single.Files[0].Total() != 1 {
+		t.Errorf("expect single-part file to be complete %#v", single)
+	}
+
+	var b bytes.Buffer
+	if _, err := nyuu.NZB().WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	n, err := Parse(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Files) != 3 || len(n.Files[0].Segments) != 4 || len(n.Files[2].Segments) != 3 {
+		t.Fatalf("unexpected NZB %#v", n)
+	}
+	f := n.Files[0]
+	if f.Subject != `[1/3] - "release.part1.rar" yEnc (1/4) 2048` || f.Poster != "nyuu@example.com" || f.Date != postDate.Unix()+1 {
+		t.Errorf("unexpected NZB file %#v", f)
+	}
+	for i, s := range f.Segments {
+		if s.Number != i+1 || s.MessageID != fmt.Sprintf(`[1/3] - "release.part1.rar" yEnc (0/4) 2048-%d@test`, i+1) {
+			t.Errorf("unexpected segment %#v", s)
+		}
+	}
+}
+
+func TestBaseName(t *testing.T) {
+	for name, expect := range map[string]string{
+		"movie.part01.rar":    "movie",
+		"movie.r00":           "movie",
+		"movie.vol00+01.par2": "movie",
+		"movie.par2":          "movie",
+		"movie.7z.001":        "movie",
+		"movie.nfo":           "movie",
+		"movie.mkv":           "movie.mkv",
+		"movie.mkv.par2":      "movie.mkv",
+		".par2":               ".par2",
+	} {
+		if BaseName(name) != expect {
+			t.Errorf("%s: expect %s but got %s", name, expect, BaseName(name))
+		}
+	}
+}
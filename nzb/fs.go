@@ -0,0 +1,24 @@
+package nzb
+
+import (
+	"time"
+
+	"gopkg.in/yenc.v0"
+)
+
+// File system of the files of the NZB under their sanitized names (see FileName and yenc.SanitizeName), fetching
+// their parts from the source returned by source for the message-IDs of their segments, e.g. nntp.Pool.Source. The
+// size of a file is only known once its first part is fetched, when it is opened or stated.
+func (n *NZB) FS(source func(messageIDs []string) yenc.SegmentSource, options ...yenc.MultipartOption) *yenc.FS {
+	files := make([]yenc.FSFile, len(n.Files))
+	for i := range n.Files {
+		name, _ := yenc.SanitizeName(n.FileName(i))
+		files[i] = yenc.FSFile{
+			Name:    name,
+			Size:    -1,
+			ModTime: time.Unix(n.Files[i].Date, 0),
+			Source:  source(n.Files[i].MessageIDs()),
+		}
+	}
+	return yenc.NewFS(files, options...)
+}
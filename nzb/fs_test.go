@@ -0,0 +1,37 @@
+package nzb
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	"gopkg.in/yenc.v0"
+)
+
+func TestNZBFS(t *testing.T) {
+	n := &NZB{
+		Meta:  []Meta{{Type: "name", Value: "0123456789abcdef=../video.mkv"}},
+		Files: []File{{Subject: `"0123456789abcdef" yEnc (1/10) 4682`, Date: 1600000000}},
+	}
+	for i := 10; i >= 1; i-- {
+		n.Files[0].Segments = append(n.Files[0].Segments, Segment{Number: i, MessageID: fmt.Sprintf("../fixture/yenc32-%03d.ntx", i)})
+	}
+	// message-IDs are paths to the articles
+	f := n.FS(func(messageIDs []string) yenc.SegmentSource { return yenc.FileSource(messageIDs) })
+	info, err := fs.Stat(f, "video.mkv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 4682 || info.ModTime().Unix() != 1600000000 {
+		t.Errorf("unexpected file info %d bytes at %v", info.Size(), info.ModTime())
+	}
+	raw, err := os.ReadFile("../fixture/yenc32-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile(f, "video.mkv"); err != nil || !bytes.Equal(b, raw) {
+		t.Errorf("unexpected content of %d bytes: %v", len(b), err)
+	}
+}
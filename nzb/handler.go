@@ -0,0 +1,172 @@
+package nzb
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/option.v0"
+	"gopkg.in/yenc.v0"
+)
+
+// Serves the files of an NZB over HTTP, each at the path of its sanitized name (see yenc.SanitizeName), and an index
+// of the files at "/". Files are read through a yenc.MultipartFile opened on first request, so that range requests
+// only fetch the parts they need, and parts are verified against their CRC32 as they are fetched. Content-Length is the
+// size of the decoded file given by the =ybegin line. A request that ends cancels the fetches of its reads, except the
+// first part, fetched on open for every request and only canceled by Close.
+//
+// Since the response headers are sent before the data is fetched, a part that fails to fetch or verify aborts the
+// response, which is then shorter than its Content-Length.
+type Handler struct {
+	n      *NZB
+	source func(messageIDs []string) yenc.SegmentSource
+	ctx    context.Context
+	cancel context.CancelFunc
+	paths  map[string]int // File index by path
+	order  []string       // Paths in the order of the files
+
+	multipartOptions []yenc.MultipartOption
+
+	mu    sync.Mutex
+	files map[int]*handlerFile
+}
+
+type handlerFile struct {
+	mu sync.Mutex
+	f  *yenc.MultipartFile
+}
+
+// Serve the files of n, fetching their parts from the source returned by source for the message-IDs of their
+// segments, e.g. nntp.Pool.Source. Files whose sanitized names collide are served under the name of the first one
+// only.
+func NewHandler(n *NZB, source func(messageIDs []string) yenc.SegmentSource, options ...HandlerOption) *Handler {
+	h := option.New(options)
+	h.n = n
+	h.source = source
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	h.paths = make(map[string]int)
+	h.files = make(map[int]*handlerFile)
+	for i := range n.Files {
+		name, _ := yenc.SanitizeName(n.FileName(i))
+		if _, ok := h.paths[name]; ok {
+			continue
+		}
+		h.paths[name] = i
+		h.order = append(h.order, name)
+	}
+	return h
+}
+
+// Paths the files are served at, without the leading "/", in the order of the files.
+func (h *Handler) Paths() []string {
+	return append([]string(nil), h.order...)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path == "" {
+		h.serveIndex(w)
+		return
+	}
+	i, ok := h.paths[path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := h.open(i)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	// a reader per request, as Read and Seek of the MultipartFile share one offset, fetching with the request context
+	// so that the fetches stop when the client goes away
+	http.ServeContent(w, r, path, time.Unix(h.n.Files[i].Date, 0), io.NewSectionReader(contextReaderAt{r.Context(), f}, 0, f.Size()))
+}
+
+// Reads a MultipartFile with ReadAtContext.
+type contextReaderAt struct {
+	ctx context.Context
+	f   *yenc.MultipartFile
+}
+
+func (r contextReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	return r.f.ReadAtContext(r.ctx, b, off)
+}
+
+func (h *Handler) serveIndex(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintln(w, "<pre>")
+	for _, path := range h.order {
+		u := url.URL{Path: path}
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(u.String()), html.EscapeString(path))
+	}
+	fmt.Fprintln(w, "</pre>")
+}
+
+// MultipartFile of file i, opened on first use. A file that fails to open is retried on the next request.
+func (h *Handler) open(i int) (f *yenc.MultipartFile, err error) {
+	h.mu.Lock()
+	hf, ok := h.files[i]
+	if !ok {
+		hf = &handlerFile{}
+		h.files[i] = hf
+	}
+	h.mu.Unlock()
+	hf.mu.Lock()
+	defer hf.mu.Unlock()
+	if hf.f == nil {
+		if hf.f, err = yenc.NewMultipartFile(h.ctx, h.source(h.n.Files[i].MessageIDs()), h.multipartOptions...); err != nil {
+			err = fmt.Errorf("[NZB] failed to open %s: %w", h.n.FileName(i), err)
+			return
+		}
+	}
+	f = hf.f
+	return
+}
+
+// Cancel the fetches in progress and release the cached parts.
+func (h *Handler) Close() error {
+	h.cancel()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, hf := range h.files {
+		hf.mu.Lock()
+		if hf.f != nil {
+			hf.f.Close()
+		}
+		hf.mu.Unlock()
+		delete(h.files, i)
+	}
+	return nil
+}
+
+// Message-IDs of the segments, ordered by part number.
+func (f *File) MessageIDs() (messageIDs []string) {
+	segments := append([]Segment(nil), f.Segments...)
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].Number < segments[j].Number })
+	for _, s := range segments {
+		messageIDs = append(messageIDs, s.MessageID)
+	}
+	return
+}
+
+type HandlerOption func(*Handler)
+
+// Options of the MultipartFile each file is read through, e.g. its cache size or readahead.
+func HandlerWithMultipartOptions(options ...yenc.MultipartOption) HandlerOption {
+	return func(h *Handler) {
+		h.multipartOptions = options
+	}
+}
@@ -0,0 +1,180 @@
+package nzb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/yenc.v0"
+	"gopkg.in/yenc.v0/nntp"
+	"gopkg.in/yenc.v0/nntp/nntptest"
+)
+
+func TestHandler(t *testing.T) {
+	articles := make(map[string][]byte)
+	f := File{Subject: `"ngPost-raw.bin" yEnc (1/10) 4682`, Date: 1600000000}
+	for i := 10; i >= 1; i-- {
+		b, err := os.ReadFile(fmt.Sprintf("../fixture/ngPost-%03d.ntx", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := fmt.Sprintf("ngPost-%03d@nntptest", i)
+		articles["<"+id+">"] = b
+		f.Segments = append(f.Segments, Segment{Number: i, MessageID: id, Bytes: int64(len(b))})
+	}
+	raw, err := os.ReadFile("../fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer srv.Close()
+	p, err := nntp.NewPool([]nntp.Server{{Addr: srv.Addr, MaxConns: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	n := &NZB{Files: []File{f}}
+	h := NewHandler(n, p.Source, HandlerWithMultipartOptions(yenc.MultipartWithReadahead(0)))
+	defer h.Close()
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	if paths := h.Paths(); len(paths) != 1 || paths[0] != "ngPost-raw.bin" {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+	// a range within part 7 (bytes 3072 to 3583) only fetches part 1, for the size, and part 7
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/ngPost-raw.bin", nil)
+	req.Header.Set("Range", "bytes=3100-3199")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("Content-Range") != "bytes 3100-3199/4682" || !bytes.Equal(b, raw[3100:3200]) {
+		t.Errorf("unexpected range response %s %v", resp.Status, resp.Header)
+	}
+	if srv.Count("BODY") != 2 {
+		t.Errorf("expect 2 articles fetched but got %d", srv.Count("BODY"))
+	}
+	// whole file
+	resp, err = http.Get(s.URL + "/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != int64(len(raw)) || !bytes.Equal(b, raw) {
+		t.Errorf("unexpected response %s of %d bytes", resp.Status, resp.ContentLength)
+	}
+	// index and missing files
+	resp, err = http.Get(s.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(b), `<a href="ngPost-raw.bin">`) {
+		t.Errorf("unexpected index %s", b)
+	}
+	if resp, err = http.Get(s.URL + "/other.bin"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expect 404 for unknown files but got %v", err)
+	}
+}
+
+func TestHandlerCorruptPart(t *testing.T) {
+	b, err := os.ReadFile("../fixture/ngPost-002.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var articles = map[string][]byte{}
+	f := File{Subject: `"ngPost-raw.bin" yEnc (1/10) 4682`}
+	for i := 1; i <= 10; i++ {
+		b, err := os.ReadFile(fmt.Sprintf("../fixture/ngPost-%03d.ntx", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := fmt.Sprintf("ngPost-%03d@nntptest", i)
+		articles["<"+id+">"] = b
+		f.Segments = append(f.Segments, Segment{Number: i, MessageID: id})
+	}
+	// flip a data byte of part 2
+	i := bytes.Index(b, []byte("=ypart begin=513 end=1024\n")) + 30
+	b[i]++
+	articles["<ngPost-002@nntptest>"] = b
+	srv := nntptest.NewServer(nntptest.ServerWithArticles(articles))
+	defer srv.Close()
+	p, err := nntp.NewPool([]nntp.Server{{Addr: srv.Addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	h := NewHandler(&NZB{Files: []File{f}}, p.Source)
+	defer h.Close()
+	s := httptest.NewServer(h)
+	defer s.Close()
+	resp, err := http.Get(s.URL + "/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if b, err := io.ReadAll(resp.Body); err == nil || len(b) >= 4682 {
+		t.Errorf("expect an aborted response but read %d bytes: %v", len(b), err)
+	}
+}
+
+// Records whether the part fetches were canceled.
+type contextSource struct {
+	yenc.SegmentSource
+	mu       sync.Mutex
+	canceled []int
+}
+
+func (c *contextSource) Fetch(ctx context.Context, i int) (*yenc.Segment, error) {
+	if ctx.Err() != nil {
+		c.mu.Lock()
+		c.canceled = append(c.canceled, i)
+		c.mu.Unlock()
+	}
+	return c.SegmentSource.Fetch(ctx, i)
+}
+
+func TestHandlerRequestContext(t *testing.T) {
+	var parts [][]byte
+	f := File{Subject: `"ngPost-raw.bin" yEnc (1/10) 4682`}
+	for i := 1; i <= 10; i++ {
+		b, err := os.ReadFile(fmt.Sprintf("../fixture/ngPost-%03d.ntx", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, b)
+		f.Segments = append(f.Segments, Segment{Number: i, MessageID: fmt.Sprintf("ngPost-%03d@nntptest", i)})
+	}
+	src := &contextSource{SegmentSource: yenc.MemorySource(parts)}
+	h := NewHandler(&NZB{Files: []File{f}}, func([]string) yenc.SegmentSource { return src },
+		HandlerWithMultipartOptions(yenc.MultipartWithReadahead(0)))
+	defer h.Close()
+
+	// the client is gone before the range within part 7 is fetched
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/ngPost-raw.bin", nil).WithContext(ctx)
+	req.Header.Set("Range", "bytes=3100-3199")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if fmt.Sprint(src.canceled) != "[6]" {
+		t.Errorf("expect the fetch of part 7 only to be canceled but got %v", src.canceled)
+	}
+}
@@ -0,0 +1,162 @@
+// Package nzb reads and writes NZB files, the XML index of the articles making up a Usenet binary post, and
+// reconstructs post sets from overview data.
+package nzb
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yenc.v0"
+)
+
+// An NZB 1.1 document.
+type NZB struct {
+	XMLName xml.Name `xml:"http://www.newzbin.com/DTD/2003/nzb nzb"`
+	Meta    []Meta   `xml:"head>meta"`
+	Files   []File   `xml:"file"`
+}
+
+// A head metadata entry such as title, password, tag or category.
+type Meta struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// A file of the post and the articles (segments) it was split into.
+type File struct {
+	Poster   string    `xml:"poster,attr"`
+	Date     int64     `xml:"date,attr"` // Unix time of the post
+	Subject  string    `xml:"subject,attr"`
+	Groups   []string  `xml:"groups>group"`
+	Segments []Segment `xml:"segments>segment"`
+}
+
+// An article holding one part of a file.
+type Segment struct {
+	Bytes     int64  `xml:"bytes,attr"`  // Size of the article, i.e. the encoded size
+	Number    int    `xml:"number,attr"` // Part number, starting from 1
+	MessageID string `xml:",chardata"`   // Message-ID without the enclosing angle brackets
+}
+
+const header = xml.Header + `<!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.1//EN" "http://www.newzbin.com/DTD/nzb/nzb-1.1.dtd">` + "\n"
+
+func Parse(r io.Reader) (n *NZB, err error) {
+	n = &NZB{}
+	d := xml.NewDecoder(r)
+	d.CharsetReader = charsetReader
+	if err = d.Decode(n); err != nil {
+		n = nil
+		err = fmt.Errorf("[NZB] failed to parse: %w", err)
+		return
+	}
+	for i := range n.Files {
+		f := &n.Files[i]
+		for j := range f.Segments {
+			f.Segments[j].MessageID = strings.Trim(strings.TrimSpace(f.Segments[j].MessageID), "<>")
+		}
+		sort.SliceStable(f.Segments, func(a, b int) bool { return f.Segments[a].Number < f.Segments[b].Number })
+	}
+	return
+}
+
+// Many NZB files declare ISO-8859-1, which encoding/xml does not support by itself.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "iso8859-1", "latin1", "l1":
+		return &latin1Reader{r: input}, nil
+	case "us-ascii", "ascii":
+		return input, nil
+	}
+	return nil, fmt.Errorf("[NZB] unsupported charset %s", charset)
+}
+
+// Converts ISO-8859-1 to UTF-8, each byte being the code point of the same value.
+type latin1Reader struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (l *latin1Reader) Read(b []byte) (n int, err error) {
+	// each byte expands to at most 2 bytes of UTF-8
+	if len(b) < 2 {
+		return 0, io.ErrShortBuffer
+	}
+	if cap(l.buf) < len(b)/2 {
+		l.buf = make([]byte, len(b)/2)
+	}
+	var m int
+	m, err = l.r.Read(l.buf[:len(b)/2])
+	for _, c := range l.buf[:m] {
+		n += utf8.EncodeRune(b[n:], rune(c))
+	}
+	return
+}
+
+// Write the document with the XML declaration and NZB 1.1 doctype.
+func (n *NZB) WriteTo(w io.Writer) (written int64, err error) {
+	var b []byte
+	if b, err = xml.MarshalIndent(n, "", "  "); err != nil {
+		err = fmt.Errorf("[NZB] failed to encode: %w", err)
+		return
+	}
+	var m int
+	m, err = io.WriteString(w, header)
+	written += int64(m)
+	if err != nil {
+		return
+	}
+	m, err = w.Write(append(b, '\n'))
+	written += int64(m)
+	return
+}
+
+// Value of the first head metadata entry of a type, e.g. "title" or "password".
+func (n *NZB) MetaValue(typ string) string {
+	for _, m := range n.Meta {
+		if m.Type == typ {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// Name of file i. Uploaders hiding the file names record the real names as "name" head metadata entries, see
+// File.NameMeta, which take precedence over the name given by the subject line. Each entry is keyed by the name of the
+// subject line, so the files keep their names when the files of NZBs are reordered, filtered or merged.
+func (n *NZB) FileName(i int) string {
+	name := n.Files[i].Name()
+	if name == "" {
+		return name
+	}
+	key := name + "="
+	for _, m := range n.Meta {
+		if m.Type == "name" && strings.HasPrefix(m.Value, key) && len(m.Value) > len(key) {
+			return m.Value[len(key):]
+		}
+	}
+	return name
+}
+
+// Head metadata entry recording the real name of f, "obfuscated=real" where obfuscated is the name given by the
+// subject line.
+func (f *File) NameMeta(name string) Meta {
+	return Meta{Type: "name", Value: f.Name() + "=" + name}
+}
+
+// File name as given by the subject line.
+func (f *File) Name() string {
+	s, _ := yenc.ParseSubject(f.Subject)
+	return s.Name
+}
+
+// Sum of the segment sizes, i.e. the encoded size of the file.
+func (f *File) Bytes() (n int64) {
+	for _, s := range f.Segments {
+		n += s.Bytes
+	}
+	return
+}
@@ -0,0 +1,104 @@
+package nzb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `<?xml version="1.0" encoding="iso-8859-1" ?>
+<!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.1//EN" "http://www.newzbin.com/DTD/nzb/nzb-1.1.dtd">
+<nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">
+ <head>
+   <meta type="title">Your File!</meta>
+   <meta type="password">secret</meta>
+ </head>
+ <file poster="Joe Bloggs &lt;bloggs@nowhere.example&gt;" date="1071674882" subject="Here's your file!  abc-mr2a.r01 (1/2)">
+   <groups>
+     <group>alt.binaries.newzbin</group>
+     <group>alt.binaries.mojo</group>
+   </groups>
+   <segments>
+     <segment bytes="54649" number="2">&lt;123456790@news.newzbin.com&gt;</segment>
+     <segment bytes="102394" number="1">123456789abcdef@news.newzbin.com</segment>
+   </segments>
+ </file>
+</nzb>`
+
+func TestParse(t *testing.T) {
+	n, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.MetaValue("title") != "Your File!" || n.MetaValue("password") != "secret" {
+		t.Errorf("unexpected meta %#v", n.Meta)
+	}
+	if len(n.Files) != 1 {
+		t.Fatalf("expect 1 file but got %d", len(n.Files))
+	}
+	f := n.Files[0]
+	if f.Poster != "Joe Bloggs <bloggs@nowhere.example>" || f.Date != 1071674882 {
+		t.Errorf("unexpected file attributes %#v", f)
+	}
+	if f.Name() != "abc-mr2a.r01" {
+		t.Errorf("unexpected file name %#v", f.Name())
+	}
+	if !reflect.DeepEqual(f.Groups, []string{"alt.binaries.newzbin", "alt.binaries.mojo"}) {
+		t.Errorf("unexpected groups %#v", f.Groups)
+	}
+	expect := []Segment{
+		{Bytes: 102394, Number: 1, MessageID: "123456789abcdef@news.newzbin.com"},
+		{Bytes: 54649, Number: 2, MessageID: "123456790@news.newzbin.com"},
+	}
+	if !reflect.DeepEqual(f.Segments, expect) {
+		t.Errorf("unexpected segments %#v", f.Segments)
+	}
+	if f.Bytes() != 157043 {
+		t.Errorf("unexpected file bytes %d", f.Bytes())
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	n, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err = n.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `<!DOCTYPE nzb PUBLIC`) || !strings.Contains(b.String(), `<nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">`) {
+		t.Errorf("unexpected output:\n%s", b.String())
+	}
+	again, err := Parse(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n.Files, again.Files) || !reflect.DeepEqual(n.Meta, again.Meta) {
+		t.Errorf("round trip mismatch:\n%#v\n%#v", n, again)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	n := &NZB{Files: []File{
+		{Subject: `[1/2] - "a1b2c3" yEnc (1/1) 10`},
+		{Subject: `d4e5f6`},
+	}}
+	if n.FileName(0) != "a1b2c3" {
+		t.Errorf("expect the subject name without meta entries but got %#v", n.FileName(0))
+	}
+	n.Meta = []Meta{n.Files[1].NameMeta("second=.bin"), {Type: "title", Value: "upload"}, n.Files[0].NameMeta("first.bin")}
+	if n.FileName(0) != "first.bin" || n.FileName(1) != "second=.bin" {
+		t.Errorf("expect the names of the entries but got %#v and %#v", n.FileName(0), n.FileName(1))
+	}
+	// the entries follow their files when the files are reordered or filtered
+	n.Files = []File{n.Files[1]}
+	if n.FileName(0) != "second=.bin" {
+		t.Errorf("expect the name of the remaining file but got %#v", n.FileName(0))
+	}
+	n.Meta = []Meta{{Type: "name", Value: "other.bin"}, {Type: "name", Value: "d4e5f6="}}
+	if n.FileName(0) != "d4e5f6" {
+		t.Errorf("expect entries of other files or without a name to be ignored but got %#v", n.FileName(0))
+	}
+}
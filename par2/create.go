@@ -0,0 +1,311 @@
+package par2
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sort"
+
+	"gopkg.in/option.v0"
+)
+
+// Creates a recovery set in a single pass over its files. Every file is added first with the first 16 KiB of its data,
+// which identifies it, then the file data is written through the returned FileWriter, typically next to a yEnc
+// Encoder with io.MultiWriter:
+//
+//	head, _ := br.Peek(par2.HashSize16k)
+//	fw, _ := c.AddFile(name, size, head)
+//	io.Copy(io.MultiWriter(fw, encoder), br)
+//	fw.Close()
+//
+// Once every file is closed, the index file and the recovery volumes can be written. Recovery slices are kept in
+// memory, i.e. the number of recovery slices times the slice size.
+type Creator struct {
+	sliceSize     int64
+	recovery      int
+	firstExponent int
+	client        string
+
+	files    []*FileWriter // In the order of the main packet once started
+	started  bool
+	slices   int
+	recovers [][]byte
+
+	setID    [16]byte
+	critical []packet // Packets repeated in every file
+}
+
+func NewCreator(sliceSize int64, options ...CreateOption) (c *Creator, err error) {
+	if sliceSize <= 0 || sliceSize%4 != 0 {
+		err = fmt.Errorf("[PAR2] slice size %d: %w", sliceSize, ErrInvalidSliceSize)
+		return
+	}
+	c = option.New(options, CreateWithClient(DefaultClient))
+	c.sliceSize = sliceSize
+	if c.firstExponent+c.recovery > MaxRecoverySlices {
+		err = fmt.Errorf("[PAR2] %d recovery slices from exponent %d: %w", c.recovery, c.firstExponent, ErrTooManySlices)
+		c = nil
+	}
+	return
+}
+
+// Choose a slice size, a multiple of 4, splitting files totalling size bytes into about count slices.
+func SliceSize(size int64, count int) int64 {
+	if count <= 0 {
+		count = 1
+	}
+	sliceSize := (size + int64(count) - 1) / int64(count)
+	sliceSize = (sliceSize + 3) &^ 3
+	if sliceSize == 0 {
+		sliceSize = 4
+	}
+	return sliceSize
+}
+
+// Add a file of the given size to the recovery set, with its first 16 KiB (or all of it if shorter) as head. Files can
+// only be added before any data is written.
+func (c *Creator) AddFile(name string, size int64, head []byte) (w *FileWriter, err error) {
+	if c.started {
+		err = fmt.Errorf("[PAR2] failed to add %s: %w", name, ErrFilesStarted)
+		return
+	}
+	if int64(len(head)) > size {
+		err = fmt.Errorf("[PAR2] head of %s exceeds its size %d: %w", name, size, ErrSizeMismatch)
+		return
+	}
+	if len(head) > HashSize16k {
+		head = head[:HashSize16k]
+	}
+	if want := size; int64(len(head)) < want && len(head) < HashSize16k {
+		if want > HashSize16k {
+			want = HashSize16k
+		}
+		err = fmt.Errorf("[PAR2] head of %s is %d bytes instead of %d: %w", name, len(head), want, ErrSizeMismatch)
+		return
+	}
+	if c.slices += sliceCount(size, c.sliceSize); c.slices > MaxSlices {
+		err = fmt.Errorf("[PAR2] %d slices of %d bytes: %w", c.slices, c.sliceSize, ErrTooManySlices)
+		return
+	}
+	w = &FileWriter{c: c, hash: md5.New()}
+	w.desc.Name = name
+	w.desc.Size = size
+	w.desc.Hash16k = md5.Sum(head)
+	w.desc.ID = FileID(w.desc.Hash16k, size, name)
+	c.files = append(c.files, w)
+	return
+}
+
+// Fix the order of the files, and with it the index of their slices, on the first write.
+func (c *Creator) start() {
+	if c.started {
+		return
+	}
+	c.started = true
+	sort.SliceStable(c.files, func(i, j int) bool { return lessID(c.files[i].desc.ID, c.files[j].desc.ID) })
+	index := 0
+	for _, f := range c.files {
+		f.first = index
+		index += sliceCount(f.desc.Size, c.sliceSize)
+	}
+	c.recovers = make([][]byte, c.recovery)
+	for i := range c.recovers {
+		c.recovers[i] = make([]byte, c.sliceSize)
+	}
+}
+
+// Add the contribution of input slice i to every recovery slice.
+func (c *Creator) addSlice(i int, data []byte) {
+	for r, recover := range c.recovers {
+		newGFTable(coefficient(i, c.firstExponent+r)).mulAdd(recover, data)
+	}
+}
+
+// File descriptions of the recovery set in the order of the main packet. Only complete once every file is closed.
+func (c *Creator) Files() (files []FileDescription) {
+	for _, f := range c.files {
+		files = append(files, f.desc)
+	}
+	sortFiles(files)
+	return
+}
+
+// Build the packets of the index file once every file is closed.
+func (c *Creator) finish() (err error) {
+	if c.critical != nil {
+		return
+	}
+	c.start()
+	for _, f := range c.files {
+		if !f.closed {
+			err = fmt.Errorf("[PAR2] %s is not closed: %w", f.desc.Name, ErrIncomplete)
+			return
+		}
+	}
+	main := mainBody(c.sliceSize, c.Files())
+	c.setID = md5.Sum(main)
+	c.critical = append(c.critical, packet{typeMain, main})
+	for _, f := range c.files {
+		c.critical = append(c.critical,
+			packet{typeFileDesc, fileDescBody(f.desc)},
+			packet{typeIFSC, ifscBody(f.desc.ID, f.checksums)})
+	}
+	c.critical = append(c.critical, packet{typeCreator, appendPadded(nil, c.client)})
+	return
+}
+
+// Recovery set ID, the MD5 of the main packet body. Only valid once every file is closed.
+func (c *Creator) SetID() [16]byte {
+	return c.setID
+}
+
+// Number of recovery slices computed.
+func (c *Creator) RecoverySlices() int {
+	return c.recovery
+}
+
+// Write the index file, holding the main, file description, slice checksum and creator packets but no recovery slice.
+func (c *Creator) WriteIndex(w io.Writer) (n int64, err error) {
+	if err = c.finish(); err != nil {
+		return
+	}
+	return c.writeCritical(w)
+}
+
+func (c *Creator) writeCritical(w io.Writer) (n int64, err error) {
+	var m int64
+	for _, p := range c.critical {
+		m, err = writePacket(w, c.setID, p.typ, p.body)
+		n += m
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Write a recovery volume holding count recovery slices, starting from the index first among the computed ones, and a
+// copy of the index packets.
+func (c *Creator) WriteVolume(w io.Writer, first, count int) (n int64, err error) {
+	if err = c.finish(); err != nil {
+		return
+	}
+	if first < 0 || count < 0 || first+count > len(c.recovers) {
+		err = fmt.Errorf("[PAR2] recovery slices %d to %d out of %d computed", first, first+count, len(c.recovers))
+		return
+	}
+	var m int64
+	for r := first; r < first+count; r++ {
+		var exponent [4]byte
+		binary.LittleEndian.PutUint32(exponent[:], uint32(c.firstExponent+r))
+		m, err = writePacket(w, c.setID, typeRecovery, exponent[:], c.recovers[r])
+		n += m
+		if err != nil {
+			return
+		}
+	}
+	m, err = c.writeCritical(w)
+	n += m
+	return
+}
+
+// Computes the checksums and recovery data of a file from its data.
+type FileWriter struct {
+	c         *Creator
+	desc      FileDescription
+	hash      hash.Hash
+	first     int // Index of the first slice of the file in the recovery set
+	written   int64
+	slice     []byte
+	checksums []SliceChecksum
+	closed    bool
+}
+
+func (w *FileWriter) Write(b []byte) (n int, err error) {
+	if w.written+int64(len(b)) > w.desc.Size {
+		err = fmt.Errorf("[PAR2] writing beyond %d bytes of %s: %w", w.desc.Size, w.desc.Name, ErrSizeMismatch)
+		return
+	}
+	w.c.start()
+	w.hash.Write(b)
+	for len(b) > 0 {
+		if w.slice == nil {
+			w.slice = make([]byte, 0, w.c.sliceSize)
+		}
+		m := int(w.c.sliceSize) - len(w.slice)
+		if m > len(b) {
+			m = len(b)
+		}
+		w.slice = append(w.slice, b[:m]...)
+		b = b[m:]
+		n += m
+		w.written += int64(m)
+		if int64(len(w.slice)) == w.c.sliceSize {
+			w.flush()
+		}
+	}
+	return
+}
+
+func (w *FileWriter) flush() {
+	// the last slice is padded with zeros
+	for int64(len(w.slice)) < w.c.sliceSize {
+		w.slice = append(w.slice, 0)
+	}
+	w.checksums = append(w.checksums, SliceChecksum{MD5: md5.Sum(w.slice), CRC32: crc32.ChecksumIEEE(w.slice)})
+	w.c.addSlice(w.first+len(w.checksums)-1, w.slice)
+	w.slice = w.slice[:0]
+}
+
+// Finish the file, returning an error if less data than its size has been written.
+func (w *FileWriter) Close() (err error) {
+	if w.closed {
+		return
+	}
+	if w.written != w.desc.Size {
+		err = fmt.Errorf("[PAR2] %s has %d bytes but %d were written: %w", w.desc.Name, w.desc.Size, w.written, ErrSizeMismatch)
+		return
+	}
+	w.c.start()
+	if len(w.slice) > 0 {
+		w.flush()
+	}
+	w.slice = nil
+	copy(w.desc.Hash[:], w.hash.Sum(nil))
+	w.closed = true
+	return
+}
+
+// File description, complete once the file is closed.
+func (w *FileWriter) Description() FileDescription {
+	return w.desc
+}
+
+// Default client identification written in the creator packet.
+var DefaultClient = "gopkg.in/yenc.v0/par2"
+
+type CreateOption func(*Creator)
+
+// Compute count recovery slices.
+func CreateWithRecoverySlices(count int) CreateOption {
+	return func(c *Creator) {
+		c.recovery = count
+	}
+}
+
+// Number the recovery slices from exponent first instead of 0, e.g. to create more recovery slices for a set later.
+func CreateWithFirstExponent(first int) CreateOption {
+	return func(c *Creator) {
+		c.firstExponent = first
+	}
+}
+
+// Identify the creating client in the creator packet.
+func CreateWithClient(client string) CreateOption {
+	return func(c *Creator) {
+		c.client = client
+	}
+}
@@ -0,0 +1,213 @@
+package par2
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+
+	"gopkg.in/yenc.v0"
+)
+
+func TestGaloisField(t *testing.T) {
+	if gfPow2(16) != 0x100B {
+		t.Errorf("expect 2^16 to be 0x100B but got %#x", gfPow2(16))
+	}
+	for _, a := range []uint16{1, 2, 0x100B, 0x8000, 0xFFFF} {
+		for _, b := range []uint16{1, 3, 0x1234, 0xFFFF} {
+			if gfDiv(gfMul(a, b), b) != a {
+				t.Errorf("%#x * %#x / %#x != %#x", a, b, b, a)
+			}
+		}
+	}
+	if len(inputLogs) != MaxSlices {
+		t.Errorf("expect %d input constants but got %d", MaxSlices, len(inputLogs))
+	}
+	for i, n := range []int{1, 2, 4, 7, 8, 11, 13, 14, 16, 19} {
+		if inputLogs[i] != n {
+			t.Errorf("expect log of input constant %d to be %d but got %d", i, n, inputLogs[i])
+		}
+	}
+}
+
+type testPacket struct {
+	setID, typ [16]byte
+	body       []byte
+}
+
+func readTestPackets(t *testing.T, b []byte) (packets []testPacket) {
+	for len(b) > 0 {
+		if len(b) < headerSize || !bytes.Equal(b[:8], magic[:]) {
+			t.Fatalf("invalid packet header %q", b[:8])
+		}
+		length := int(binary.LittleEndian.Uint64(b[8:]))
+		if length%4 != 0 || length > len(b) {
+			t.Fatalf("invalid packet length %d", length)
+		}
+		if sum := md5.Sum(b[32:length]); !bytes.Equal(sum[:], b[16:32]) {
+			t.Fatalf("packet MD5 mismatch")
+		}
+		p := testPacket{body: b[headerSize:length]}
+		copy(p.setID[:], b[32:48])
+		copy(p.typ[:], b[48:64])
+		packets = append(packets, p)
+		b = b[length:]
+	}
+	return
+}
+
+// Reference computation of a recovery slice, word by word.
+func recoverySlice(inputs [][]byte, exponent int) []byte {
+	r := make([]byte, len(inputs[0]))
+	for i, in := range inputs {
+		c := coefficient(i, exponent)
+		for j := 0; j < len(in); j += 2 {
+			w := gfMul(c, binary.LittleEndian.Uint16(in[j:]))
+			binary.LittleEndian.PutUint16(r[j:], binary.LittleEndian.Uint16(r[j:])^w)
+		}
+	}
+	return r
+}
+
+func TestCreator(t *testing.T) {
+	raw, err := os.ReadFile("../fixture/encode-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := make([]byte, 40000)
+	rand.New(rand.NewSource(1)).Read(random)
+	files := map[string][]byte{"encode-raw.bin": raw, "random.bin": random, "short.txt": []byte("short file")}
+
+	const sliceSize = 1024
+	c, err := NewCreator(sliceSize, CreateWithRecoverySlices(4), CreateWithFirstExponent(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writers := make(map[string]*FileWriter)
+	for name, data := range files {
+		br := bufio.NewReaderSize(bytes.NewReader(data), HashSize16k)
+		head, _ := br.Peek(HashSize16k)
+		if writers[name], err = c.AddFile(name, int64(len(data)), head); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the yEnc encoder comes first, and must leave the data intact for the recovery computation
+	for name, data := range files {
+		var encoded bytes.Buffer
+		e, err := yenc.Encode(&encoded, name, uint64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = io.Copy(io.MultiWriter(e, writers[name]), bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err = e.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = c.AddFile("late.bin", 0, nil); err == nil {
+			t.Fatal("expect files to be refused once writing has started")
+		}
+		if _, err = c.WriteIndex(io.Discard); err == nil {
+			t.Fatal("expect the index to be refused before every file is closed")
+		}
+		if err = writers[name].Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var index, volume bytes.Buffer
+	if _, err = c.WriteIndex(&index); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.WriteVolume(&volume, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	packets := readTestPackets(t, index.Bytes())
+	if len(packets) != 2+2*len(files) {
+		t.Fatalf("expect %d packets in the index but got %d", 2+2*len(files), len(packets))
+	}
+	if packets[0].typ != typeMain || packets[len(packets)-1].typ != typeCreator {
+		t.Fatal("expect the index to start with the main packet and end with the creator packet")
+	}
+	setID := md5.Sum(packets[0].body)
+	if setID != c.SetID() {
+		t.Error("recovery set ID is not the MD5 of the main packet body")
+	}
+	main := packets[0].body
+	if binary.LittleEndian.Uint64(main) != sliceSize || binary.LittleEndian.Uint32(main[8:]) != uint32(len(files)) {
+		t.Fatalf("unexpected main packet %x", main[:12])
+	}
+
+	// input slices in the order of the main packet
+	var inputs [][]byte
+	var previous [16]byte
+	for i, desc := range c.Files() {
+		var id [16]byte
+		copy(id[:], main[12+16*i:])
+		if id != desc.ID || (i > 0 && !lessID(previous, id)) {
+			t.Fatalf("file %d of the main packet is not in ID order", i)
+		}
+		previous = id
+		data := files[desc.Name]
+		if desc.Hash != md5.Sum(data) || desc.Size != int64(len(data)) {
+			t.Errorf("unexpected description of %s", desc.Name)
+		}
+		head := data
+		if len(head) > HashSize16k {
+			head = head[:HashSize16k]
+		}
+		if desc.ID != FileID(md5.Sum(head), int64(len(data)), desc.Name) {
+			t.Errorf("unexpected file ID of %s", desc.Name)
+		}
+		if p := packets[1+2*i]; p.typ != typeFileDesc || !bytes.Equal(p.body, fileDescBody(desc)) {
+			t.Errorf("unexpected file description packet of %s", desc.Name)
+		}
+		ifsc := packets[2+2*i]
+		if ifsc.typ != typeIFSC || !bytes.Equal(ifsc.body[:16], id[:]) || len(ifsc.body) != 16+20*sliceCount(desc.Size, sliceSize) {
+			t.Fatalf("unexpected slice checksum packet of %s", desc.Name)
+		}
+		for j := 0; j < len(data); j += sliceSize {
+			slice := make([]byte, sliceSize)
+			copy(slice, data[j:])
+			inputs = append(inputs, slice)
+			entry := ifsc.body[16+20*(j/sliceSize):]
+			if sum := md5.Sum(slice); !bytes.Equal(entry[:16], sum[:]) || binary.LittleEndian.Uint32(entry[16:]) != crc32.ChecksumIEEE(slice) {
+				t.Errorf("checksum mismatch of slice %d of %s", j/sliceSize, desc.Name)
+			}
+		}
+	}
+
+	packets = readTestPackets(t, volume.Bytes())
+	if len(packets) != 3+2+2*len(files) {
+		t.Fatalf("expect the volume to hold 3 recovery packets and the index packets but got %d packets", len(packets))
+	}
+	for i, p := range packets[:3] {
+		exponent := int(binary.LittleEndian.Uint32(p.body))
+		if p.typ != typeRecovery || p.setID != setID || exponent != 3+i {
+			t.Fatalf("unexpected recovery packet with exponent %d", exponent)
+		}
+		if !bytes.Equal(p.body[4:], recoverySlice(inputs, exponent)) {
+			t.Errorf("recovery slice mismatch for exponent %d", exponent)
+		}
+	}
+}
+
+func TestVolumeName(t *testing.T) {
+	for _, c := range []struct {
+		first, count, total int
+		expect              string
+	}{
+		{0, 1, 8, "name.vol0+1.par2"},
+		{3, 4, 15, "name.vol03+04.par2"},
+		{63, 37, 100, "name.vol063+037.par2"},
+	} {
+		if name := VolumeName("name", c.first, c.count, c.total); name != c.expect {
+			t.Errorf("expect %s but got %s", c.expect, name)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package par2
+
+import "errors"
+
+var ErrInvalidSliceSize = errors.New("slice size must be a positive multiple of 4")
+var ErrTooManySlices = errors.New("too many slices for a recovery set")
+var ErrFilesStarted = errors.New("files can not be added after writing has started")
+var ErrSizeMismatch = errors.New("written data does not match the file size")
+var ErrIncomplete = errors.New("not every file has been written")
+var ErrNoMainPacket = errors.New("no main packet found")
+var ErrNotEnoughRecovery = errors.New("not enough recovery slices")
+var ErrRepairFailed = errors.New("repair failed")
+var ErrUnknownFile = errors.New("file not in recovery set")
+var ErrMissingPackets = errors.New("critical packets missing")
@@ -0,0 +1,90 @@
+package par2
+
+import "encoding/binary"
+
+// Arithmetic in GF(2^16) with the generator polynomial x^16 + x^12 + x^3 + x + 1 used by PAR 2.0.
+const (
+	gfGenerator = 0x1100B
+	gfLimit     = 65535 // Order of the multiplicative group
+)
+
+var (
+	gfLog [1 << 16]uint16
+	gfExp [2 * gfLimit]uint16
+)
+
+func init() {
+	x := 1
+	for i := 0; i < gfLimit; i++ {
+		gfExp[i] = uint16(x)
+		gfExp[i+gfLimit] = uint16(x)
+		gfLog[x] = uint16(i)
+		if x <<= 1; x&0x10000 != 0 {
+			x ^= gfGenerator
+		}
+	}
+}
+
+func gfMul(a, b uint16) uint16 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b uint16) uint16 {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+gfLimit-int(gfLog[b])]
+}
+
+// 2 to the power of n.
+func gfPow2(n int) uint16 {
+	return gfExp[n%gfLimit]
+}
+
+// Logarithms of the constants of the input slices. The constant of input slice i is 2^inputLogs[i], the logarithms
+// being the integers that are coprime to 65535, in ascending order. There are 32768 of them, the maximum number of
+// input slices.
+var inputLogs = func() (logs []int) {
+	for n := 1; n < gfLimit; n++ {
+		if n%3 != 0 && n%5 != 0 && n%17 != 0 && n%257 != 0 {
+			logs = append(logs, n)
+		}
+	}
+	return
+}()
+
+// Maximum number of input slices of a recovery set.
+const MaxSlices = 32768
+
+// Maximum recovery exponent, i.e. the number of distinct recovery slices.
+const MaxRecoverySlices = gfLimit
+
+// Coefficient of input slice i in the recovery slice of exponent e: constant^e.
+func coefficient(i, exponent int) uint16 {
+	return gfPow2(inputLogs[i] * exponent % gfLimit)
+}
+
+// Multiplication by a constant through tables of the products of the low and high bytes of the 16-bit word.
+type gfTable struct {
+	low, high [256]uint16
+}
+
+func newGFTable(c uint16) (t *gfTable) {
+	t = &gfTable{}
+	for x := 0; x < 256; x++ {
+		t.low[x] = gfMul(c, uint16(x))
+		t.high[x] = gfMul(c, uint16(x)<<8)
+	}
+	return
+}
+
+// dst ^= c * src, both being slices of little endian 16-bit words of the same length.
+func (t *gfTable) mulAdd(dst, src []byte) {
+	for i := 0; i+1 < len(src); i += 2 {
+		w := t.low[src[i]] ^ t.high[src[i+1]]
+		binary.LittleEndian.PutUint16(dst[i:], binary.LittleEndian.Uint16(dst[i:])^w)
+	}
+}
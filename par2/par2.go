@@ -0,0 +1,160 @@
+// Package par2 creates, verifies and repairs PAR 2.0 recovery sets, the parity data posted alongside yEnc encoded
+// binaries to repair missing or damaged articles.
+package par2
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+var magic = [8]byte{'P', 'A', 'R', '2', 0, 'P', 'K', 'T'}
+
+// Packet types.
+var (
+	typeMain     = packetType("PAR 2.0\x00Main")
+	typeFileDesc = packetType("PAR 2.0\x00FileDesc")
+	typeIFSC     = packetType("PAR 2.0\x00IFSC")
+	typeRecovery = packetType("PAR 2.0\x00RecvSlic")
+	typeCreator  = packetType("PAR 2.0\x00Creator")
+)
+
+func packetType(s string) (t [16]byte) {
+	copy(t[:], s)
+	return
+}
+
+type packet struct {
+	typ  [16]byte
+	body []byte
+}
+
+// Size of the packet header: magic, length, packet MD5, recovery set ID and type.
+const headerSize = 64
+
+// Number of bytes at the start of a file whose MD5 identifies the file.
+const HashSize16k = 16 * 1024
+
+// A file of the recovery set as described by its file description packet.
+type FileDescription struct {
+	ID      [16]byte // MD5 of Hash16k, Size and Name
+	Hash    [16]byte // MD5 of the whole file
+	Hash16k [16]byte // MD5 of the first 16 KiB of the file
+	Size    int64
+	Name    string
+}
+
+// Checksums of an input slice, the last slice of a file being padded with zeros.
+type SliceChecksum struct {
+	MD5   [16]byte
+	CRC32 uint32
+}
+
+// Compute the file ID from the 16 KiB hash, size and name.
+func FileID(hash16k [16]byte, size int64, name string) (id [16]byte) {
+	b := make([]byte, 0, 16+8+len(name))
+	b = append(b, hash16k[:]...)
+	b = binary.LittleEndian.AppendUint64(b, uint64(size))
+	b = append(b, name...)
+	return md5.Sum(b)
+}
+
+// File IDs are ordered as 128-bit little endian integers.
+func lessID(a, b [16]byte) bool {
+	for i := 15; i >= 0; i-- {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func sortFiles(files []FileDescription) {
+	sort.Slice(files, func(i, j int) bool { return lessID(files[i].ID, files[j].ID) })
+}
+
+// Number of slices of a file.
+func sliceCount(size, sliceSize int64) int {
+	return int((size + sliceSize - 1) / sliceSize)
+}
+
+// Append a string padded with zeros to a multiple of 4 bytes.
+func appendPadded(b []byte, s string) []byte {
+	b = append(b, s...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func mainBody(sliceSize int64, files []FileDescription) []byte {
+	b := make([]byte, 0, 12+16*len(files))
+	b = binary.LittleEndian.AppendUint64(b, uint64(sliceSize))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(files)))
+	for _, f := range files {
+		b = append(b, f.ID[:]...)
+	}
+	return b
+}
+
+func fileDescBody(f FileDescription) []byte {
+	b := make([]byte, 0, 56+len(f.Name)+3)
+	b = append(b, f.ID[:]...)
+	b = append(b, f.Hash[:]...)
+	b = append(b, f.Hash16k[:]...)
+	b = binary.LittleEndian.AppendUint64(b, uint64(f.Size))
+	return appendPadded(b, f.Name)
+}
+
+func ifscBody(id [16]byte, checksums []SliceChecksum) []byte {
+	b := make([]byte, 0, 16+20*len(checksums))
+	b = append(b, id[:]...)
+	for _, c := range checksums {
+		b = append(b, c.MD5[:]...)
+		b = binary.LittleEndian.AppendUint32(b, c.CRC32)
+	}
+	return b
+}
+
+// Write a packet. The body must be a multiple of 4 bytes long.
+func writePacket(w io.Writer, setID, typ [16]byte, body ...[]byte) (n int64, err error) {
+	length := int64(headerSize)
+	h := md5.New()
+	h.Write(setID[:])
+	h.Write(typ[:])
+	for _, b := range body {
+		length += int64(len(b))
+		h.Write(b)
+	}
+	var header bytes.Buffer
+	header.Write(magic[:])
+	binary.Write(&header, binary.LittleEndian, uint64(length))
+	header.Write(h.Sum(nil))
+	header.Write(setID[:])
+	header.Write(typ[:])
+	var m int
+	for _, b := range append([][]byte{header.Bytes()}, body...) {
+		m, err = w.Write(b)
+		n += int64(m)
+		if err != nil {
+			err = fmt.Errorf("[PAR2] failed to write packet: %w", err)
+			return
+		}
+	}
+	return
+}
+
+// Name of the index file of a recovery set, e.g. "name.par2".
+func IndexName(base string) string {
+	return base + ".par2"
+}
+
+// Name of a recovery volume holding count recovery slices starting from exponent first, e.g. "name.vol03+04.par2". The
+// numbers are zero padded to the width of total, the number of recovery slices of the set.
+func VolumeName(base string, first, count, total int) string {
+	width := len(fmt.Sprint(total))
+	return fmt.Sprintf("%s.vol%0*d+%0*d.par2", base, width, first, width, count)
+}
@@ -0,0 +1,198 @@
+package par2
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A recovery set as read from its index file and recovery volumes.
+type Set struct {
+	ID        [16]byte
+	SliceSize int64
+	Files     []FileDescription // In the order of the main packet. Files without a description packet are left out.
+	Creator   string
+
+	fileIDs   [][16]byte
+	descs     map[[16]byte]FileDescription
+	checksums map[[16]byte][]SliceChecksum
+	recovery  map[int][]byte // Recovery slices by exponent
+	pending   []pendingPacket
+}
+
+// Read a recovery set from an index file or any of its volumes. More volumes can be added with ReadFrom.
+func Parse(r io.Reader) (s *Set, err error) {
+	s = &Set{}
+	if _, err = s.ReadFrom(r); err != nil {
+		s = nil
+	}
+	return
+}
+
+// Read the packets of a file of the recovery set. Damaged packets, packets of unknown types and packets of other
+// recovery sets are skipped. Returns ErrNoMainPacket if no main packet has been seen yet.
+func (s *Set) ReadFrom(r io.Reader) (n int64, err error) {
+	br := bufio.NewReader(r)
+	for {
+		var header []byte
+		if header, err = br.Peek(headerSize); err != nil {
+			n += int64(len(header))
+			break
+		}
+		length := int64(binary.LittleEndian.Uint64(header[8:]))
+		if !bytes.Equal(header[:len(magic)], magic[:]) || length < headerSize || length%4 != 0 || length > headerSize+4+MaxSliceSize {
+			// resynchronize on the next magic sequence after garbage or a corrupt header
+			br.Discard(1)
+			n++
+			continue
+		}
+		var setID, typ [16]byte
+		copy(setID[:], header[32:48])
+		copy(typ[:], header[48:64])
+		h := md5.New()
+		h.Write(header[32:])
+		var sum [16]byte
+		copy(sum[:], header[16:32])
+		br.Discard(headerSize)
+		n += headerSize
+		body := make([]byte, length-headerSize)
+		var m int
+		m, err = io.ReadFull(br, body)
+		n += int64(m)
+		if err != nil {
+			break
+		}
+		if h.Write(body); bytes.Equal(h.Sum(nil), sum[:]) {
+			s.add(setID, typ, body)
+		}
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if err == nil && s.fileIDs == nil {
+		err = fmt.Errorf("[PAR2] failed to read recovery set: %w", ErrNoMainPacket)
+	}
+	return
+}
+
+// Largest slice accepted when reading, so that a corrupt packet length does not allocate without bounds.
+const MaxSliceSize = 1 << 30
+
+func (s *Set) add(setID, typ [16]byte, body []byte) {
+	if typ == typeMain {
+		s.addMain(setID, body)
+		return
+	}
+	if s.fileIDs == nil {
+		// the set ID is only known once the main packet is read
+		s.pending = append(s.pending, pendingPacket{setID, packet{typ, body}})
+		return
+	}
+	if setID != s.ID {
+		return
+	}
+	switch typ {
+	case typeFileDesc:
+		if len(body) < 56 {
+			return
+		}
+		var d FileDescription
+		copy(d.ID[:], body)
+		copy(d.Hash[:], body[16:])
+		copy(d.Hash16k[:], body[32:])
+		d.Size = int64(binary.LittleEndian.Uint64(body[48:]))
+		d.Name = strings.TrimRight(string(body[56:]), "\x00")
+		if _, ok := s.descs[d.ID]; !ok && s.hasFile(d.ID) {
+			s.descs[d.ID] = d
+			s.Files = s.Files[:0]
+			for _, id := range s.fileIDs {
+				if d, ok := s.descs[id]; ok {
+					s.Files = append(s.Files, d)
+				}
+			}
+		}
+	case typeIFSC:
+		if len(body) < 16 || (len(body)-16)%20 != 0 {
+			return
+		}
+		var id [16]byte
+		copy(id[:], body)
+		checksums := make([]SliceChecksum, (len(body)-16)/20)
+		for i := range checksums {
+			entry := body[16+20*i:]
+			copy(checksums[i].MD5[:], entry)
+			checksums[i].CRC32 = binary.LittleEndian.Uint32(entry[16:])
+		}
+		s.checksums[id] = checksums
+	case typeRecovery:
+		if int64(len(body)) == 4+s.SliceSize {
+			s.recovery[int(binary.LittleEndian.Uint32(body))] = body[4:]
+		}
+	case typeCreator:
+		s.Creator = strings.TrimRight(string(body), "\x00")
+	}
+}
+
+func (s *Set) addMain(setID [16]byte, body []byte) {
+	if s.fileIDs != nil || len(body) < 12 || md5.Sum(body) != setID {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(body[8:]))
+	if len(body) < 12+16*count {
+		return
+	}
+	s.ID = setID
+	s.SliceSize = int64(binary.LittleEndian.Uint64(body))
+	s.fileIDs = make([][16]byte, count)
+	for i := range s.fileIDs {
+		copy(s.fileIDs[i][:], body[12+16*i:])
+	}
+	s.descs = make(map[[16]byte]FileDescription)
+	s.checksums = make(map[[16]byte][]SliceChecksum)
+	s.recovery = make(map[int][]byte)
+	for _, p := range s.pending {
+		s.add(p.setID, p.typ, p.body)
+	}
+	s.pending = nil
+}
+
+type pendingPacket struct {
+	setID [16]byte
+	packet
+}
+
+func (s *Set) hasFile(id [16]byte) bool {
+	for _, f := range s.fileIDs {
+		if f == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Description of a file of the set by name.
+func (s *Set) File(name string) (f FileDescription, ok bool) {
+	for _, f = range s.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return FileDescription{}, false
+}
+
+// Slice checksums of a file, nil if its IFSC packet has not been read.
+func (s *Set) Checksums(id [16]byte) []SliceChecksum {
+	return s.checksums[id]
+}
+
+// Exponents of the recovery slices read, in no particular order.
+func (s *Set) RecoveryExponents() (exponents []int) {
+	for e := range s.recovery {
+		exponents = append(exponents, e)
+	}
+	return
+}
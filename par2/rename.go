@@ -0,0 +1,92 @@
+package par2
+
+import (
+	"crypto/md5"
+	"hash"
+	"io"
+)
+
+// A decoded file to identify, by the MD5 of its first 16 KiB and its size.
+type DecodedFile struct {
+	Name    string
+	Size    int64
+	Hash16k [16]byte
+}
+
+// Renaming of a decoded file to its name in the recovery set.
+type Rename struct {
+	From string
+	To   string
+}
+
+// Match decoded files, typically posted under obfuscated names, to the files of the recovery set by their 16 KiB hash
+// and size, and return how to rename them to their real names. Files already named correctly need no renaming, and
+// files matching no description or a description already matched are returned as unmatched. Names in the recovery
+// set are not sanitized, and may contain "/" separated directories.
+func (s *Set) RenamePlan(files []DecodedFile) (plan []Rename, unmatched []DecodedFile) {
+	type key struct {
+		hash16k [16]byte
+		size    int64
+	}
+	names := make(map[key]string, len(s.Files))
+	for _, f := range s.Files {
+		names[key{f.Hash16k, f.Size}] = f.Name
+	}
+	matched := make(map[string]bool)
+	// files already named correctly take precedence over duplicates under other names
+	for _, f := range files {
+		if name, ok := names[key{f.Hash16k, f.Size}]; ok && name == f.Name {
+			matched[name] = true
+		}
+	}
+	for _, f := range files {
+		name, ok := names[key{f.Hash16k, f.Size}]
+		switch {
+		case ok && name == f.Name:
+		case ok && !matched[name]:
+			matched[name] = true
+			plan = append(plan, Rename{From: f.Name, To: name})
+		default:
+			unmatched = append(unmatched, f)
+		}
+	}
+	return
+}
+
+// A hash of only the first 16 KiB of the data written, as in file description packets. It can be fed the decoded data
+// with yenc.DecodeWithHash, or the joined file with Joiner.HashHead.
+func NewHash16k() hash.Hash {
+	return &hash16k{Hash: md5.New()}
+}
+
+type hash16k struct {
+	hash.Hash
+	n int
+}
+
+func (h *hash16k) Write(b []byte) (int, error) {
+	if m := HashSize16k - h.n; len(b) > m {
+		h.Hash.Write(b[:m])
+		h.n += m
+	} else {
+		h.Hash.Write(b)
+		h.n += len(b)
+	}
+	return len(b), nil
+}
+
+func (h *hash16k) Reset() {
+	h.Hash.Reset()
+	h.n = 0
+}
+
+// Identify a file from its data, read to the end to find its size.
+func ReadDecodedFile(name string, r io.Reader) (f DecodedFile, err error) {
+	h := NewHash16k()
+	f.Name = name
+	if f.Size, err = io.Copy(h, r); err != nil {
+		return
+	}
+	copy(f.Hash16k[:], h.Sum(nil))
+	return
+}
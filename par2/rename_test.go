@@ -0,0 +1,89 @@
+package par2
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"gopkg.in/yenc.v0"
+)
+
+func TestRenamePlan(t *testing.T) {
+	nyuu, err := os.ReadFile("../fixture/260731a73db67e8095a5eaf0b64b9d3db0117cdb-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ngPost, err := os.ReadFile("../fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := createTestSet(t, 4096, 0, map[string][]byte{"holiday.mkv": nyuu, "notes.txt": ngPost})
+	var index bytes.Buffer
+	if _, err = c.WriteIndex(&index); err != nil {
+		t.Fatal(err)
+	}
+	set, err := Parse(&index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the obfuscated single-part post is hashed while decoding
+	encoded, err := os.Open("../fixture/260731a73db67e8095a5eaf0b64b9d3db0117cdb@nyuu.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer encoded.Close()
+	h := NewHash16k()
+	d, err := yenc.Decode(encoded, yenc.DecodeWithHash(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := io.Copy(io.Discard, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []DecodedFile{{Name: d.Header().Name, Size: size}}
+	copy(files[0].Hash16k[:], h.Sum(nil))
+
+	// the multipart post is hashed by the joiner, whatever the order of the parts
+	h = NewHash16k()
+	j := yenc.NewJoiner(&memFile{})
+	j.HashHead(h, HashSize16k)
+	for part := 10; part >= 1; part-- {
+		encoded, err := os.ReadFile(fmt.Sprintf("../fixture/ngPost-%03d.ntx", part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = j.Join(bytes.NewReader(encoded)); err != nil {
+			t.Fatal(err)
+		}
+		if j.HeadHashed() != (part == 1) {
+			t.Fatalf("expect the head to be hashed only once part 1 is joined")
+		}
+	}
+	files = append(files, DecodedFile{Name: j.Header().Name, Size: int64(j.Header().Size)})
+	copy(files[1].Hash16k[:], h.Sum(nil))
+
+	// files not in the set, or duplicates of a file already named correctly, are left alone
+	unknown, err := ReadDecodedFile("unknown.bin", bytes.NewReader([]byte("unknown")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	duplicate := files[0]
+	duplicate.Name = "holiday.mkv"
+	files = append(files, unknown, duplicate)
+
+	plan, unmatched := set.RenamePlan(files)
+	if fmt.Sprint(plan) != "[{ngPost-raw.bin notes.txt}]" {
+		t.Errorf("unexpected rename plan %v", plan)
+	}
+	if len(unmatched) != 2 || unmatched[0].Name != files[0].Name || unmatched[1].Name != "unknown.bin" {
+		t.Errorf("unexpected unmatched files %v", unmatched)
+	}
+	plan, _ = set.RenamePlan(files[:2])
+	if fmt.Sprint(plan) != "[{260731a73db67e8095a5eaf0b64b9d3db0117cdb holiday.mkv} {ngPost-raw.bin notes.txt}]" {
+		t.Errorf("unexpected rename plan %v", plan)
+	}
+}
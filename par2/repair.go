@@ -0,0 +1,224 @@
+package par2
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+
+	"gopkg.in/yenc.v0"
+)
+
+// Read and write access to a file being repaired, e.g. an *os.File.
+type ReadWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Find the damaged slices of a file, by index within the file. Slices overlapping the bad ranges, e.g. the missing and
+// damaged ranges of a yenc.Joiner, are damaged without being read. The other slices are read and checked against
+// their checksums.
+func (s *Set) Verify(name string, r io.ReaderAt, bad []yenc.Range) (damaged []int, err error) {
+	f, ok := s.File(name)
+	if !ok {
+		err = fmt.Errorf("[PAR2] failed to verify %s: %w", name, ErrUnknownFile)
+		return
+	}
+	checksums := s.checksums[f.ID]
+	slice := make([]byte, s.SliceSize)
+	for i := 0; i < sliceCount(f.Size, s.SliceSize); i++ {
+		begin, end := s.sliceRange(f, i)
+		if overlaps(bad, begin, end) {
+			damaged = append(damaged, i)
+			continue
+		}
+		if i >= len(checksums) {
+			// without the checksum packet, only the bad ranges tell
+			continue
+		}
+		var intact bool
+		if intact, err = s.readSlice(r, f, i, slice); err != nil {
+			err = fmt.Errorf("[PAR2] failed to verify %s: %w", name, err)
+			return
+		}
+		if !intact || !checksums[i].matches(slice) {
+			damaged = append(damaged, i)
+		}
+	}
+	return
+}
+
+// Byte range of a slice of a file.
+func (s *Set) sliceRange(f FileDescription, i int) (begin, end int64) {
+	begin = int64(i) * s.SliceSize
+	end = begin + s.SliceSize
+	if end > f.Size {
+		end = f.Size
+	}
+	return
+}
+
+func overlaps(ranges []yenc.Range, begin, end int64) bool {
+	for _, r := range ranges {
+		if int64(r.Begin) < end && int64(r.End) > begin {
+			return true
+		}
+	}
+	return false
+}
+
+// Read slice i of a file into b, padded with zeros. intact is false if the file is shorter than expected.
+func (s *Set) readSlice(r io.ReaderAt, f FileDescription, i int, b []byte) (intact bool, err error) {
+	begin, end := s.sliceRange(f, i)
+	var n int
+	n, err = r.ReadAt(b[:end-begin], begin)
+	if err == io.EOF {
+		err = nil
+	}
+	for j := n; j < len(b); j++ {
+		b[j] = 0
+	}
+	intact = int64(n) == end-begin
+	return
+}
+
+func (c SliceChecksum) matches(slice []byte) bool {
+	if crc32.ChecksumIEEE(slice) != c.CRC32 {
+		return false
+	}
+	sum := md5.Sum(slice)
+	return bytes.Equal(sum[:], c.MD5[:])
+}
+
+// Reconstruct the damaged slices of the files of the set, by file name as returned by Verify, from the intact slices of
+// every file and as many recovery slices as there are damaged slices. files gives access to every file of the set by
+// name, a missing file being an empty file with all of its slices damaged.
+func (s *Set) Repair(files map[string]ReadWriterAt, damaged map[string][]int) (err error) {
+	if len(s.Files) != len(s.fileIDs) {
+		err = fmt.Errorf("[PAR2] %d of %d file descriptions read: %w", len(s.Files), len(s.fileIDs), ErrMissingPackets)
+		return
+	}
+	// global indexes of the damaged slices, numbered across the files in the order of the main packet
+	var lost []int
+	isLost := make(map[int]bool)
+	base := make([]int, len(s.Files))
+	index := 0
+	for i, f := range s.Files {
+		base[i] = index
+		for _, j := range damaged[f.Name] {
+			if j >= 0 && j < sliceCount(f.Size, s.SliceSize) && !isLost[index+j] {
+				lost = append(lost, index+j)
+				isLost[index+j] = true
+			}
+		}
+		if files[f.Name] == nil {
+			err = fmt.Errorf("[PAR2] %s of the recovery set is not available: %w", f.Name, ErrUnknownFile)
+			return
+		}
+		index += sliceCount(f.Size, s.SliceSize)
+	}
+	if len(lost) == 0 {
+		return
+	}
+	sort.Ints(lost)
+	exponents := s.RecoveryExponents()
+	if len(exponents) < len(lost) {
+		err = fmt.Errorf("[PAR2] %d damaged slices but %d recovery slices: %w", len(lost), len(exponents), ErrNotEnoughRecovery)
+		return
+	}
+	sort.Ints(exponents)
+	exponents = exponents[:len(lost)]
+
+	// remove the contribution of the intact slices from the recovery slices
+	acc := make([][]byte, len(lost))
+	for r, e := range exponents {
+		acc[r] = append([]byte(nil), s.recovery[e]...)
+	}
+	slice := make([]byte, s.SliceSize)
+	for i, f := range s.Files {
+		for j := 0; j < sliceCount(f.Size, s.SliceSize); j++ {
+			if isLost[base[i]+j] {
+				continue
+			}
+			if _, err = s.readSlice(files[f.Name], f, j, slice); err != nil {
+				err = fmt.Errorf("[PAR2] failed to read %s: %w", f.Name, err)
+				return
+			}
+			for r, e := range exponents {
+				newGFTable(coefficient(base[i]+j, e)).mulAdd(acc[r], slice)
+			}
+		}
+	}
+
+	// solve for the damaged slices
+	m := make([][]uint16, len(lost))
+	for r, e := range exponents {
+		m[r] = make([]uint16, len(lost))
+		for k, i := range lost {
+			m[r][k] = coefficient(i, e)
+		}
+	}
+	var inverse [][]uint16
+	if inverse, err = invert(m); err != nil {
+		return
+	}
+	for k, global := range lost {
+		for j := range slice {
+			slice[j] = 0
+		}
+		for r := range exponents {
+			newGFTable(inverse[k][r]).mulAdd(slice, acc[r])
+		}
+		i := sort.Search(len(base), func(i int) bool { return base[i] > global }) - 1
+		f, j := s.Files[i], global-base[i]
+		if checksums := s.checksums[f.ID]; j < len(checksums) && !checksums[j].matches(slice) {
+			err = fmt.Errorf("[PAR2] slice %d of %s does not match its checksum after repair: %w", j, f.Name, ErrRepairFailed)
+			return
+		}
+		begin, end := s.sliceRange(f, j)
+		if _, err = files[f.Name].WriteAt(slice[:end-begin], begin); err != nil {
+			err = fmt.Errorf("[PAR2] failed to write %s: %w", f.Name, err)
+			return
+		}
+	}
+	return
+}
+
+// Invert a square matrix over GF(2^16) by Gauss-Jordan elimination.
+func invert(m [][]uint16) (inverse [][]uint16, err error) {
+	n := len(m)
+	inverse = make([][]uint16, n)
+	for i := range inverse {
+		inverse[i] = make([]uint16, n)
+		inverse[i][i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && m[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			err = fmt.Errorf("[PAR2] recovery matrix is singular: %w", ErrRepairFailed)
+			return
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inverse[col], inverse[pivot] = inverse[pivot], inverse[col]
+		if p := m[col][col]; p != 1 {
+			for j := 0; j < n; j++ {
+				m[col][j] = gfDiv(m[col][j], p)
+				inverse[col][j] = gfDiv(inverse[col][j], p)
+			}
+		}
+		for row := 0; row < n; row++ {
+			if f := m[row][col]; row != col && f != 0 {
+				for j := 0; j < n; j++ {
+					m[row][j] ^= gfMul(f, m[col][j])
+					inverse[row][j] ^= gfMul(f, inverse[col][j])
+				}
+			}
+		}
+	}
+	return
+}
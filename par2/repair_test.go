@@ -0,0 +1,178 @@
+package par2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+
+	"gopkg.in/yenc.v0"
+)
+
+// An in-memory file.
+type memFile struct {
+	b []byte
+}
+
+func (m *memFile) ReadAt(b []byte, off int64) (n int, err error) {
+	if off >= int64(len(m.b)) {
+		return 0, io.EOF
+	}
+	if n = copy(b, m.b[off:]); n < len(b) {
+		err = io.EOF
+	}
+	return
+}
+
+func (m *memFile) WriteAt(b []byte, off int64) (int, error) {
+	if end := int(off) + len(b); end > len(m.b) {
+		m.b = append(m.b, make([]byte, end-len(m.b))...)
+	}
+	return copy(m.b[off:], b), nil
+}
+
+func createTestSet(t *testing.T, sliceSize int64, recovery int, files map[string][]byte) *Creator {
+	c, err := NewCreator(sliceSize, CreateWithRecoverySlices(recovery))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writers := make(map[string]*FileWriter)
+	for name, data := range files {
+		if writers[name], err = c.AddFile(name, int64(len(data)), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, data := range files {
+		if _, err = writers[name].Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err = writers[name].Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestRepair(t *testing.T) {
+	raw, err := os.ReadFile("../fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := make([]byte, 3000)
+	rand.New(rand.NewSource(1)).Read(random)
+	c := createTestSet(t, 400, 6, map[string][]byte{"ngPost-raw.bin": raw, "random.bin": random})
+	var index, vol0, vol4 bytes.Buffer
+	if _, err = c.WriteIndex(&index); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.WriteVolume(&vol0, 0, 4); err != nil {
+		t.Fatal(err)
+	}
+	// garbage in front of the packets is skipped
+	vol4.WriteString("garbage PAR2\x00PKT")
+	if _, err = c.WriteVolume(&vol4, 4, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := Parse(&index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.ID != c.SetID() || set.SliceSize != 400 || set.Creator != DefaultClient || len(set.Files) != 2 {
+		t.Fatalf("unexpected recovery set %+v", set)
+	}
+	if f, ok := set.File("random.bin"); !ok || f.Size != 3000 {
+		t.Fatalf("unexpected description %+v of random.bin", f)
+	}
+
+	// join the parts, skipping part 3 and corrupting part 5
+	joined := &memFile{}
+	j := yenc.NewJoiner(joined)
+	for part := 1; part <= 10; part++ {
+		if part == 3 {
+			continue
+		}
+		encoded, err := os.ReadFile(fmt.Sprintf("../fixture/ngPost-%03d.ntx", part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part == 5 {
+			encoded[bytes.LastIndex(encoded, []byte("=yend"))-10] ^= 0x01
+		}
+		j.Join(bytes.NewReader(encoded))
+	}
+	damaged := make(map[string][]int)
+	// bytes 1024 to 1536 and 2048 to 2560 are bad, i.e. slices 2, 3, 5 and 6
+	if damaged["ngPost-raw.bin"], err = set.Verify("ngPost-raw.bin", joined, j.Bad()); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(damaged["ngPost-raw.bin"]) != "[2 3 5 6]" {
+		t.Errorf("unexpected damaged slices %v", damaged["ngPost-raw.bin"])
+	}
+	corrupted := &memFile{b: append([]byte(nil), random...)}
+	corrupted.b[1000] ^= 0xff
+	if damaged["random.bin"], err = set.Verify("random.bin", corrupted, nil); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(damaged["random.bin"]) != "[2]" {
+		t.Errorf("unexpected damaged slices %v", damaged["random.bin"])
+	}
+
+	files := map[string]ReadWriterAt{"ngPost-raw.bin": joined, "random.bin": corrupted}
+	if _, err = set.ReadFrom(&vol0); err != nil {
+		t.Fatal(err)
+	}
+	if err = set.Repair(files, damaged); !errors.Is(err, ErrNotEnoughRecovery) {
+		t.Fatalf("expect ErrNotEnoughRecovery with 4 recovery slices but got %v", err)
+	}
+	if _, err = set.ReadFrom(&vol4); err != nil {
+		t.Fatal(err)
+	}
+	if err = set.Repair(files, damaged); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(joined.b, raw) || !bytes.Equal(corrupted.b, random) {
+		t.Error("repaired data mismatch")
+	}
+	for name, f := range files {
+		if d, err := set.Verify(name, f, nil); err != nil || len(d) > 0 {
+			t.Errorf("expect %s to verify after repair but got damaged slices %v: %v", name, d, err)
+		}
+	}
+}
+
+func TestRepairMissingFile(t *testing.T) {
+	files := map[string][]byte{"a.bin": make([]byte, 1000), "b.bin": make([]byte, 1500)}
+	rand.New(rand.NewSource(2)).Read(files["a.bin"])
+	rand.New(rand.NewSource(3)).Read(files["b.bin"])
+	c := createTestSet(t, 256, 4, files)
+	var volume bytes.Buffer
+	if _, err := c.WriteVolume(&volume, 0, 4); err != nil {
+		t.Fatal(err)
+	}
+	set, err := Parse(&volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a.bin is lost entirely
+	a, b := &memFile{}, &memFile{b: files["b.bin"]}
+	damaged, err := set.Verify("a.bin", a, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(damaged) != 4 {
+		t.Fatalf("expect every slice of the missing file to be damaged but got %v", damaged)
+	}
+	if err = set.Repair(map[string]ReadWriterAt{"a.bin": a, "b.bin": b}, map[string][]int{"a.bin": damaged}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.b, files["a.bin"]) {
+		t.Error("repaired data mismatch")
+	}
+	if _, err = set.Verify("c.bin", a, nil); !errors.Is(err, ErrUnknownFile) {
+		t.Errorf("expect ErrUnknownFile but got %v", err)
+	}
+}
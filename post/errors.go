@@ -0,0 +1,6 @@
+package post
+
+import "errors"
+
+var ErrInvalidPartSize = errors.New("part size must be positive")
+var ErrInvalidConnections = errors.New("number of connections must be positive")
@@ -0,0 +1,285 @@
+// Package post uploads files to Usenet as yEnc encoded articles and builds the NZB of the upload, optionally hiding
+// the real file names and the identity of the poster.
+package post
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/mail"
+	"sync"
+	"time"
+
+	"gopkg.in/option.v0"
+	"gopkg.in/yenc.v0"
+	"gopkg.in/yenc.v0/nntp"
+	"gopkg.in/yenc.v0/nzb"
+)
+
+// Posts files through a connection pool, one article per part, and records them in an NZB. An Uploader is safe for
+// concurrent use, files uploaded concurrently being recorded in the order they complete.
+//
+// With obfuscation, the real name of a file appears neither in the articles nor in the NZB subjects, only in the NZB
+// head as a "name" meta entry keyed by the name of the subject (see nzb.NZB.FileName). The obfuscated name is used
+// consistently in the =ybegin line and the subjects, so that the articles can still be joined by name.
+type Uploader struct {
+	pool   *nntp.Pool
+	groups []string
+
+	from              string
+	domain            string
+	partSize          int64
+	connections       int
+	obfuscateNames    bool
+	obfuscateSubjects bool
+	encodeOptions     []yenc.EncodeOption
+
+	mu  sync.Mutex
+	nzb nzb.NZB
+}
+
+func NewUploader(pool *nntp.Pool, groups []string, options ...UploadOption) (u *Uploader, err error) {
+	u = option.New(options,
+		UploadWithPartSize(DefaultPartSize),
+		UploadWithConnections(1))
+	u.pool = pool
+	u.groups = groups
+	if u.partSize <= 0 {
+		err = fmt.Errorf("[Post] part size %d: %w", u.partSize, ErrInvalidPartSize)
+	} else if u.connections <= 0 {
+		err = fmt.Errorf("[Post] %d connections: %w", u.connections, ErrInvalidConnections)
+	}
+	if err != nil {
+		u = nil
+		return
+	}
+	if u.from == "" {
+		// a random identity, but the same for every article of the upload
+		var user, host string
+		if user, err = randomToken(6); err == nil {
+			host, err = randomToken(6)
+		}
+		if err != nil {
+			u = nil
+			return
+		}
+		u.from = user + " <" + user + "@" + host + ".invalid>"
+	}
+	// the form the From header is written in, which the NZB records as the poster
+	if addr, perr := mail.ParseAddress(u.from); perr == nil {
+		u.from = addr.String()
+	}
+	return
+}
+
+// Encode and post a file in parts of the configured size, and record it in the NZB. Parts are posted over as many
+// connections as configured, each part being retried on the other servers of the pool if it fails.
+func (u *Uploader) Upload(ctx context.Context, name string, r io.ReaderAt, size int64) (f nzb.File, err error) {
+	posted := name
+	if u.obfuscateNames {
+		if posted, err = randomToken(16); err != nil {
+			return
+		}
+	}
+	total := uint64((size + u.partSize - 1) / u.partSize)
+	if total == 0 {
+		total = 1
+	}
+	f = nzb.File{Poster: u.from, Date: time.Now().Unix(), Groups: u.groups, Segments: make([]nzb.Segment, total)}
+	subjects := make([]string, total)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		parts    = make(chan uint64)
+		firstErr error
+	)
+	for i := 0; i < u.connections; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range parts {
+				s, err := u.postPart(ctx, posted, r, size, part, total, &f.Segments[part-1])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				subjects[part-1] = s
+			}
+		}()
+	}
+	for part := uint64(1); part <= total && ctx.Err() == nil; part++ {
+		parts <- part
+	}
+	close(parts)
+	wg.Wait()
+	if err = firstErr; err != nil {
+		return
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	f.Subject = subjects[0]
+	u.mu.Lock()
+	u.nzb.Files = append(u.nzb.Files, f)
+	if u.obfuscateNames || u.obfuscateSubjects {
+		u.nzb.Meta = append(u.nzb.Meta, f.NameMeta(name))
+	}
+	u.mu.Unlock()
+	return
+}
+
+// Post one part, returning the subject it was posted with.
+func (u *Uploader) postPart(ctx context.Context, name string, r io.ReaderAt, size int64, part, total uint64, segment *nzb.Segment) (subject string, err error) {
+	begin := int64(part-1) * u.partSize
+	end := begin + u.partSize
+	if end > size {
+		end = size
+	}
+	subject = yenc.Subject{Name: name, Part: part, Total: total, YEnc: true, Size: uint64(size)}.String()
+	if u.obfuscateSubjects {
+		if subject, err = randomToken(16); err != nil {
+			return
+		}
+	}
+	article := yenc.Article{From: u.from, Newsgroups: u.groups, Subject: subject}
+	if article.MessageID, err = yenc.GenerateMessageID(u.messageIDDomain()); err != nil {
+		return
+	}
+	options := append([]yenc.EncodeOption{
+		yenc.EncodeWithPart(part, total, uint64(begin), uint64(end)),
+		yenc.EncodeWithPartCrc32ForLastPart(),
+	}, u.encodeOptions...)
+	_, err = u.pool.Do(ctx, func(c *nntp.Conn) (err error) {
+		var w io.WriteCloser
+		if w, err = c.Post(); err != nil {
+			return
+		}
+		counter := &countingWriter{w: w}
+		var a *yenc.ArticleWriter
+		if a, err = yenc.EncodeArticle(counter, article, name, uint64(size), options...); err != nil {
+			return
+		}
+		if _, err = io.Copy(a, io.NewSectionReader(r, begin, end-begin)); err != nil {
+			return
+		}
+		if err = a.Close(); err != nil {
+			return
+		}
+		if err = w.Close(); err != nil {
+			return
+		}
+		*segment = nzb.Segment{Bytes: counter.n, Number: int(part), MessageID: article.MessageID[1 : len(article.MessageID)-1]}
+		return
+	})
+	if err != nil {
+		err = fmt.Errorf("[Post] failed to post part %d of %s: %w", part, name, err)
+	}
+	return
+}
+
+func (u *Uploader) messageIDDomain() string {
+	if u.domain != "" {
+		return u.domain
+	}
+	return yenc.MessageIDDomain
+}
+
+// The NZB of the files uploaded so far.
+func (u *Uploader) NZB() *nzb.NZB {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return &nzb.NZB{
+		Meta:  append([]nzb.Meta(nil), u.nzb.Meta...),
+		Files: append([]nzb.File(nil), u.nzb.Files...),
+	}
+}
+
+// Identity the articles are posted from.
+func (u *Uploader) From() string {
+	return u.from
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (n int, err error) {
+	n, err = c.w.Write(b)
+	c.n += int64(n)
+	return
+}
+
+// Random lower case hexadecimal token of 2n characters.
+func randomToken(n int) (token string, err error) {
+	b := make([]byte, n)
+	if _, err = rand.Read(b); err != nil {
+		err = fmt.Errorf("[Post] failed to generate a random token: %w", err)
+		return
+	}
+	token = hex.EncodeToString(b)
+	return
+}
+
+// Default size of the data of each article, the 700 KiB most posting tools use.
+var DefaultPartSize int64 = 700 * 1024
+
+type UploadOption func(*Uploader)
+
+// Split files into parts of size bytes. NewUploader fails with ErrInvalidPartSize if size is not positive.
+func UploadWithPartSize(size int64) UploadOption {
+	return func(u *Uploader) {
+		u.partSize = size
+	}
+}
+
+// Post up to n parts of a file at the same time. NewUploader fails with ErrInvalidConnections if n is not positive.
+func UploadWithConnections(n int) UploadOption {
+	return func(u *Uploader) {
+		u.connections = n
+	}
+}
+
+// Post every article from the given identity, e.g. "poster <poster@example.com>". Without it, a random identity is
+// generated for the Uploader.
+func UploadWithFrom(from string) UploadOption {
+	return func(u *Uploader) {
+		u.from = from
+	}
+}
+
+// Generate Message-IDs in the given domain instead of yenc.MessageIDDomain.
+func UploadWithDomain(domain string) UploadOption {
+	return func(u *Uploader) {
+		u.domain = domain
+	}
+}
+
+// Replace the name of each file with a random token in the =ybegin line and the subjects.
+func UploadWithObfuscatedNames() UploadOption {
+	return func(u *Uploader) {
+		u.obfuscateNames = true
+	}
+}
+
+// Post each article with a random subject, without the name, the part counter or the yEnc marker.
+func UploadWithObfuscatedSubjects() UploadOption {
+	return func(u *Uploader) {
+		u.obfuscateSubjects = true
+	}
+}
+
+// Encode options applied to every part, e.g. line length or EOL.
+func UploadWithEncodeOptions(options ...yenc.EncodeOption) UploadOption {
+	return func(u *Uploader) {
+		u.encodeOptions = options
+	}
+}
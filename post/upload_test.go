@@ -0,0 +1,138 @@
+package post
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yenc.v0"
+	"gopkg.in/yenc.v0/nntp"
+	"gopkg.in/yenc.v0/nntp/nntptest"
+)
+
+const testGroup = "alt.binaries.test"
+
+func testUpload(t *testing.T, options ...UploadOption) (p *nntp.Pool, u *Uploader, raw []byte) {
+	raw, err := os.ReadFile("../fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := nntptest.NewServer()
+	t.Cleanup(srv.Close)
+	if p, err = nntp.NewPool([]nntp.Server{{Addr: srv.Addr, MaxConns: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	options = append([]UploadOption{UploadWithPartSize(1000), UploadWithConnections(2)}, options...)
+	if u, err = NewUploader(p, []string{testGroup}, options...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = u.Upload(context.Background(), "ngPost-raw.bin", bytes.NewReader(raw), int64(len(raw))); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+// Fetch the segments of the uploaded file, checking that they make up the original data under a consistent name.
+func fetchUpload(t *testing.T, p *nntp.Pool, u *Uploader, raw []byte) (name string, subjects []string) {
+	n := u.NZB()
+	if len(n.Files) != 1 || len(n.Files[0].Segments) != 5 {
+		t.Fatalf("unexpected NZB %+v", n)
+	}
+	data := make([]byte, len(raw))
+	for i, s := range n.Files[0].Segments {
+		part, err := p.Fetch(context.Background(), s.MessageID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			name = part.Header.Name
+		} else if part.Header.Name != name {
+			t.Errorf("part %d is named %s instead of %s", s.Number, part.Header.Name, name)
+		}
+		if s.Number != i+1 || part.Header.Part != uint64(s.Number) || part.Header.Total != 5 {
+			t.Errorf("unexpected segment %+v of part %d/%d", s, part.Header.Part, part.Header.Total)
+		}
+		copy(data[part.Header.Begin:], part.Data)
+		p.Do(context.Background(), func(c *nntp.Conn) error {
+			h, err := c.Head(s.MessageID)
+			if err != nil {
+				return err
+			}
+			if h.Get("From") != u.From() || h.Get("Newsgroups") != testGroup {
+				t.Errorf("unexpected headers %v", h)
+			}
+			subjects = append(subjects, h.Get("Subject"))
+			return nil
+		})
+	}
+	if !bytes.Equal(data, raw) {
+		t.Error("uploaded data mismatch")
+	}
+	if subjects[0] != n.Files[0].Subject || n.Files[0].Poster != u.From() {
+		t.Errorf("NZB file entry %+v does not match the articles", n.Files[0])
+	}
+	return
+}
+
+func TestUpload(t *testing.T) {
+	p, u, raw := testUpload(t, UploadWithFrom("poster <poster@example.com>"), UploadWithDomain("example.com"))
+	name, subjects := fetchUpload(t, p, u, raw)
+	if name != "ngPost-raw.bin" || subjects[2] != `"ngPost-raw.bin" yEnc (3/5) 4682` {
+		t.Errorf("unexpected name %s and subject %s", name, subjects[2])
+	}
+	n := u.NZB()
+	if len(n.Meta) != 0 || n.FileName(0) != "ngPost-raw.bin" {
+		t.Errorf("unexpected NZB meta %+v", n.Meta)
+	}
+	if !strings.HasSuffix(n.Files[0].Segments[0].MessageID, "@example.com") {
+		t.Errorf("unexpected Message-ID %s", n.Files[0].Segments[0].MessageID)
+	}
+}
+
+func TestUploadObfuscated(t *testing.T) {
+	p, u, raw := testUpload(t, UploadWithObfuscatedNames(), UploadWithObfuscatedSubjects())
+	name, subjects := fetchUpload(t, p, u, raw)
+	if strings.Contains(name, "ngPost") {
+		t.Errorf("expect an obfuscated name but got %s", name)
+	}
+	seen := make(map[string]bool)
+	for _, s := range subjects {
+		if seen[s] || strings.Contains(s, "ngPost") || strings.Contains(s, name) || strings.Contains(s, "yEnc") {
+			t.Errorf("expect unique random subjects but got %#v", s)
+		}
+		seen[s] = true
+	}
+	if !strings.HasSuffix(u.From(), ".invalid>") {
+		t.Errorf("expect a random identity but got %s", u.From())
+	}
+	if n := u.NZB(); n.FileName(0) != "ngPost-raw.bin" {
+		t.Errorf("expect the real name in the NZB but got %s", n.FileName(0))
+	}
+}
+
+func TestUploadObfuscatedNames(t *testing.T) {
+	p, u, raw := testUpload(t, UploadWithObfuscatedNames())
+	name, subjects := fetchUpload(t, p, u, raw)
+	// the subject carries the obfuscated name, consistent with the =ybegin line
+	if s, ok := yenc.ParseSubject(subjects[0]); !ok || s.Name != name || name == "ngPost-raw.bin" {
+		t.Errorf("subject %s does not match name %s", subjects[0], name)
+	}
+	if n := u.NZB(); n.FileName(0) != "ngPost-raw.bin" || n.Files[0].Name() != name {
+		t.Errorf("unexpected NZB names %s and %s", n.FileName(0), n.Files[0].Name())
+	}
+}
+
+func TestNewUploaderInvalidOptions(t *testing.T) {
+	if _, err := NewUploader(nil, []string{testGroup}, UploadWithPartSize(0)); !errors.Is(err, ErrInvalidPartSize) {
+		t.Errorf("expect ErrInvalidPartSize but got %v", err)
+	}
+	for _, n := range []int{0, -1} {
+		if _, err := NewUploader(nil, []string{testGroup}, UploadWithConnections(n)); !errors.Is(err, ErrInvalidConnections) {
+			t.Errorf("expect ErrInvalidConnections for %d connections but got %v", n, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package uu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"gopkg.in/option.v0"
+)
+
+type Decoder struct {
+	h      Header
+	format Format
+	br     *bufio.Reader
+	first  string // First data line, read to detect the format
+	data   []byte // Decoded data of the current line not read yet
+	done   bool   // The end line was read
+
+	// If the begin line is not at the beginning of the data stream, returns ErrRejectPrefixData
+	allowPrefixData bool
+	detect          bool
+}
+
+// Read the begin line and the first data line of a uuencoded or xxencoded data stream, whose alphabet tells the
+// format unless set by DecodeWithFormat. Data lines are decoded as they are read.
+func Decode(r io.Reader, options ...DecodeOption) (d *Decoder, err error) {
+	d = option.New(options, func(d *Decoder) { d.detect = true })
+	d.br = bufio.NewReader(r)
+	if err = d.readHeader(); err != nil {
+		d = nil
+		return
+	}
+	if d.first, err = d.readLine(); err != nil {
+		d = nil
+		return
+	}
+	if d.detect {
+		d.format = detect(d.first)
+	}
+	return
+}
+
+func (d *Decoder) readHeader() (err error) {
+	for {
+		var line string
+		if line, err = d.readLine(); err != nil {
+			return
+		}
+		if h, ok := parseBegin(line); ok {
+			d.h = h
+			return
+		}
+		if !d.allowPrefixData {
+			err = ErrRejectPrefixData
+			return
+		}
+	}
+}
+
+// Parse a "begin <mode> <name>" line.
+func parseBegin(line string) (h Header, ok bool) {
+	if !strings.HasPrefix(line, "begin ") {
+		return
+	}
+	mode, name, found := strings.Cut(line[len("begin "):], " ")
+	if !found || name == "" || len(mode) < 3 || len(mode) > 4 {
+		return
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return
+	}
+	h, ok = Header{Name: name, Mode: fs.FileMode(m).Perm()}, true
+	return
+}
+
+// Format of a data line, preferring one whose line length is exact, then uuencode.
+func detect(line string) Format {
+	exact := func(f Format) bool {
+		n := f.decode(line[0])
+		return f.isLine(line) && (len(line)-1 == (n+2)/3*4 || len(line)-1 == (4*n+2)/3)
+	}
+	if line != "" && !exact(UU) && (exact(XX) || !UU.isLine(line) && XX.isLine(line)) {
+		return XX
+	}
+	return UU
+}
+
+// Read a line without its line ending. Returns ErrInvalidFormat at the end of the stream.
+func (d *Decoder) readLine() (line string, err error) {
+	line, err = d.br.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err == io.EOF {
+		err = fmt.Errorf("[uu] no end line: %w", ErrInvalidFormat)
+		return
+	}
+	line = strings.TrimRight(line, "\r\n")
+	return
+}
+
+func (d *Decoder) Read(b []byte) (n int, err error) {
+	for n < len(b) {
+		if len(d.data) == 0 {
+			if d.done {
+				break
+			}
+			if err = d.readData(); err != nil {
+				break
+			}
+			continue
+		}
+		i := copy(b[n:], d.data)
+		d.data = d.data[i:]
+		n += i
+	}
+	if n == 0 && err == nil {
+		err = io.EOF
+	}
+	return
+}
+
+// Decode the next data line, or read the end line.
+func (d *Decoder) readData() (err error) {
+	line := d.first
+	if d.first != "" {
+		d.first = ""
+	} else if line, err = d.readLine(); err != nil {
+		return
+	}
+	if line == "end" {
+		d.done = true
+		return
+	}
+	if line == "" {
+		// a zero length line whose space was stripped in transit
+		return
+	}
+	if !d.format.isLine(line) {
+		err = fmt.Errorf("[uu] invalid %s line %q: %w", d.format, line, ErrDataCorruption)
+		return
+	}
+	n := d.format.decode(line[0])
+	d.data = d.data[:0]
+	for i := 1; len(d.data) < n; i += 4 {
+		var v [4]int
+		for j := range v {
+			if i+j < len(line) {
+				v[j] = d.format.decode(line[i+j])
+			}
+		}
+		d.data = append(d.data, byte(v[0]<<2|v[1]>>4), byte(v[1]<<4|v[2]>>2), byte(v[2]<<6|v[3]))
+	}
+	d.data = d.data[:n]
+	return
+}
+
+func (d *Decoder) Header() *Header {
+	return &d.h
+}
+
+func (d *Decoder) Format() Format {
+	return d.format
+}
+
+type DecodeOption func(*Decoder)
+
+func DecodeWithPrefixData() DecodeOption {
+	return func(d *Decoder) {
+		d.allowPrefixData = true
+	}
+}
+
+// Decode in the given format instead of detecting it from the first data line.
+func DecodeWithFormat(f Format) DecodeOption {
+	return func(d *Decoder) {
+		d.format = f
+		d.detect = false
+	}
+}
@@ -0,0 +1,96 @@
+package uu
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+
+	"gopkg.in/option.v0"
+)
+
+type Encoder struct {
+	h      Header
+	w      io.Writer
+	format Format
+	eol    string
+	buf    []byte // Data of the current line
+	line   []byte
+}
+
+// Write the begin line, then return an Encoder to write the data through, in lines of LineLimit bytes. Close writes
+// the last line and the end line.
+func Encode(w io.Writer, name string, mode fs.FileMode, options ...EncodeOption) (e *Encoder, err error) {
+	e = option.New(options, EncodeWithFormat(UU), EncodeWithEOL("\n"))
+	e.w = w
+	e.h = Header{Name: name, Mode: mode.Perm()}
+	e.buf = make([]byte, 0, LineLimit)
+	if _, err = fmt.Fprintf(w, "begin %03o %s%s", e.h.Mode, name, e.eol); err != nil {
+		err = fmt.Errorf("[uu] failed to write header: %w", err)
+	}
+	return
+}
+
+func (e *Encoder) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		i := copy(e.buf[len(e.buf):cap(e.buf)], b)
+		e.buf = e.buf[:len(e.buf)+i]
+		b = b[i:]
+		n += i
+		if len(e.buf) == cap(e.buf) {
+			if err = e.writeLine(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (e *Encoder) writeLine() (err error) {
+	e.line = append(e.line[:0], e.format.encode(byte(len(e.buf))))
+	for i := 0; i < len(e.buf); i += 3 {
+		var g [3]byte
+		copy(g[:], e.buf[i:])
+		e.line = append(e.line,
+			e.format.encode(g[0]>>2),
+			e.format.encode(g[0]<<4|g[1]>>4),
+			e.format.encode(g[1]<<2|g[2]>>6),
+			e.format.encode(g[2]))
+	}
+	e.line = append(e.line, e.eol...)
+	e.buf = e.buf[:0]
+	_, err = e.w.Write(e.line)
+	return
+}
+
+func (e *Encoder) Header() *Header {
+	return &e.h
+}
+
+// Write the last data line, a zero length line and the end line.
+func (e *Encoder) Close() (err error) {
+	if len(e.buf) > 0 {
+		if err = e.writeLine(); err != nil {
+			return
+		}
+	}
+	if err = e.writeLine(); err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(e.w, "end%s", e.eol)
+	return
+}
+
+type EncodeOption func(*Encoder)
+
+func EncodeWithFormat(f Format) EncodeOption {
+	return func(e *Encoder) {
+		e.format = f
+	}
+}
+
+// Line ending, LF by default as written by uuencode(1).
+func EncodeWithEOL(eol string) EncodeOption {
+	return func(e *Encoder) {
+		e.eol = eol
+	}
+}
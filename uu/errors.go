@@ -0,0 +1,7 @@
+package uu
+
+import "errors"
+
+var ErrInvalidFormat = errors.New("not a valid uuencode or xxencode formatted data stream")
+var ErrRejectPrefixData = errors.New("begin line not at the beginning of the data stream")
+var ErrDataCorruption = errors.New("data corruption detected")
@@ -0,0 +1,78 @@
+// Package uu decodes and encodes uuencoded and xxencoded data, the formats of binaries posted before yEnc. Both map 3
+// bytes to 4 characters on lines of up to 45 bytes, between a "begin <mode> <name>" and an "end" line, and differ only
+// in their alphabet.
+package uu
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// An encoding alphabet.
+type Format int
+
+const (
+	UU Format = iota // Characters 0x20 to 0x5f, with ` for zero
+	XX               // +, -, digits and letters
+)
+
+func (f Format) String() string {
+	if f == XX {
+		return "xxencode"
+	}
+	return "uuencode"
+}
+
+// Header of the "begin" line.
+type Header struct {
+	Name string
+	Mode fs.FileMode // Permission bits
+}
+
+// Max number of bytes per line, as written by uuencode(1).
+const LineLimit = 45
+
+const xxAlphabet = "+-0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func (f Format) encode(v byte) byte {
+	if f == XX {
+		return xxAlphabet[v&63]
+	}
+	if v&63 == 0 {
+		return '`'
+	}
+	return v&63 + ' '
+}
+
+// Value of an encoded character, or -1 if it is not in the alphabet. Spaces decode to zero in uuencode, as written by
+// old encoders.
+func (f Format) decode(c byte) int {
+	if f == XX {
+		return strings.IndexByte(xxAlphabet, c)
+	}
+	if c < ' ' || c > '`' {
+		return -1
+	}
+	return int(c-' ') & 63
+}
+
+// Whether a line is a well-formed data line of the format: its length character matches the number of characters.
+// Trailing spaces of uuencoded lines may have been stripped in transit, so shorter lines are accepted.
+func (f Format) isLine(line string) bool {
+	if line == "" {
+		return false
+	}
+	n := f.decode(line[0])
+	if n < 0 || n > LineLimit {
+		return false
+	}
+	for i := 1; i < len(line); i++ {
+		if f.decode(line[i]) < 0 {
+			return false
+		}
+	}
+	if len(line)-1 > (n+2)/3*4 {
+		return false
+	}
+	return f == UU || len(line)-1 >= (4*n+2)/3
+}
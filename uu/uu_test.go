@@ -0,0 +1,97 @@
+package uu
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Encoded by Python's binascii.b2a_uu with backtick=True
+const uuFixture = "begin 644 data.bin\n" +
+	"M``$\"`P0%!@<(\"0H+#`T.#Q`1$A,4%187&!D:&QP='A\\@(2(C)\"4F)R@I*BLL\n" +
+	"%+2XO,#$`\n" +
+	"`\n" +
+	"end\n"
+
+func fixtureData() []byte {
+	b := make([]byte, 50)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		article string
+		format  Format
+	}{
+		{"uuencode", uuFixture, UU},
+		{"CRLF and prefix", "Here it is:\r\n\r\n" + strings.ReplaceAll(uuFixture, "\n", "\r\n"), UU},
+		// spaces for zero, then stripped from the end of lines
+		{"stripped", strings.NewReplacer("`\n", "\n", "`", " ").Replace(uuFixture), UU},
+		{"xxencode", "begin 644 data.bin\nh++20+kE3-UQ60Ec91+oC1l+F2VAI3FML4-YO4lkR5VwU6G6X70Ia7mUd8Wgg\n39GsjA12+\n+\nend\n", XX},
+	} {
+		d, err := Decode(strings.NewReader(c.article), DecodeWithPrefixData())
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		data, err := io.ReadAll(d)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if h := d.Header(); h.Name != "data.bin" || h.Mode != 0644 || d.Format() != c.format || !bytes.Equal(data, fixtureData()) {
+			t.Errorf("%s: unexpected %+v %s %x", c.name, h, d.Format(), data)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := Decode(strings.NewReader("text\n" + uuFixture)); !errors.Is(err, ErrRejectPrefixData) {
+		t.Errorf("expect prefix data to be rejected but got %v", err)
+	}
+	d, err := Decode(strings.NewReader(strings.TrimSuffix(uuFixture, "`\nend\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.ReadAll(d); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expect a missing end line to fail but got %v", err)
+	}
+	d, err = Decode(strings.NewReader(strings.Replace(uuFixture, "%+2XO", "%+2XOOOOOOOOO", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.ReadAll(d); !errors.Is(err, ErrDataCorruption) {
+		t.Errorf("expect a line too long to fail but got %v", err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, f := range []Format{UU, XX} {
+		var b bytes.Buffer
+		e, err := Encode(&b, "data.bin", 0644, EncodeWithFormat(f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Write(fixtureData()[:20])
+		e.Write(fixtureData()[20:])
+		if err = e.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if f == UU && b.String() != uuFixture {
+			t.Errorf("expect %q but got %q", uuFixture, b.String())
+		}
+		d, err := Decode(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, err := io.ReadAll(d); err != nil || d.Format() != f || !bytes.Equal(data, fixtureData()) {
+			t.Errorf("%s: expect a round trip but got %s %x: %v", f, d.Format(), data, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package yenc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yenc.v0/uu"
+)
+
+// Decoded data of a binary in any format DecodeAny detects: a *Decoder for yEnc, or a *uu.Decoder for uuencode and
+// xxencode.
+type AnyDecoder interface {
+	io.Reader
+	FileName() string
+	Format() string // "yEnc", "uuencode" or "xxencode"
+}
+
+// Max number of lines DecodeAny reads to find the first line of a binary.
+var SniffLineLimit = 1000
+
+// Decode the first binary in r, detected by its first line: =ybegin for yEnc, "begin <mode> <name>" for uuencode or
+// xxencode, told apart by their first data line. Lines before it, e.g. the text of a post, are skipped. The options
+// apply to yEnc only. Returns ErrNoBinary if no such line is found within SniffLineLimit lines.
+func DecodeAny(r io.Reader, options ...DecodeOption) (d AnyDecoder, err error) {
+	br := bufio.NewReaderSize(r, BufferLimit)
+	for i := 0; i < SniffLineLimit; i++ {
+		var line []byte
+		if line, err = br.ReadSlice('\n'); err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return
+		}
+		// the sniffed line is read again by the decoder
+		rest := io.MultiReader(bytes.NewReader(append([]byte(nil), line...)), br)
+		text := strings.TrimRight(string(line), "\r\n")
+		if strings.HasPrefix(text, "=ybegin ") {
+			var y *Decoder
+			if y, err = Decode(rest, options...); err == nil {
+				d = y
+			}
+			return
+		}
+		if isUUBegin(text) {
+			var u *uu.Decoder
+			if u, err = uu.Decode(rest); err == nil {
+				d = uuDecoder{u}
+			}
+			return
+		}
+		for err == bufio.ErrBufferFull {
+			_, err = br.ReadSlice('\n')
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+	}
+	err = fmt.Errorf("[yEnc] %w", ErrNoBinary)
+	return
+}
+
+// Whether a line is a "begin <mode> <name>" line, with a mode of 3 or 4 octal digits.
+func isUUBegin(line string) bool {
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) < 3 || fields[0] != "begin" || fields[2] == "" || len(fields[1]) < 3 || len(fields[1]) > 4 {
+		return false
+	}
+	return strings.Trim(fields[1], "01234567") == ""
+}
+
+func (d *Decoder) FileName() string {
+	return d.h.Name
+}
+
+func (d *Decoder) Format() string {
+	return "yEnc"
+}
+
+type uuDecoder struct {
+	*uu.Decoder
+}
+
+func (d uuDecoder) FileName() string {
+	return d.Header().Name
+}
+
+func (d uuDecoder) Format() string {
+	return d.Decoder.Format().String()
+}
@@ -0,0 +1,46 @@
+package yenc
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAny(t *testing.T) {
+	ntx, err := os.ReadFile("fixture/yEncBinPoster-001.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile("fixture/yEncBinPoster-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		article string
+		format  string
+		name    string
+		size    int
+	}{
+		{"Please begin the download now\r\n\r\n" + string(ntx), "yEnc", "yEncBinPoster-raw.bin", len(raw)},
+		{"The file:\n\nbegin 644 cat.txt\n#0V%T\n`\nend\n", "uuencode", "cat.txt", 3},
+		{"begin 0600 cat.txt\n1Eq3o\n+\nend\n", "xxencode", "cat.txt", 3},
+	} {
+		d, err := DecodeAny(strings.NewReader(c.article))
+		if err != nil {
+			t.Errorf("%s: %v", c.format, err)
+			continue
+		}
+		data, err := io.ReadAll(d)
+		if err != nil {
+			t.Errorf("%s: %v", c.format, err)
+		}
+		if d.Format() != c.format || d.FileName() != c.name || len(data) != c.size {
+			t.Errorf("expect %s %s of %d bytes but got %s %s of %d bytes", c.format, c.name, c.size, d.Format(), d.FileName(), len(data))
+		}
+	}
+	if _, err = DecodeAny(strings.NewReader("no binary here\n")); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expect no binary to be found but got %v", err)
+	}
+}
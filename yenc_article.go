@@ -0,0 +1,192 @@
+package yenc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Usenet article metadata, written as RFC 5536 headers in front of the yEnc body.
+type Article struct {
+	From       string
+	Newsgroups []string
+	Subject    string    // If empty, formatted from the yEnc header as `"name" yEnc (part/total)`
+	MessageID  string    // If empty, a unique one is generated in Domain
+	Domain     string    // Domain of the generated Message-ID. If empty, MessageIDDomain is used.
+	Date       time.Time // If zero, the current time is used
+
+	// Additional headers, e.g. X-Newsposter or Organization. Written in sorted order after the standard headers.
+	Headers map[string][]string
+}
+
+// Writes a complete article: headers, an empty line, then the yEnc encoded body. Write and Close behave as the
+// embedded Encoder's.
+type ArticleWriter struct {
+	*Encoder
+	a Article
+}
+
+// Write the headers of an article and the yEnc header of its body, then return an ArticleWriter to write the file
+// (part) data through. The EOL of the encode options is also used for the article headers, and defaults to CRLF. When
+// writing to an NNTP connection without a textproto.DotWriter, ExtendedCriticalChars should be used.
+func EncodeArticle(w io.Writer, article Article, fileName string, fileSize uint64, options ...EncodeOption) (a *ArticleWriter, err error) {
+	options = append([]EncodeOption{EncodeWithEOL("\r\n")}, options...)
+	a = &ArticleWriter{Encoder: newEncoder(w, fileName, fileSize, options), a: article}
+	if a.a.Subject == "" {
+		a.a.Subject = formatArticleSubject(&a.h)
+	}
+	if a.a.MessageID == "" {
+		domain := a.a.Domain
+		if domain == "" {
+			domain = MessageIDDomain
+		}
+		if a.a.MessageID, err = GenerateMessageID(domain); err != nil {
+			return
+		}
+	}
+	a.a.MessageID = FormatMessageID(a.a.MessageID)
+	if a.a.Date.IsZero() {
+		a.a.Date = time.Now()
+	}
+	if err = a.writeHeaders(w, a.eol); err != nil {
+		return
+	}
+	err = a.writeHeader()
+	return
+}
+
+func (a *ArticleWriter) writeHeaders(w io.Writer, eol string) (err error) {
+	var b strings.Builder
+	header := func(key, value string) {
+		b.WriteString(key)
+		b.WriteString(": ")
+		b.WriteString(sanitizeHeaderValue(value))
+		b.WriteString(eol)
+	}
+	header("From", formatFrom(a.a.From))
+	header("Newsgroups", strings.Join(a.a.Newsgroups, ","))
+	header("Subject", encodeWords(a.a.Subject))
+	header("Message-ID", a.a.MessageID)
+	header("Date", a.a.Date.Format(time.RFC1123Z))
+	keys := make([]string, 0, len(a.a.Headers))
+	for key := range a.a.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range a.a.Headers[key] {
+			header(sanitizeHeaderKey(key), encodeWords(value))
+		}
+	}
+	b.WriteString(eol)
+	if _, err = io.WriteString(w, b.String()); err != nil {
+		err = fmt.Errorf("[yEnc] failed to write article headers: %w", err)
+	}
+	return
+}
+
+// The article metadata as written, including the generated Subject, Message-ID and Date.
+func (a *ArticleWriter) Article() *Article {
+	return &a.a
+}
+
+// Format a Message-ID with surrounding spaces removed and with angle brackets, adding the missing ones.
+func FormatMessageID(messageID string) string {
+	messageID = strings.TrimSpace(messageID)
+	if !strings.HasPrefix(messageID, "<") {
+		messageID = "<" + messageID
+	}
+	if !strings.HasSuffix(messageID, ">") {
+		messageID += ">"
+	}
+	return messageID
+}
+
+// The From header value: an address with its display name encoded if needed, as RFC 2047 forbids encoded-words in the
+// address itself. A value that does not parse as an address is written with its non-ASCII words encoded.
+func formatFrom(from string) string {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		return addr.String()
+	}
+	return encodeWords(from)
+}
+
+// Encode the runs of words with non-ASCII characters as RFC 2047 encoded-words, leaving the ASCII words as they are so
+// that e.g. the `yEnc (1/5)` part of a subject stays readable to indexers. Spaces between the words of a run are
+// encoded with them, as the space between two encoded-words is dropped when decoding.
+func encodeWords(value string) string {
+	words := strings.Split(value, " ")
+	for i := 0; i < len(words); i++ {
+		j := i
+		for j < len(words) && !isASCII(words[j]) {
+			j++
+		}
+		if j > i {
+			encoded := mime.QEncoding.Encode("utf-8", strings.Join(words[i:j], " "))
+			words = append(words[:i], append([]string{encoded}, words[j:]...)...)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// Generate a unique Message-ID, angle brackets included, in the given domain.
+func GenerateMessageID(domain string) (messageID string, err error) {
+	var b [16]byte
+	if _, err = rand.Read(b[:]); err != nil {
+		err = fmt.Errorf("[yEnc] failed to generate Message-ID: %w", err)
+		return
+	}
+	messageID = "<" + hex.EncodeToString(b[:]) + "@" + domain + ">"
+	return
+}
+
+// The conventional subject of a yEnc post as recommended by yEnc 1.3: `"name" yEnc (part/total)`. Single-part files
+// are numbered (1/1).
+func formatArticleSubject(h *Header) string {
+	part, total := h.Part, h.Total
+	if part == 0 {
+		part = 1
+	}
+	if total == 0 {
+		total = part
+	}
+	return Subject{Name: h.Name, Part: part, Total: total, YEnc: true}.String()
+}
+
+// Header values can not contain line breaks, which would otherwise inject headers or end the header block early.
+func sanitizeHeaderValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return ' '
+		}
+		return r
+	}, value)
+}
+
+func sanitizeHeaderKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == ':' || r >= 0x7f {
+			return '-'
+		}
+		return r
+	}, key)
+}
+
+// Default domain of generated Message-IDs. The ".invalid" top-level domain is reserved and never clashes with real
+// hosts, but posting under a domain you control is recommended.
+var MessageIDDomain = "yenc.invalid"
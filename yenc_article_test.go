@@ -0,0 +1,159 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/mail"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeArticle(t *testing.T) {
+	raw, err := os.ReadFile("fixture/encode-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, err := os.ReadFile("fixture/encode-001.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	date := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	a, err := EncodeArticle(&b, Article{
+		From:       "Poster <poster@example.com>",
+		Newsgroups: []string{"alt.binaries.test", "alt.binaries.misc"},
+		Domain:     "example.com",
+		Date:       date,
+		Headers:    map[string][]string{"X-Newsposter": {"yenc"}, "Bad\r\nKey": {"evil\r\nInjected: header"}},
+	}, "encode-raw.bin", uint64(len(raw)),
+		EncodeWithPart(1, 10, 0, 512),
+		EncodeWithLF(),
+		EncodeWithPartCrc32ForLastPart())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Write(append([]byte(nil), raw[:512]...)); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := mail.ReadMessage(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{
+		"From":         `"Poster" <poster@example.com>`,
+		"Newsgroups":   "alt.binaries.test,alt.binaries.misc",
+		"Subject":      `"encode-raw.bin" yEnc (1/10)`,
+		"Message-Id":   a.Article().MessageID,
+		"Date":         "Sat, 01 Oct 2022 12:00:00 +0000",
+		"X-Newsposter": "yenc",
+		"Injected":     "",
+	} {
+		if m.Header.Get(key) != value {
+			t.Errorf("expect header %s to be %#v but got %#v", key, value, m.Header.Get(key))
+		}
+	}
+	if !strings.HasSuffix(a.Article().MessageID, "@example.com>") {
+		t.Errorf("unexpected Message-ID %s", a.Article().MessageID)
+	}
+	body, err := io.ReadAll(m.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, expect) {
+		t.Error("article body mismatch with encoded part")
+	}
+}
+
+func TestEncodeArticleSinglePart(t *testing.T) {
+	var b bytes.Buffer
+	a, err := EncodeArticle(&b, Article{From: "poster@example.com", Newsgroups: []string{"alt.binaries.test"}}, "héllo.bin", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Write([]byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Article().Subject != `"héllo.bin" yEnc (1/1)` {
+		t.Errorf("unexpected subject %#v", a.Article().Subject)
+	}
+	// only the non-ASCII word is encoded, so the subject still looks like a yEnc post to indexers
+	if !strings.Contains(b.String(), "\r\nSubject: =?utf-8?q?\"h=C3=A9llo.bin\"?= yEnc (1/1)\r\n") {
+		t.Errorf("expect the non-ASCII word of the subject to be encoded:\n%s", b.String())
+	}
+	if !strings.HasPrefix(b.String(), "From: <poster@example.com>\r\n") {
+		t.Errorf("unexpected From header:\n%s", b.String())
+	}
+	if !strings.Contains(b.String(), "\r\n\r\n=ybegin line=128 size=3 name=héllo.bin\r\n") {
+		t.Errorf("expect CRLF line endings by default:\n%s", b.String())
+	}
+	if a.Article().Date.IsZero() || a.Article().MessageID == "" {
+		t.Error("expect Date and Message-ID to be generated")
+	}
+}
+
+func TestEncodeArticleHeaderWords(t *testing.T) {
+	var b bytes.Buffer
+	a, err := EncodeArticle(&b, Article{
+		From:      "Jörg Müller <jorg@example.com>",
+		Subject:   `[1/2] - "日本 語.bin" yEnc (1/1) 3`,
+		MessageID: "<abc@example.com",
+	}, "a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m, err := mail.ReadMessage(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from := m.Header.Get("From"); !strings.HasSuffix(from, "?= <jorg@example.com>") {
+		t.Errorf("expect only the display name to be encoded but got %#v", from)
+	}
+	if addr, err := m.Header.AddressList("From"); err != nil || addr[0].Name != "Jörg Müller" {
+		t.Errorf("unexpected From %v: %v", addr, err)
+	}
+	subject := m.Header.Get("Subject")
+	if !strings.HasPrefix(subject, "[1/2] - =?utf-8?q?") || !strings.HasSuffix(subject, "?= yEnc (1/1) 3") {
+		t.Errorf("expect only the non-ASCII words to be encoded but got %#v", subject)
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(subject); err != nil || decoded != a.Article().Subject {
+		t.Errorf("subject decoded as %#v: %v", decoded, err)
+	}
+	if m.Header.Get("Message-Id") != "<abc@example.com>" {
+		t.Errorf("unexpected Message-ID %#v", m.Header.Get("Message-Id"))
+	}
+}
+
+func TestFormatMessageID(t *testing.T) {
+	for _, id := range []string{"abc@example", "<abc@example>", " <abc@example", "abc@example>"} {
+		if FormatMessageID(id) != "<abc@example>" {
+			t.Errorf("%#v formatted as %#v", id, FormatMessageID(id))
+		}
+	}
+}
+
+func TestGenerateMessageID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := GenerateMessageID("example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate Message-ID %s", id)
+		}
+		seen[id] = true
+	}
+}
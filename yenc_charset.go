@@ -0,0 +1,163 @@
+package yenc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// A character set the name= value can be written in. The yEnc specification leaves it undefined, and besides UTF-8
+// names are found in the legacy charset of the posting system, most often Latin-1 or its Windows variant.
+type Charset interface {
+	// IANA name of the charset, e.g. "windows-1252", as in RFC 2047 encoded-words.
+	Name() string
+	// Decode bytes to UTF-8, returning ErrCharset if they are not valid in the charset.
+	Decode(b []byte) (string, error)
+	// Encode a UTF-8 string, returning ErrCharset if it has characters the charset cannot represent.
+	Encode(s string) ([]byte, error)
+}
+
+var (
+	UTF8        Charset = utf8Charset{}
+	Latin1      Charset = &singleByteCharset{name: "iso-8859-1", high: latin1High()}
+	Windows1252 Charset = &singleByteCharset{name: "windows-1252", high: windows1252High}
+)
+
+// Charsets tried in order to decode a name= value that is not valid UTF-8. Windows-1252 is tried first as it is what
+// most legacy posting tools wrote, even when labeled Latin-1.
+var DefaultCharsets = []Charset{Windows1252, Latin1}
+
+type utf8Charset struct{}
+
+func (utf8Charset) Name() string {
+	return "utf-8"
+}
+
+func (utf8Charset) Decode(b []byte) (s string, err error) {
+	if !utf8.Valid(b) {
+		err = fmt.Errorf("[yEnc] %#v is not valid utf-8: %w", b, ErrCharset)
+		return
+	}
+	s = string(b)
+	return
+}
+
+func (utf8Charset) Encode(s string) (b []byte, err error) {
+	if !utf8.ValidString(s) {
+		err = fmt.Errorf("[yEnc] %#v is not valid utf-8: %w", s, ErrCharset)
+		return
+	}
+	b = []byte(s)
+	return
+}
+
+// Charset of one byte per character, ASCII compatible, where the bytes 0x80 to 0xff map to high. Bytes mapping to
+// utf8.RuneError are undefined.
+type singleByteCharset struct {
+	name string
+	high [128]rune
+}
+
+func (c *singleByteCharset) Name() string {
+	return c.name
+}
+
+func (c *singleByteCharset) Decode(b []byte) (s string, err error) {
+	var sb strings.Builder
+	for _, x := range b {
+		if x < 0x80 {
+			sb.WriteByte(x)
+			continue
+		}
+		r := c.high[x-0x80]
+		if r == utf8.RuneError {
+			err = fmt.Errorf("[yEnc] byte %#02x is undefined in %s: %w", x, c.name, ErrCharset)
+			return
+		}
+		sb.WriteRune(r)
+	}
+	s = sb.String()
+	return
+}
+
+func (c *singleByteCharset) Encode(s string) (b []byte, err error) {
+	b = make([]byte, 0, len(s))
+next:
+	for _, r := range s {
+		if r < 0x80 {
+			b = append(b, byte(r))
+			continue
+		}
+		for i, h := range c.high {
+			if h == r && h != utf8.RuneError {
+				b = append(b, byte(0x80+i))
+				continue next
+			}
+		}
+		err = fmt.Errorf("[yEnc] %#v has no %s encoding: %w", string(r), c.name, ErrCharset)
+		b = nil
+		return
+	}
+	return
+}
+
+func latin1High() (high [128]rune) {
+	for i := range high {
+		high[i] = rune(0x80 + i)
+	}
+	return
+}
+
+var windows1252High = func() (high [128]rune) {
+	high = latin1High()
+	copy(high[:32], []rune{
+		'€', utf8.RuneError, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', utf8.RuneError, 'Ž', utf8.RuneError,
+		utf8.RuneError, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', utf8.RuneError, 'ž', 'Ÿ',
+	})
+	return
+}()
+
+// Find a charset by its IANA name among the given and the built-in charsets, case insensitively.
+func lookupCharset(name string, charsets []Charset) Charset {
+	for _, c := range append(append([]Charset(nil), charsets...), UTF8, Latin1, Windows1252) {
+		if strings.EqualFold(c.Name(), name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Convert a raw name= value to UTF-8: if it is not valid UTF-8, decode it with the first of charsets that accepts it,
+// then decode the RFC 2047 encoded-words it may contain. The raw value is returned as is if nothing applies.
+func decodeName(raw string, charsets []Charset) (name string) {
+	name = raw
+	if !utf8.ValidString(name) {
+		for _, c := range charsets {
+			if s, err := c.Decode([]byte(raw)); err == nil {
+				name = s
+				break
+			}
+		}
+	}
+	if strings.Contains(name, "=?") {
+		d := mime.WordDecoder{CharsetReader: func(label string, r io.Reader) (io.Reader, error) {
+			c := lookupCharset(label, charsets)
+			if c == nil {
+				return nil, fmt.Errorf("[yEnc] unknown charset %s: %w", label, ErrCharset)
+			}
+			var b bytes.Buffer
+			if _, err := b.ReadFrom(r); err != nil {
+				return nil, err
+			}
+			s, err := c.Decode(b.Bytes())
+			return strings.NewReader(s), err
+		}}
+		if s, err := d.DecodeHeader(name); err == nil {
+			name = s
+		}
+	}
+	return
+}
@@ -0,0 +1,87 @@
+package yenc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func decodeTestName(t *testing.T, name string, options ...DecodeOption) *Header {
+	d, err := Decode(strings.NewReader("=ybegin line=128 size=3 name="+name+"\r\nKLM\r\n=yend size=3\r\n"), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.ReadAll(d); err != nil {
+		t.Fatal(err)
+	}
+	return d.Header()
+}
+
+func TestDecodeNameCharset(t *testing.T) {
+	for _, c := range []struct {
+		raw, expect string
+	}{
+		{"plain.bin", "plain.bin"},
+		{"Café.txt", "Café.txt"},
+		{"Caf\xe9.txt", "Café.txt"},
+		{"\x93quoted\x94 \x80.txt", "“quoted” €.txt"},
+		{"undefined \x81.txt", "undefined \u0081.txt"},
+		{"=?UTF-8?B?Q2Fmw6kudHh0?=", "Café.txt"},
+		{"=?iso-8859-1?Q?Caf=E9?=.txt", "Café.txt"},
+		{"=?windows-1252?Q?=80uro?= rate.txt", "€uro rate.txt"},
+		{"=?unknown?Q?x?=.txt", "=?unknown?Q?x?=.txt"},
+	} {
+		h := decodeTestName(t, c.raw)
+		if h.Name != c.expect || h.RawName != c.raw {
+			t.Errorf("expect %#v to be decoded as %#v but got %#v (raw %#v)", c.raw, c.expect, h.Name, h.RawName)
+		}
+	}
+	if h := decodeTestName(t, "Caf\xe9.txt", DecodeWithCharsets()); h.Name != "Caf\xe9.txt" {
+		t.Errorf("expect the name to be left as is without charsets but got %#v", h.Name)
+	}
+	if h := decodeTestName(t, "\x81", DecodeWithCharsets(Windows1252)); h.Name != "\x81" {
+		t.Errorf("expect an undefined byte to fail decoding but got %#v", h.Name)
+	}
+}
+
+func TestEncodeNameCharset(t *testing.T) {
+	var b bytes.Buffer
+	e, err := Encode(&b, "Café €.txt", 3, EncodeWithNameCharset(Windows1252), EncodeWithLF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.Write([]byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b.Bytes(), []byte("name=Caf\xe9 \x80.txt\n")) || e.Header().RawName != "Caf\xe9 \x80.txt" {
+		t.Errorf("expect a windows-1252 name but got %#v", b.String())
+	}
+	d, err := Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Header().Name != "Café €.txt" {
+		t.Errorf("expect the name to be decoded back but got %#v", d.Header().Name)
+	}
+	if _, err = Encode(io.Discard, "Café €.txt", 3, EncodeWithNameCharset(Latin1)); !errors.Is(err, ErrCharset) {
+		t.Errorf("expect ErrCharset but got %v", err)
+	}
+	if _, err = Encode(io.Discard, "Caf\xe9.txt", 3, EncodeWithNameCharset(UTF8)); !errors.Is(err, ErrCharset) {
+		t.Errorf("expect ErrCharset for a name that is not valid UTF-8 but got %v", err)
+	}
+	// by default, or with a nil charset, a name that is not valid UTF-8 is written as is
+	for _, options := range [][]EncodeOption{nil, {EncodeWithNameCharset(nil)}} {
+		b.Reset()
+		if e, err = Encode(&b, "Caf\xe9.txt", 3, append(options, EncodeWithLF())...); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(b.Bytes(), []byte("name=Caf\xe9.txt\n")) || e.Header().RawName != "Caf\xe9.txt" {
+			t.Errorf("expect the raw name but got %#v", b.String())
+		}
+	}
+}
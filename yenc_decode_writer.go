@@ -0,0 +1,299 @@
+package yenc
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"gopkg.in/option.v0"
+)
+
+// Push counterpart of Decoder: the encoded data stream is fed through Write in chunks split anywhere, even within a
+// keyword line or an escape sequence, and the decoded data is written to an io.Writer as it comes. Data after the =yend
+// line is ignored. Takes the same options as Decode, except the buffer ones.
+type DecodeWriter struct {
+	out  io.Writer
+	h    Header
+	t    *Trailer
+	hash hash.Hash32
+	s    int // state
+
+	line    []byte // Keyword line, or the start of a line that may be one
+	keyword bool   // line is being collected
+	skip    bool   // Skipping a line of prefix data
+	ready   bool   // Header and =ypart line parsed, data may follow
+	hasPart bool
+	buf     []byte // Decoded data not written to out yet
+	err     error  // First error, returned by all later calls
+
+	hashes          []hash.Hash
+	charsets        []Charset
+	allowPrefixData bool
+	sizeDecoded     uint64
+	headerFunc      func(*Header) error
+}
+
+func NewDecodeWriter(out io.Writer, options ...DecodeOption) *DecodeWriter {
+	d := option.New(options, DecodeWithCharsets(DefaultCharsets...))
+	return &DecodeWriter{
+		out:             out,
+		hash:            crc32.NewIEEE(),
+		hashes:          d.hashes,
+		charsets:        d.charsets,
+		allowPrefixData: d.allowPrefixData,
+		headerFunc:      d.headerFunc,
+	}
+}
+
+// Decode a chunk of the encoded data stream. Errors are sticky: once one is returned, all later calls return it.
+func (w *DecodeWriter) Write(p []byte) (n int, err error) {
+	if w.err != nil {
+		err = w.err
+		return
+	}
+	for ; n < len(p); n++ {
+		if w.feed(p[n]); w.err != nil {
+			break
+		}
+	}
+	if w.err == nil {
+		w.flush()
+	}
+	err = w.err
+	return
+}
+
+// Finish decoding: parse a =yend line without EOL, and check the =ybegin and =yend lines were seen. The trailer has
+// been verified as Decoder does once Close returns nil. Does not close the underlying writer.
+func (w *DecodeWriter) Close() (err error) {
+	if w.err != nil {
+		return w.err
+	}
+	if w.keyword {
+		if w.isKeyword(w.line, true) {
+			w.endLine()
+		} else {
+			w.replay()
+		}
+	}
+	if w.err == nil {
+		w.flush()
+	}
+	if w.err == nil && w.s == sStart {
+		w.err = fmt.Errorf("[yEnc] no =ybegin line: %w", ErrInvalidFormat)
+	}
+	if w.err == nil && w.t == nil {
+		w.err = fmt.Errorf("[yEnc] no =yend line: %w", ErrInvalidFormat)
+	}
+	return w.err
+}
+
+// Feed one byte of the encoded data stream.
+func (w *DecodeWriter) feed(c byte) {
+	if w.t != nil {
+		return
+	}
+	if w.skip {
+		w.skip = !matchCRLF(c)
+		return
+	}
+	if w.keyword {
+		if matchCRLF(c) {
+			w.endLine()
+			return
+		}
+		if len(w.line) >= BufferLimit {
+			w.err = fmt.Errorf("[yEnc] keyword line too long: %w", ErrInvalidFormat)
+			return
+		}
+		w.line = append(w.line, c)
+		if !w.isKeyword(w.line, false) {
+			w.replay()
+		}
+		return
+	}
+	if w.s == sStart || w.s == sBegin {
+		if matchCRLF(c) {
+			return
+		}
+		if c == '=' {
+			w.line, w.keyword = append(w.line[:0], c), true
+			return
+		}
+		if w.s == sStart {
+			w.prefixData()
+			return
+		}
+	}
+	w.data(c)
+}
+
+// Decode one byte of a data line.
+func (w *DecodeWriter) data(c byte) {
+	if !w.ready && !w.headerDone() {
+		return
+	}
+	if w.s == sEscape && matchCRLF(c) {
+		// an escape at the end of the line is dropped, as Decoder.Read does
+		w.s = sBegin
+	} else if w.s == sEscape {
+		w.buf = append(w.buf, c-64-42)
+		w.s = sData
+	} else if c == '=' {
+		w.s = sEscape
+	} else if matchCRLF(c) {
+		w.s = sBegin
+	} else {
+		w.buf = append(w.buf, c-42)
+		w.s = sData
+	}
+}
+
+// The collected start of a line is no keyword line: skip it as prefix data, or decode it as data.
+func (w *DecodeWriter) replay() {
+	w.keyword = false
+	if w.s == sStart {
+		w.prefixData()
+		return
+	}
+	w.s = sData
+	for _, c := range w.line {
+		if w.data(c); w.err != nil {
+			return
+		}
+	}
+}
+
+func (w *DecodeWriter) prefixData() {
+	if !w.allowPrefixData {
+		w.err = ErrRejectPrefixData
+		return
+	}
+	w.keyword, w.skip = false, true
+}
+
+// Whether line is, or with complete false may become, a keyword line expected in the current state.
+func (w *DecodeWriter) isKeyword(line []byte, complete bool) bool {
+	match := func(keyword []byte) bool {
+		if len(line) < len(keyword) {
+			return !complete && bytes.HasPrefix(keyword, line)
+		}
+		return bytes.HasPrefix(line, keyword)
+	}
+	if w.s == sStart {
+		return match(ybegin)
+	}
+	return match(yend) || !w.ready && !w.hasPart && match(ypart)
+}
+
+// A keyword line ended.
+func (w *DecodeWriter) endLine() {
+	line := string(w.line)
+	w.keyword = false
+	if !w.isKeyword(w.line, true) {
+		if w.s == sStart {
+			w.prefixData()
+			w.skip = false
+		} else {
+			w.replay()
+			w.data('\n')
+		}
+		return
+	}
+	switch {
+	case strings.HasPrefix(line, string(ybegin)):
+		w.parseBegin(line[len(ybegin):])
+	case strings.HasPrefix(line, string(ypart)):
+		w.parsePart(line[len(ypart):])
+	default:
+		if w.ready || w.headerDone() {
+			w.flush()
+		}
+		if w.err == nil {
+			w.parseEnd(line[len(yend):])
+		}
+	}
+}
+
+func (w *DecodeWriter) parseBegin(args string) {
+	w.err = w.h.parseBegin(args, w.charsets)
+	w.s = sBegin
+}
+
+func (w *DecodeWriter) parsePart(args string) {
+	if w.err = w.h.parsePart(args); w.err != nil {
+		return
+	}
+	w.hasPart = true
+	w.headerDone()
+}
+
+func (w *DecodeWriter) parseEnd(args string) {
+	w.t, w.err = parseTrailer(&w.h, args, w.sizeDecoded, w.hash.Sum32())
+}
+
+// The header is complete: check it and call the header func. Returns false on error.
+func (w *DecodeWriter) headerDone() bool {
+	w.ready = true
+	if (w.h.Part > 1 || w.h.Total > 1) && !w.hasPart {
+		w.err = fmt.Errorf("[yEnc] missing =ypart line for multipart: %w", ErrInvalidFormat)
+		return false
+	}
+	if w.headerFunc != nil {
+		w.err = w.headerFunc(&w.h)
+	}
+	return w.err == nil
+}
+
+// Write the decoded data to out.
+func (w *DecodeWriter) flush() {
+	if len(w.buf) == 0 {
+		return
+	}
+	w.sizeDecoded += uint64(len(w.buf))
+	w.hash.Write(w.buf)
+	for _, h := range w.hashes {
+		h.Write(w.buf)
+	}
+	_, w.err = w.out.Write(w.buf)
+	w.buf = w.buf[:0]
+}
+
+// Header of the =ybegin and =ypart lines. Only valid once the header func is called, or Write has consumed them.
+func (w *DecodeWriter) Header() *Header {
+	return &w.h
+}
+
+// Trailer information of the =yend line. Returns nil until the =yend line is consumed.
+func (w *DecodeWriter) Trailer() *Trailer {
+	return w.t
+}
+
+// CRC32 checksum of the preceeding data decoded so far.
+func (w *DecodeWriter) CRC32() uint32 {
+	return w.hash.Sum32()
+}
+
+// Split the key=value arguments of a keyword line, as Decoder.readArgument does, and call f with each.
+func eachArgument(args string, readToEOL func(key string) bool, f func(key, value string) error) (err error) {
+	for args = strings.TrimLeft(args, " "); args != ""; args = strings.TrimLeft(args, " ") {
+		key, rest, found := strings.Cut(args, "=")
+		if !found {
+			err = fmt.Errorf("[yEnc] invalid keyword argument %#v: %w", args, ErrInvalidFormat)
+			return
+		}
+		value := rest
+		if readToEOL == nil || !readToEOL(key) {
+			value, args, _ = strings.Cut(rest, " ")
+		} else {
+			args = ""
+		}
+		if err = f(key, value); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,169 @@
+package yenc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// Feed each part of a fixture through a DecodeWriter in chunks of at most max bytes, max 1 being byte by byte.
+func decodeWriterParts(t *testing.T, prefix string, parts int, max int, rnd *rand.Rand) []byte {
+	var out bytes.Buffer
+	for i := 1; i <= parts; i++ {
+		b, err := os.ReadFile(fmt.Sprintf("fixture/%s-%03d.ntx", prefix, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewDecodeWriter(&out)
+		for len(b) > 0 {
+			n := 1 + rnd.Intn(max)
+			if n > len(b) {
+				n = len(b)
+			}
+			if _, err = w.Write(b[:n]); err != nil {
+				t.Fatalf("%s part %d: %v", prefix, i, err)
+			}
+			b = b[n:]
+		}
+		if err = w.Close(); err != nil {
+			t.Fatalf("%s part %d: %v", prefix, i, err)
+		}
+		if w.Trailer() == nil || w.Trailer().Size != w.Header().End-w.Header().Begin {
+			t.Errorf("%s part %d: trailer %#v", prefix, i, w.Trailer())
+		}
+	}
+	return out.Bytes()
+}
+
+func TestDecodeWriter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, prefix := range []string{"yenc32", "ngPost"} {
+		raw, err := os.ReadFile(fmt.Sprintf("fixture/%s-raw.bin", prefix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, max := range []int{1, 2, 7, 100, 1 << 20} {
+			if out := decodeWriterParts(t, prefix, 10, max, rnd); !bytes.Equal(out, raw) {
+				t.Errorf("%s in chunks of up to %d: decode output mismatch", prefix, max)
+			}
+		}
+	}
+}
+
+func TestDecodeWriterHeaderFunc(t *testing.T) {
+	b, err := os.ReadFile("fixture/ngPost-002.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	var h Header
+	calls := 0
+	w := NewDecodeWriter(&out, DecodeWithHeaderFunc(func(header *Header) error {
+		h = *header
+		calls++
+		if out.Len() != 0 {
+			t.Error("header func called after data was written")
+		}
+		return nil
+	}))
+	for i := range b {
+		if _, err = w.Write(b[i : i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("header func called %d times", calls)
+	}
+	if h.Part != 2 || h.Begin == 0 || h.End-h.Begin != uint64(out.Len()) {
+		t.Errorf("header %#v, decoded %d bytes", h, out.Len())
+	}
+
+	stop := errors.New("stop")
+	w = NewDecodeWriter(&out, DecodeWithHeaderFunc(func(*Header) error { return stop }))
+	if _, err = w.Write(b); err != stop {
+		t.Errorf("expect header func error, got %v", err)
+	}
+	if err = w.Close(); err != stop {
+		t.Errorf("expect sticky header func error on Close, got %v", err)
+	}
+}
+
+func TestDecodeWriterCorrupt(t *testing.T) {
+	b, err := os.ReadFile("fixture/ngPost-001.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := bytes.Index(b, []byte(" pcrc32="))
+	if i < 0 {
+		t.Fatal("no pcrc32 in fixture")
+	}
+	b = append([]byte(nil), b...)
+	i += len(" pcrc32=")
+	if b[i] == '0' {
+		b[i] = '1'
+	} else {
+		b[i] = '0'
+	}
+	w := NewDecodeWriter(&bytes.Buffer{})
+	_, err = w.Write(b)
+	if err == nil {
+		err = w.Close()
+	}
+	if !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expect ErrInvalidFormat for a wrong pcrc32, got %v", err)
+	}
+
+	// no =yend line
+	j := bytes.Index(b, []byte("=yend "))
+	w = NewDecodeWriter(&bytes.Buffer{})
+	if _, err = w.Write(b[:j]); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expect ErrInvalidFormat without =yend line, got %v", err)
+	}
+
+	// prefix data
+	w = NewDecodeWriter(&bytes.Buffer{})
+	if _, err = w.Write([]byte("hello\r\n")); err != ErrRejectPrefixData {
+		t.Errorf("expect ErrRejectPrefixData, got %v", err)
+	}
+}
+
+// An escape at the end of a line is dropped as Decoder does, not decoded with the line ending.
+func TestDecodeWriterEscapeAtEOL(t *testing.T) {
+	for _, article := range []string{
+		"=ybegin line=128 size=6 name=a\r\nKLM=\r\nKLM\r\n=yend size=6\r\n",
+		"=ybegin line=128 size=4 name=a\r\nKLM=\r=M\r\n=yend size=4\r\n",
+		"=ybegin line=128 size=3 name=a\r\nKLM=\n=yend size=3\r\n",
+	} {
+		d, err := Decode(bytes.NewReader([]byte(article)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect, err := io.ReadAll(d)
+		if err != nil {
+			t.Fatalf("%q: %v", article, err)
+		}
+		for _, n := range []int{1, len(article)} {
+			var out bytes.Buffer
+			w := NewDecodeWriter(&out)
+			for b := []byte(article); len(b) > 0 && err == nil; b = b[n:] {
+				_, err = w.Write(b[:n])
+			}
+			if err == nil {
+				err = w.Close()
+			}
+			if err != nil || !bytes.Equal(out.Bytes(), expect) {
+				t.Errorf("%q in chunks of %d: expect %q but got %q: %v", article, n, expect, out.Bytes(), err)
+			}
+		}
+	}
+}
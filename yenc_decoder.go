@@ -1,6 +1,8 @@
 package yenc
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash"
 	"hash/crc32"
@@ -14,31 +16,150 @@ import (
 
 type Decoder struct {
 	h    Header
+	t    *Trailer
 	r    io.Reader
 	b    *ringbuffer.Buffer
 	hash hash.Hash32
 	s    int // state
 
+	hashes   []hash.Hash // Also fed the decoded data
+	charsets []Charset   // Fallbacks for names that are not valid UTF-8
+
 	// If =ybegin keywork is not at the beginning of the data stream, returns ErrRejectPrefixData
 	allowPrefixData bool
 	sizeDecoded     uint64
+	headerFunc      func(*Header) error
+	src             []byte // Data held in memory not decoded yet, if decoding with DecodeBytes
+	lineLen         int    // Bytes of src up to the end of the current line
 }
 
 func Decode(r io.Reader, options ...DecodeOption) (decoder *Decoder, err error) {
-	d := option.New(options)
+	d := newDecoder(options)
+	if err = d.Reset(r); err != nil {
+		return
+	}
+	decoder = d
+	return
+}
+
+// Decode an article held in memory. Its keyword lines and data are parsed straight from b rather than copied through a
+// buffer first, scanning for escapes and line breaks a word at a time, which is faster than Decode. b must not be
+// modified until the data is read. For a *bytes.Reader, decode the slice it was made of.
+func DecodeBytes(b []byte, options ...DecodeOption) (decoder *Decoder, err error) {
+	d := newDecoder(options)
+	if err = d.ResetBytes(b); err != nil {
+		return
+	}
+	decoder = d
+	return
+}
+
+func newDecoder(options []DecodeOption) (d *Decoder) {
+	d = option.New(options, DecodeWithCharsets(DefaultCharsets...))
+	d.hash = crc32.NewIEEE()
+	return
+}
+
+// Start decoding another data stream from r, reusing the buffer and hasher of d and keeping its options, as Decode does
+// with a new Decoder. Hashes given by DecodeWithHash are not reset. The state of d is reset even if reading the header
+// fails, so it can be reused in any case. For servers decoding many articles, decoders can be kept in a sync.Pool: take
+// one with Get and Reset it, or call Decode if the pool is empty, then Put it back once its data is read.
+func (d *Decoder) Reset(r io.Reader) (err error) {
+	d.h, d.t, d.r, d.s, d.sizeDecoded, d.src = Header{}, nil, r, sStart, 0, nil
 	if d.b == nil {
+		// allocated on first use, as decoding from memory does not need it
 		DecodeWithBufferSize(BufferLimit)(d)
 	}
-	d.r = r
-	d.hash = crc32.NewIEEE()
+	d.b.Reset()
+	d.hash.Reset()
 	if err = d.readHeader(); err != nil {
 		return
 	}
-	decoder = d
+	if d.headerFunc != nil {
+		err = d.headerFunc(&d.h)
+	}
+	return
+}
+
+// Reset d to decode an article held in memory, as DecodeBytes does. The buffer of d is not used.
+func (d *Decoder) ResetBytes(b []byte) (err error) {
+	d.h, d.t, d.r, d.s, d.sizeDecoded, d.src = Header{}, nil, nil, sStart, 0, nil
+	if d.b != nil {
+		d.b.Reset()
+	}
+	d.hash.Reset()
+	if d.src, err = d.readHeaderBytes(b); err != nil {
+		d.src = nil
+		return
+	}
+	d.lineLen = lineLength(d.src)
+	if d.headerFunc != nil {
+		err = d.headerFunc(&d.h)
+	}
+	return
+}
+
+// Parse the header of an article held in memory as readHeader does, returning the rest of b.
+func (d *Decoder) readHeaderBytes(b []byte) (rest []byte, err error) {
+	var line []byte
+	for !bytes.HasPrefix(b, ybegin) {
+		// there are data before the =ybegin keyword
+		if !d.allowPrefixData {
+			err = ErrRejectPrefixData
+			return
+		}
+		i := bytes.IndexAny(b, "\r\n")
+		if i < 0 {
+			err = io.EOF
+			return
+		}
+		b = b[i+1:]
+	}
+	line, b = cutLine(b[len(ybegin):])
+	if err = d.h.parseBegin(string(line), d.charsets); err != nil {
+		return
+	}
+	d.s = sBegin
+	for len(b) > 0 && matchCRLF(b[0]) {
+		b = b[1:]
+	}
+	hasPart := false
+	if bytes.HasPrefix(b, ypart) {
+		// multipart detected
+		line, b = cutLine(b[len(ypart):])
+		if err = d.h.parsePart(string(line)); err != nil {
+			return
+		}
+		hasPart = true
+	} else if bytes.HasPrefix(b, yend) {
+		// empty file detected, end now
+		line, b = cutLine(b[len(yend):])
+		if d.t, err = parseTrailer(&d.h, string(line), d.sizeDecoded, d.hash.Sum32()); err != nil {
+			return
+		}
+		b = b[len(b):]
+	}
+	if (d.h.Part > 1 || d.h.Total > 1) && !hasPart {
+		err = fmt.Errorf("[yEnc] missing =ypart line for multipart: %w", ErrInvalidFormat)
+		return
+	}
+	rest = b
 	return
 }
 
+// Split the line at the start of b from the rest, dropping the CR or LF ending it.
+func cutLine(b []byte) (line, rest []byte) {
+	i := bytes.IndexAny(b, "\r\n")
+	if i < 0 {
+		return b, b[len(b):]
+	}
+	return b[:i], b[i+1:]
+}
+
 func (d *Decoder) Read(b []byte) (n int, err error) {
+	if d.r == nil {
+		return d.readBytes(b)
+	}
 	var (
 		i               int
 		c               byte
@@ -84,15 +205,71 @@ func (d *Decoder) Read(b []byte) (n int, err error) {
 			d.s = sData
 		}
 	}
-	if n > 0 {
-		for i = 0; i < n; i++ {
-			b[i] -= 42
+	d.decoded(b[:n])
+	if hasEnd {
+		if err = d.consumeEnd(); err != nil {
+			return
 		}
-		d.sizeDecoded += uint64(n)
-		d.hash.Write(b[:n])
 	}
+	if n == 0 {
+		err = io.EOF
+	}
+	return
+}
+
+// Read of the data held in memory: copies the runs of data between escapes, found with bytes.IndexByte, straight from
+// the source slice. Line breaks are looked for once per line.
+func (d *Decoder) readBytes(b []byte) (n int, err error) {
+	var (
+		i      int
+		run    []byte
+		hasEnd bool
+	)
+	for n < len(b) && len(d.src) > 0 && !hasEnd {
+		switch d.s {
+		case sEscape:
+			if d.lineLen == 0 {
+				// an escape at the end of the line is dropped, as Read does
+				d.s = sBegin
+				continue
+			}
+			b[n] = d.src[0] - 64
+			d.src = d.src[1:]
+			n++
+			d.lineLen--
+			d.s = sData
+		case sBegin:
+			for i = 0; i < len(d.src) && matchCRLF(d.src[i]); i++ {
+			}
+			d.src = d.src[i:]
+			if hasEnd = bytes.HasPrefix(d.src, yend); !hasEnd {
+				d.s = sData
+				d.lineLen = lineLength(d.src)
+			}
+		default:
+			if run = d.src[:d.lineLen]; len(run) > len(b)-n {
+				run = run[:len(b)-n]
+			}
+			if i = bytes.IndexByte(run, '='); i >= 0 {
+				run = run[:i]
+			}
+			n += copy(b[n:], run)
+			d.src = d.src[len(run):]
+			// the run stopped at the end of the line, an escape, or the end of b
+			if d.lineLen -= len(run); d.lineLen == 0 {
+				d.s = sBegin
+			} else if d.src[0] == '=' {
+				d.src = d.src[1:]
+				d.lineLen--
+				d.s = sEscape
+			}
+		}
+	}
+	d.decoded(b[:n])
 	if hasEnd {
-		if err = d.consumeEnd(); err != nil {
+		line, _ := cutLine(d.src[len(yend):])
+		d.src = d.src[len(d.src):]
+		if d.t, err = parseTrailer(&d.h, string(line), d.sizeDecoded, d.hash.Sum32()); err != nil {
 			return
 		}
 	}
@@ -102,6 +279,46 @@ func (d *Decoder) Read(b []byte) (n int, err error) {
 	return
 }
 
+// Length of the line at the start of b, up to its CR or LF.
+func lineLength(b []byte) (n int) {
+	if n = bytes.IndexByte(b, '\n'); n < 0 {
+		n = len(b)
+	}
+	if i := bytes.IndexByte(b[:n], '\r'); i >= 0 {
+		n = i
+	}
+	return
+}
+
+// Finish decoding the bytes of b, copied from the data with escapes undone, and count them.
+func (d *Decoder) decoded(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	subtract42(b)
+	d.sizeDecoded += uint64(len(b))
+	d.hash.Write(b)
+	for _, h := range d.hashes {
+		h.Write(b)
+	}
+}
+
+// Subtract 42 from each byte of b, 8 bytes at a time: the high bit of each byte is set so that no borrow crosses into
+// the next byte, then restored.
+func subtract42(b []byte) {
+	const (
+		high = 0x8080808080808080
+		k    = 0x2a2a2a2a2a2a2a2a
+	)
+	for ; len(b) >= 8; b = b[8:] {
+		x := binary.LittleEndian.Uint64(b)
+		binary.LittleEndian.PutUint64(b, ((x|high)-k)^(^x&high))
+	}
+	for i := range b {
+		b[i] -= 42
+	}
+}
+
 func (d *Decoder) readMore() (err error) {
 	if !d.b.IsFull() {
 		if _, err = d.b.ReadFrom(d.r); err == io.EOF && !d.b.IsEmpty() {
@@ -141,53 +358,12 @@ func (d *Decoder) readHeader() (err error) {
 				if key, value, atEOL, err = d.readArgument(func(key string) bool { return key == "name" }); err != nil {
 					return
 				}
-				switch key {
-				case "line":
-					if d.h.Line, err = strconv.ParseUint(value, 10, 64); err != nil {
-						err = fmt.Errorf("[yEnc] invalid line value %#v: %w", value, ErrInvalidFormat)
-						return
-					}
-					// We should be able to handle arbitrary line size using ring buffer. Disable this check for now.
-
-					// Each escape uses 2 bytes, and each line includes the LF byte, so at max a line can be
-					// (d.h.Line * 2) + 1 bytes.
-
-					// lineMax := (d.h.Line * 2) + 1
-					// if lineMax > uint64(d.bufferSize) {
-					// 	err = fmt.Errorf("[yEnc] average line length is %d, expecting buffer requirement of %d bytes, but buffer has size %d: %w", d.h.Line, lineMax, d.bufferSize, ErrBufferTooSmall)
-					// 	return
-					// }
-				case "size":
-					if d.h.Size, err = strconv.ParseUint(value, 10, 64); err != nil {
-						err = fmt.Errorf("[yEnc] invalid size value %#v: %w", value, ErrInvalidFormat)
-						return
-					}
-					hasSize = true
-				case "part":
-					if d.h.Part, err = strconv.ParseUint(value, 10, 64); err != nil {
-						err = fmt.Errorf("[yEnc] invalid part value %#v: %w", value, ErrInvalidFormat)
-						return
-					}
-				case "total":
-					if d.h.Total, err = strconv.ParseUint(value, 10, 64); err != nil {
-						err = fmt.Errorf("[yEnc] invalid total value %#v: %w", value, ErrInvalidFormat)
-						return
-					}
-				case "name":
-					// (1.2): Leading and trailing spaces will be cut by decoders!
-					d.h.Name = strings.TrimSpace(value)
-					if d.h.Name == "" {
-						err = fmt.Errorf("[yEnc] empty name value: %w", ErrInvalidFormat)
-						return
-					}
+				if err = d.h.parseArgument(key, value, d.charsets); err != nil {
+					return
 				}
+				hasSize = hasSize || key == "size"
 			}
-			if d.h.Line == 0 {
-				err = fmt.Errorf("[yEnc] missing line value: %w", ErrInvalidFormat)
-				return
-			}
-			if !hasSize {
-				err = fmt.Errorf("[yEnc] missing size value: %w", ErrInvalidFormat)
+			if err = d.h.checkBegin(hasSize); err != nil {
 				return
 			}
 			d.s = sBegin
@@ -232,116 +408,225 @@ func (d *Decoder) consumePart() (err error) {
 		if key, value, atEOL, err = d.readArgument(nil); err != nil {
 			return
 		}
-		if key == "begin" {
-			if d.h.Begin, err = strconv.ParseUint(value, 10, 64); err != nil {
-				err = fmt.Errorf("[yEnc] invalid part begin value %#v: %w", value, ErrInvalidFormat)
-				return
-			}
-			if d.h.Begin < 1 {
-				err = fmt.Errorf("[yEnc] part begin raw value should start from 1 but got %d: %w", d.h.Begin, ErrInvalidFormat)
-				return
-			}
-		} else if key == "end" {
-			if d.h.End, err = strconv.ParseUint(value, 10, 64); err != nil {
-				err = fmt.Errorf("[yEnc] invalid part end value %#v: %w", value, ErrInvalidFormat)
-				return
-			}
+		if err = d.h.parsePartArgument(key, value); err != nil {
+			return
 		}
 	}
-	if d.h.Begin == 0 {
-		err = fmt.Errorf("[yEnc] no part begin value: %w", ErrInvalidFormat)
-		return
-	}
-	d.h.Begin-- // our contract is keep Begin a 0-based index
-	if d.h.End < d.h.Begin {
-		err = fmt.Errorf("[yEnc] part start %d end %d: %w", d.h.Begin, d.h.End, ErrInvalidFormat)
-		return
-	}
-	if d.h.End > d.h.Size {
-		err = fmt.Errorf("[yEnc] part end %d exceeds file size %d: %w", d.h.End, d.h.Size, ErrDataCorruption)
-		return
-	}
-	return
+	return d.h.checkPart()
 }
 
 // =yend keyword line is seen, now consume it.
 func (d *Decoder) consumeEnd() (err error) {
 	var (
 		crc32          uint32
-		u64, size      uint64
 		key, value     string
 		hasSize, atEOL bool
 	)
 	crc32 = d.hash.Sum32()
 	d.b.Consume(len(yend))
+	t := &Trailer{}
 	for !atEOL {
 		if key, value, atEOL, err = d.readArgument(nil); err != nil {
 			return
 		}
-		if key == "size" {
-			if u64, err = strconv.ParseUint(value, 10, 64); err != nil {
-				err = fmt.Errorf("[yEnc] invalid trailer size value %#v: %w", value, ErrInvalidFormat)
-				return
-			}
-			if d.h.Part > 0 {
-				size = d.h.End - d.h.Begin
-			} else {
-				size = d.h.Size
-			}
-			if u64 != size {
-				err = fmt.Errorf("[yEnc] header size %d != trailer size %d: %w", d.h.Size, u64, ErrDataCorruption)
-				return
-			}
-			if d.sizeDecoded != u64 {
-				err = fmt.Errorf("[yEnc] metadata has size %d but decoded data has size %d: %w", u64, d.sizeDecoded, ErrDataCorruption)
-				return
-			}
-			hasSize = true
-		} else if key == "part" {
-			if u64, err = strconv.ParseUint(value, 10, 64); err != nil {
-				err = fmt.Errorf("[yEnc] invalid trailer part value %#v: %w", value, ErrInvalidFormat)
-				return
-			}
-			if u64 != d.h.Part {
-				err = fmt.Errorf("[yEnc] header part %d != trailer part %d: %w", d.h.Part, u64, ErrDataCorruption)
-				return
-			}
-		} else if key == "total" {
-			if u64, err = strconv.ParseUint(value, 10, 64); err != nil {
-				err = fmt.Errorf("[yEnc] invalid trailer total value %#v: %w", value, ErrInvalidFormat)
-				return
-			}
-			if u64 != d.h.Total {
-				err = fmt.Errorf("[yEnc] header total %d != trailer total %d: %w", d.h.Total, u64, ErrDataCorruption)
-				return
-			}
-		} else if key == "pcrc32" {
-			if u64, err = strconv.ParseUint(value, 16, 32); err != nil {
-				err = fmt.Errorf("[yEnc] invalid trailer pcrc32 value %#v: %w", value, ErrInvalidFormat)
-				return
-			}
-			if uint32(u64) != crc32 {
-				err = fmt.Errorf("[yEnc] expect preceeding data to have CRC32 value %#08x but got %#x: %w", uint32(u64), value, ErrInvalidFormat)
-				return
-			}
-		} else if key == "crc32" {
-			if u64, err = strconv.ParseUint(value, 16, 32); err != nil {
-				err = fmt.Errorf("[yEnc] invalid trailer u64 value %#v: %w", value, ErrInvalidFormat)
-				return
-			}
-			if d.sizeDecoded == d.h.Size {
-				// this is the last part, validate the final CRC32 value
-				if uint32(u64) != crc32 {
-					err = fmt.Errorf("[yEnc] expect final file to have CRC32 value %#08x but got %#x: %w", uint32(u64), value, ErrInvalidFormat)
-					return
-				}
-			}
+		if err = t.parseArgument(&d.h, key, value, d.sizeDecoded, crc32); err != nil {
+			return
 		}
+		hasSize = hasSize || key == "size"
 	}
 	if !hasSize {
 		err = fmt.Errorf("[yEnc] no trailer size value: %w", ErrInvalidFormat)
 		return
 	}
+	d.t = t
+	return
+}
+
+// Parse a keyword argument of the =ybegin line.
+func (h *Header) parseArgument(key, value string, charsets []Charset) (err error) {
+	switch key {
+	case "line":
+		if h.Line, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = fmt.Errorf("[yEnc] invalid line value %#v: %w", value, ErrInvalidFormat)
+		}
+	case "size":
+		if h.Size, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = fmt.Errorf("[yEnc] invalid size value %#v: %w", value, ErrInvalidFormat)
+		}
+	case "part":
+		if h.Part, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = fmt.Errorf("[yEnc] invalid part value %#v: %w", value, ErrInvalidFormat)
+		}
+	case "total":
+		if h.Total, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = fmt.Errorf("[yEnc] invalid total value %#v: %w", value, ErrInvalidFormat)
+		}
+	case "name":
+		// (1.2): Leading and trailing spaces will be cut by decoders!
+		h.RawName = strings.TrimSpace(value)
+		if h.RawName == "" {
+			err = fmt.Errorf("[yEnc] empty name value: %w", ErrInvalidFormat)
+			return
+		}
+		h.Name = decodeName(h.RawName, charsets)
+	}
+	return
+}
+
+// Parse the arguments of a =ybegin line, following the keyword.
+func (h *Header) parseBegin(args string, charsets []Charset) (err error) {
+	var hasSize bool
+	if err = eachArgument(args, func(key string) bool { return key == "name" }, func(key, value string) error {
+		hasSize = hasSize || key == "size"
+		return h.parseArgument(key, value, charsets)
+	}); err != nil {
+		return
+	}
+	return h.checkBegin(hasSize)
+}
+
+// Check the required values of the =ybegin line.
+func (h *Header) checkBegin(hasSize bool) (err error) {
+	if h.Line == 0 {
+		err = fmt.Errorf("[yEnc] missing line value: %w", ErrInvalidFormat)
+		return
+	}
+	if !hasSize {
+		err = fmt.Errorf("[yEnc] missing size value: %w", ErrInvalidFormat)
+	}
+	return
+}
+
+// Parse a keyword argument of the =ypart line.
+func (h *Header) parsePartArgument(key, value string) (err error) {
+	if key == "begin" {
+		if h.Begin, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = fmt.Errorf("[yEnc] invalid part begin value %#v: %w", value, ErrInvalidFormat)
+			return
+		}
+		if h.Begin < 1 {
+			err = fmt.Errorf("[yEnc] part begin raw value should start from 1 but got %d: %w", h.Begin, ErrInvalidFormat)
+			return
+		}
+	} else if key == "end" {
+		if h.End, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = fmt.Errorf("[yEnc] invalid part end value %#v: %w", value, ErrInvalidFormat)
+			return
+		}
+	}
+	return
+}
+
+// Parse the arguments of a =ypart line, following the keyword.
+func (h *Header) parsePart(args string) (err error) {
+	if err = eachArgument(args, nil, h.parsePartArgument); err != nil {
+		return
+	}
+	return h.checkPart()
+}
+
+// Check the values of the =ypart line, and make Begin 0-based.
+func (h *Header) checkPart() (err error) {
+	if h.Begin == 0 {
+		err = fmt.Errorf("[yEnc] no part begin value: %w", ErrInvalidFormat)
+		return
+	}
+	h.Begin-- // our contract is keep Begin a 0-based index
+	if h.End < h.Begin {
+		err = fmt.Errorf("[yEnc] part start %d end %d: %w", h.Begin, h.End, ErrInvalidFormat)
+		return
+	}
+	if h.End > h.Size {
+		err = fmt.Errorf("[yEnc] part end %d exceeds file size %d: %w", h.End, h.Size, ErrDataCorruption)
+		return
+	}
+	return
+}
+
+// Parse the arguments of a =yend line, following the keyword, and check them against the header, and the size and
+// CRC32 of the data decoded.
+func parseTrailer(h *Header, args string, sizeDecoded uint64, crc32 uint32) (t *Trailer, err error) {
+	var hasSize bool
+	t = &Trailer{}
+	if err = eachArgument(args, nil, func(key, value string) error {
+		hasSize = hasSize || key == "size"
+		return t.parseArgument(h, key, value, sizeDecoded, crc32)
+	}); err == nil && !hasSize {
+		err = fmt.Errorf("[yEnc] no trailer size value: %w", ErrInvalidFormat)
+	}
+	if err != nil {
+		t = nil
+	}
+	return
+}
+
+// Parse a keyword argument of the =yend line and check it against the header, and the size and CRC32 of the data
+// decoded.
+func (t *Trailer) parseArgument(h *Header, key, value string, sizeDecoded uint64, crc32 uint32) (err error) {
+	var u64, size uint64
+	if key == "size" {
+		if u64, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = fmt.Errorf("[yEnc] invalid trailer size value %#v: %w", value, ErrInvalidFormat)
+			return
+		}
+		if h.Part > 0 {
+			size = h.End - h.Begin
+		} else {
+			size = h.Size
+		}
+		if u64 != size {
+			err = fmt.Errorf("[yEnc] header size %d != trailer size %d: %w", h.Size, u64, ErrDataCorruption)
+			return
+		}
+		if sizeDecoded != u64 {
+			err = fmt.Errorf("[yEnc] metadata has size %d but decoded data has size %d: %w", u64, sizeDecoded, ErrDataCorruption)
+			return
+		}
+		t.Size = u64
+	} else if key == "part" {
+		if u64, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = fmt.Errorf("[yEnc] invalid trailer part value %#v: %w", value, ErrInvalidFormat)
+			return
+		}
+		if u64 != h.Part {
+			err = fmt.Errorf("[yEnc] header part %d != trailer part %d: %w", h.Part, u64, ErrDataCorruption)
+			return
+		}
+		t.Part = u64
+	} else if key == "total" {
+		if u64, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = fmt.Errorf("[yEnc] invalid trailer total value %#v: %w", value, ErrInvalidFormat)
+			return
+		}
+		if u64 != h.Total {
+			err = fmt.Errorf("[yEnc] header total %d != trailer total %d: %w", h.Total, u64, ErrDataCorruption)
+			return
+		}
+		t.Total = u64
+	} else if key == "pcrc32" {
+		if u64, err = strconv.ParseUint(value, 16, 32); err != nil {
+			err = fmt.Errorf("[yEnc] invalid trailer pcrc32 value %#v: %w", value, ErrInvalidFormat)
+			return
+		}
+		if uint32(u64) != crc32 {
+			err = fmt.Errorf("[yEnc] expect preceeding data to have CRC32 value %#08x but got %#x: %w", uint32(u64), value, ErrInvalidFormat)
+			return
+		}
+		t.PCRC32, t.HasPCRC32 = uint32(u64), true
+	} else if key == "crc32" {
+		if u64, err = strconv.ParseUint(value, 16, 32); err != nil {
+			err = fmt.Errorf("[yEnc] invalid trailer u64 value %#v: %w", value, ErrInvalidFormat)
+			return
+		}
+		if sizeDecoded == h.Size {
+			// this is the last part, validate the final CRC32 value
+			if uint32(u64) != crc32 {
+				err = fmt.Errorf("[yEnc] expect final file to have CRC32 value %#08x but got %#x: %w", uint32(u64), value, ErrInvalidFormat)
+				return
+			}
+		}
+		t.CRC32, t.HasCRC32 = uint32(u64), true
+	}
 	return
 }
 
@@ -369,11 +654,14 @@ func (d *Decoder) readArgument(readToEOL func(key string) bool) (key, value stri
 			return
 		}
 	}
-	value = string(token[:len(token)-1])
 	d.b.Consume(len(token))
-	if matchCRLF(token[len(token)-1]) || err == io.EOF {
-		atEOL = true
+	if err == io.EOF {
+		// last line of the stream without EOL, the value has no delimiter
+		value, atEOL, err = string(token), true, nil
+		return
 	}
+	value = string(token[:len(token)-1])
+	atEOL = matchCRLF(token[len(token)-1])
 	return
 }
 
@@ -386,8 +674,16 @@ func (d *Decoder) Header() *Header {
 	return &d.h
 }
 
+// Trailer information of the =yend line. Returns nil until the =yend line is consumed, i.e. until Read returns io.EOF.
+func (d *Decoder) Trailer() *Trailer {
+	return d.t
+}
+
 // Get the remaining bytes in the buffer consumed but not decoded.
 func (d *Decoder) Buffer() []byte {
+	if d.b == nil {
+		return nil
+	}
 	return d.b.Bytes()
 }
 
@@ -441,3 +737,27 @@ func DecodeWithBufferSize(size int) DecodeOption {
 		d.b = ringbuffer.New(ringbuffer.WithSize(size))
 	}
 }
+
+// Feed the decoded data to h as it is read, e.g. to compute an MD5 while decoding rather than by reading the output
+// again. Can be given more than once.
+func DecodeWithHash(h hash.Hash) DecodeOption {
+	return func(d *Decoder) {
+		d.hashes = append(d.hashes, h)
+	}
+}
+
+// Call f once the =ybegin and =ypart lines are parsed, before any data is decoded. An error from f aborts decoding and
+// is returned as is.
+func DecodeWithHeaderFunc(f func(*Header) error) DecodeOption {
+	return func(d *Decoder) {
+		d.headerFunc = f
+	}
+}
+
+// Decode names that are not valid UTF-8 with the first of charsets that accepts them, instead of DefaultCharsets. With
+// no charset, such names are left as is. Header.RawName keeps the name as written in any case.
+func DecodeWithCharsets(charsets ...Charset) DecodeOption {
+	return func(d *Decoder) {
+		d.charsets = charsets
+	}
+}
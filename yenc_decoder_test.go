@@ -5,7 +5,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -214,6 +217,10 @@ func TestDecodeNyuu(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	// the =yend line ends the file without EOL
+	if d.Trailer() == nil || !d.Trailer().HasCRC32 || d.Trailer().CRC32 != 0xbda9fbc2 {
+		t.Errorf("unexpected trailer %+v", d.Trailer())
+	}
 	f, err = os.Open("fixture/260731a73db67e8095a5eaf0b64b9d3db0117cdb-raw.bin")
 	if err != nil {
 		t.Fatal(err)
@@ -226,3 +233,213 @@ func TestDecodeNyuu(t *testing.T) {
 		t.Error("Nyuu decode output mismatch!")
 	}
 }
+
+func TestDecoderReset(t *testing.T) {
+	var b bytes.Buffer
+	var d *Decoder
+	for i := 1; i <= 10; i++ {
+		f, err := os.Open(fmt.Sprintf("fixture/ngPost-%03d.ntx", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d == nil {
+			d, err = Decode(f)
+		} else {
+			err = d.Reset(f)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Header().Part != uint64(i) {
+			t.Errorf("expect part %d but got %d", i, d.Header().Part)
+		}
+		if _, err = io.Copy(&b, d); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if d.Trailer() == nil {
+			t.Fatalf("no trailer for part %d", i)
+		}
+
+		// a failed Reset leaves d reusable
+		if err = d.Reset(bytes.NewReader([]byte("=ybegin size=1\n"))); err == nil {
+			t.Error("expect error for a header without line value")
+		}
+	}
+	f, err := os.Open("fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	identical, err := Diff(f, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !identical {
+		t.Error("ngPost decode output mismatch after Reset!")
+	}
+}
+
+// Decode with DecodeBytes and with Decode, reading into slices of every size up to max, and compare.
+func TestDecodeBytes(t *testing.T) {
+	files, err := filepath.Glob("fixture/*.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	articles := map[string][]byte{
+		// an escape at the end of a line, and a dangling one before the line break
+		"escapes": []byte("=ybegin line=4 size=5 name=a\r\nKL=}\r\nM=\r\nN\r\n=yend size=5\r\n"),
+	}
+	for _, file := range files {
+		if articles[file], err = os.ReadFile(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for file, article := range articles {
+		d, err := Decode(bytes.NewReader(article))
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		expect, err := io.ReadAll(d)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		for size := 1; size <= 300; size += 7 {
+			fast, err := DecodeBytes(article)
+			if err != nil {
+				t.Fatalf("%s: %v", file, err)
+			}
+			var got []byte
+			b := make([]byte, size)
+			for {
+				n, err := fast.Read(b)
+				got = append(got, b[:n]...)
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("%s: %v", file, err)
+				}
+			}
+			if !bytes.Equal(got, expect) {
+				t.Fatalf("%s: DecodeBytes output mismatch reading %d bytes at a time", file, size)
+			}
+			if fast.Trailer() == nil || *fast.Trailer() != *d.Trailer() || fast.CRC32() != d.CRC32() {
+				t.Fatalf("%s: trailer %#v, expect %#v", file, fast.Trailer(), d.Trailer())
+			}
+			if *fast.Header() != *d.Header() || fast.b != nil {
+				t.Fatalf("%s: header %#v, expect %#v, without a buffer", file, fast.Header(), d.Header())
+			}
+		}
+	}
+	// keyword lines parsed from the slice as from the buffer
+	for _, c := range []struct {
+		article string
+		options []DecodeOption
+	}{
+		{"From: poster\r\n\r\n=ybegin line=128 size=3 name=a b\r\nKLM\r\n=yend size=3", []DecodeOption{DecodeWithPrefixData()}},
+		{"=ybegin line=128 size=0 name=empty\r\n=yend size=0 crc32=00000000\r\n", nil},
+		{"From: poster\r\n=ybegin line=128 size=3 name=a\r\nKLM\r\n=yend size=3\r\n", nil},
+		{"=ybegin line=128 size=6 part=1 total=2 name=a\r\nKLM\r\n=yend size=3\r\n", nil},
+		{"=ybegin line=128 size=3 part=1 name=a\r\n=ypart begin=0 end=3\r\nKLM\r\n=yend size=3\r\n", nil},
+		{"=ybegin line=128 name=a\r\nKLM\r\n=yend size=3\r\n", nil},
+		{"=ybegin line=128 size=3 name=a\r\nKLM\r\n=yend size=2\r\n", nil},
+	} {
+		d, err := Decode(strings.NewReader(c.article), c.options...)
+		var expect []byte
+		if err == nil {
+			expect, err = io.ReadAll(d)
+		}
+		fast, ferr := DecodeBytes([]byte(c.article), c.options...)
+		var got []byte
+		if ferr == nil {
+			got, ferr = io.ReadAll(fast)
+		}
+		if (err == nil) != (ferr == nil) || !bytes.Equal(got, expect) {
+			t.Errorf("%q: DecodeBytes returned %q, %v but Decode %q, %v", c.article, got, ferr, expect, err)
+		} else if err == nil && (*fast.Header() != *d.Header() || *fast.Trailer() != *d.Trailer()) {
+			t.Errorf("%q: DecodeBytes header %#v and trailer %#v, expect %#v and %#v", c.article, fast.Header(), fast.Trailer(), d.Header(), d.Trailer())
+		}
+	}
+}
+
+func TestSubtract42(t *testing.T) {
+	b := make([]byte, 256+5)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	subtract42(b)
+	for i, c := range b {
+		if c != byte(i)-42 {
+			t.Fatalf("byte %d: expect %d but got %d", i, byte(i)-42, c)
+		}
+	}
+}
+
+// Articles to benchmark decoding with: the fixture corpus, whose parts are small enough for the header to weigh, and
+// a single 1 MiB article of random data.
+func benchmarkArticles(b *testing.B) map[string][][]byte {
+	files, err := filepath.Glob("fixture/*.ntx")
+	if err != nil {
+		b.Fatal(err)
+	}
+	articles := map[string][][]byte{}
+	for _, file := range files {
+		article, err := os.ReadFile(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		articles["fixtures"] = append(articles["fixtures"], article)
+	}
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	var article bytes.Buffer
+	e, err := Encode(&article, "random.bin", uint64(len(data)), EncodeWithEOL("\r\n"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err = e.Write(data); err != nil {
+		b.Fatal(err)
+	}
+	if err = e.Close(); err != nil {
+		b.Fatal(err)
+	}
+	articles["1MiB"] = [][]byte{article.Bytes()}
+	return articles
+}
+
+func benchmarkDecode(b *testing.B, decode func(article []byte) (*Decoder, error)) {
+	corpus := benchmarkArticles(b)
+	for _, name := range []string{"fixtures", "1MiB"} {
+		articles := corpus[name]
+		b.Run(name, func(b *testing.B) {
+			var size int64
+			for _, article := range articles {
+				size += int64(len(article))
+			}
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				for _, article := range articles {
+					d, err := decode(article)
+					if err != nil {
+						b.Fatal(err)
+					}
+					if _, err = io.Copy(io.Discard, d); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	benchmarkDecode(b, func(article []byte) (*Decoder, error) {
+		return Decode(bytes.NewReader(article))
+	})
+}
+
+func BenchmarkDecodeBytes(b *testing.B) {
+	benchmarkDecode(b, func(article []byte) (*Decoder, error) {
+		return DecodeBytes(article)
+	})
+}
@@ -16,46 +16,108 @@ type Encoder struct {
 	sizeEncoded              int
 	partSize                 int
 	hash                     hash.Hash32
+	buf                      []byte
 	eol                      string
 	criticalChars            []byte
+	nameCharset              Charset
 	useTrailerPart           bool
 	useTrailerTotal          bool
 	useSinglePartAsMultiPart bool
 	usePcrc32ForLastPart     bool
 	useCrc32ForLastPart      bool
+	trailer                  *Trailer // Keywords and crc32 value of the trailer, if copied from another article
 }
 
 func Encode(w io.Writer, fileName string, fileSize uint64, options ...EncodeOption) (e *Encoder, err error) {
-	e = option.New(options,
+	e = newEncoder(w, fileName, fileSize, options)
+	err = e.writeHeader()
+	return
+}
+
+// Start encoding another file to w, reusing the write buffer and hasher of e, as Encode does with a new Encoder. The
+// options of the previous file are not kept. A zero Encoder can be Reset too, so encoders can be kept in a sync.Pool
+// whose New returns new(Encoder): take one with Get, Reset it, and Put it back once closed.
+func (e *Encoder) Reset(w io.Writer, fileName string, fileSize uint64, options ...EncodeOption) (err error) {
+	hash, buf := e.hash, e.buf
+	*e = Encoder{buf: buf[:0]}
+	for _, o := range append(encodeDefaults(), options...) {
+		o(e)
+	}
+	if e.hash = hash; e.hash == nil {
+		e.hash = crc32.NewIEEE()
+	} else {
+		e.hash.Reset()
+	}
+	e.init(w, fileName, fileSize)
+	err = e.writeHeader()
+	return
+}
+
+func newEncoder(w io.Writer, fileName string, fileSize uint64, options []EncodeOption) (e *Encoder) {
+	e = option.New(options, encodeDefaults()...)
+	e.hash = crc32.NewIEEE()
+	e.init(w, fileName, fileSize)
+	return
+}
+
+func encodeDefaults() []EncodeOption {
+	return []EncodeOption{
 		EncodeWithLineMax(LineLimit),
-		EncodeWithCriticalChars(DefaultCriticalChars))
+		EncodeWithCriticalChars(DefaultCriticalChars),
+		EncodeWithNameCharset(nil),
+	}
+}
+
+// Set the header values given by the arguments and options.
+func (e *Encoder) init(w io.Writer, fileName string, fileSize uint64) {
 	e.w = w
 	e.h.Name = fileName
 	e.h.Size = fileSize
-	e.hash = crc32.NewIEEE()
 	e.partSize = int(fileSize)
+	if e.h.Part > 0 && e.h.End == 0 {
+		// the part runs to the end of the file
+		e.h.End = fileSize
+	}
 	if e.h.End > 0 {
 		e.partSize = int(e.h.End - e.h.Begin)
 	}
 	if e.h.Total == 0 && e.useSinglePartAsMultiPart {
 		e.h.Part = 1
 		e.h.Total = 1
+		e.h.End = fileSize
 	}
-	err = e.writeHeader()
 	return
 }
 
 func (e *Encoder) writeHeader() (err error) {
-	if e.h.Part > 0 {
+	if e.h.Line == 0 {
+		err = fmt.Errorf("[yEnc] line length %d: %w", e.h.Line, ErrInvalidLineLength)
+		return
+	}
+	name := []byte(e.h.Name)
+	if e.nameCharset != nil {
+		if name, err = e.nameCharset.Encode(e.h.Name); err != nil {
+			err = fmt.Errorf("[yEnc] failed to encode name: %w", err)
+			return
+		}
+	}
+	e.h.RawName = string(name)
+	if e.h.Part > 0 && e.h.Total == 0 {
+		_, err = fmt.Fprintf(e.w,
+			"=ybegin part=%d line=%d size=%d name=%s%s"+
+				"=ypart begin=%d end=%d%s",
+			e.h.Part, e.h.Line, e.h.Size, name, e.eol,
+			e.h.Begin+1, e.h.End, e.eol)
+	} else if e.h.Part > 0 {
 		_, err = fmt.Fprintf(e.w,
 			"=ybegin part=%d total=%d line=%d size=%d name=%s%s"+
 				"=ypart begin=%d end=%d%s",
-			e.h.Part, e.h.Total, e.h.Line, e.h.Size, e.h.Name, e.eol,
+			e.h.Part, e.h.Total, e.h.Line, e.h.Size, name, e.eol,
 			e.h.Begin+1, e.h.End, e.eol)
 	} else {
 		_, err = fmt.Fprintf(e.w,
 			"=ybegin line=%d size=%d name=%s%s",
-			e.h.Line, e.h.Size, e.h.Name, e.eol)
+			e.h.Line, e.h.Size, name, e.eol)
 	}
 	if err != nil {
 		err = fmt.Errorf("[yEnc] failed to write header: %w", err)
@@ -71,6 +133,9 @@ func (e *Encoder) Write(b []byte) (n int, err error) {
 		atEOL, atEscape bool
 	)
 	_, _ = e.hash.Write(b)
+	// encode in a copy, as io.Writer must not modify b, so that the same data can be fed to other writers
+	e.buf = append(e.buf[:0], b...)
+	b = e.buf
 	for i, j = 0, 0; i < len(b) && j < len(b); j++ {
 		b[j] += 42
 		c = b[j]
@@ -97,6 +162,7 @@ func (e *Encoder) Write(b []byte) (n int, err error) {
 				if _, err = e.w.Write([]byte(e.eol)); err != nil {
 					return
 				}
+				e.lineOffset = 0
 			}
 			_, err = e.w.Write([]byte{'=', c})
 			i++
@@ -130,27 +196,38 @@ func (e *Encoder) Header() *Header {
 }
 
 func (e *Encoder) Close() (err error) {
-	crc32 := e.hash.Sum32()
+	var (
+		crc32        = e.hash.Sum32()
+		fileCRC32    = crc32
+		usePart      = e.useTrailerPart && e.h.Part > 0
+		useTotal     = e.useTrailerTotal && e.h.Part > 0
+		last         = e.h.Part == e.h.Total || e.h.Total == 0 // A part of unknown total may be the last
+		usePcrc32    = e.h.Part < e.h.Total || (e.usePcrc32ForLastPart && last)
+		useFileCRC32 = (e.h.Part == 0 && e.h.Total == 0) || (e.useCrc32ForLastPart && last)
+	)
+	if t := e.trailer; t != nil {
+		usePart, useTotal, usePcrc32, useFileCRC32, fileCRC32 = t.Part > 0, t.Total > 0, t.HasPCRC32, t.HasCRC32, t.CRC32
+	}
 	if _, err = fmt.Fprintf(e.w, "%s=yend size=%d", e.eol, e.sizeEncoded); err != nil {
 		return
 	}
-	if e.useTrailerPart && e.h.Part > 0 {
-		if _, err = fmt.Fprintf(e.w, " part=%d", e.h.Part); err == nil {
+	if usePart {
+		if _, err = fmt.Fprintf(e.w, " part=%d", e.h.Part); err != nil {
 			return
 		}
 	}
-	if e.useTrailerTotal && e.h.Part > 0 {
-		if _, err = fmt.Fprintf(e.w, " total=%d", e.h.Total); err == nil {
+	if useTotal {
+		if _, err = fmt.Fprintf(e.w, " total=%d", e.h.Total); err != nil {
 			return
 		}
 	}
-	if e.h.Part < e.h.Total || (e.usePcrc32ForLastPart && e.h.Part == e.h.Total) {
+	if usePcrc32 {
 		if _, err = fmt.Fprintf(e.w, " pcrc32=%08x", crc32); err != nil {
 			return
 		}
 	}
-	if (e.h.Part == 0 && e.h.Total == 0) || (e.useCrc32ForLastPart && e.h.Part == e.h.Total) {
-		if _, err = fmt.Fprintf(e.w, " crc32=%08x", crc32); err != nil {
+	if useFileCRC32 {
+		if _, err = fmt.Fprintf(e.w, " crc32=%08x", fileCRC32); err != nil {
 			return
 		}
 	}
@@ -184,6 +261,8 @@ var ExtendedCriticalChars = []byte{0, '\n', '\r', '=', '\t', '.'}
 
 type EncodeOption func(*Encoder)
 
+// Encode a part of a multi-part file, from begin up to end. A total of 0 leaves total= out, for posts whose number of
+// parts is unknown, and an end of 0 runs the part to the end of the file.
 func EncodeWithPart(part uint64, total uint64, begin uint64, end uint64) EncodeOption {
 	return func(e *Encoder) {
 		e.h.Part = part
@@ -193,6 +272,8 @@ func EncodeWithPart(part uint64, total uint64, begin uint64, end uint64) EncodeO
 	}
 }
 
+// Wrap the encoded lines at lineMax characters, LineLimit by default. Encoding fails with ErrInvalidLineLength if
+// lineMax is 0.
 func EncodeWithLineMax(lineMax uint64) EncodeOption {
 	return func(e *Encoder) {
 		e.h.Line = lineMax
@@ -232,7 +313,8 @@ func EncodeWithTrailerTotal() EncodeOption {
 	}
 }
 
-// Treat a single-part file as multi-part. That is, output part=1 and total=1 keywords, and include part CRC32.
+// Treat a single-part file as multi-part. That is, output part=1 and total=1 keywords and a =ypart line. As the part is
+// the last one, EncodeWithPartCrc32ForLastPart includes its part CRC32.
 func EncodeWithSinglePartAsMultiPart() EncodeOption {
 	return func(e *Encoder) {
 		e.useSinglePartAsMultiPart = true
@@ -254,3 +336,12 @@ func EncodeWithFileCRC32ForLastPart() EncodeOption {
 		e.useCrc32ForLastPart = true
 	}
 }
+
+// Write the name= value in the given charset, e.g. UTF8 to only accept valid UTF-8 names or a legacy charset for
+// decoders that expect one. Encoding fails with ErrCharset if the name has characters the charset cannot represent. By
+// default, or with a nil charset, the bytes of the name are written as they are.
+func EncodeWithNameCharset(c Charset) EncodeOption {
+	return func(e *Encoder) {
+		e.nameCharset = c
+	}
+}
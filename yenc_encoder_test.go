@@ -3,12 +3,41 @@ package yenc
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"testing"
 )
 
+// io.Writer must not modify the slice passed to Write.
+func TestEncoderKeepsInput(t *testing.T) {
+	var b bytes.Buffer
+	e, err := Encode(&b, "a", 4, EncodeWithLF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := []byte("ab=\x00")
+	if _, err = e.Write(in); err != nil {
+		t.Fatal(err)
+	}
+	if string(in) != "ab=\x00" {
+		t.Errorf("Write modified its input to %q", in)
+	}
+	if err = e.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncoderLineMaxZero(t *testing.T) {
+	if _, err := Encode(io.Discard, "a", 4, EncodeWithLineMax(0)); !errors.Is(err, ErrInvalidLineLength) {
+		t.Errorf("expect ErrInvalidLineLength but got %v", err)
+	}
+	if err := new(Encoder).Reset(io.Discard, "a", 4, EncodeWithLineMax(0)); !errors.Is(err, ErrInvalidLineLength) {
+		t.Errorf("expect ErrInvalidLineLength from Reset but got %v", err)
+	}
+}
+
 func TestEncoder(t *testing.T) {
 	in, err := os.Open("fixture/encode-raw.bin")
 	if err != nil {
@@ -70,3 +99,107 @@ func TestEncoder(t *testing.T) {
 		}
 	}
 }
+
+// The =ypart line ends at the file size, and the =yend line goes on after part= and total=.
+func TestEncoderSinglePartAsMultiPart(t *testing.T) {
+	var b bytes.Buffer
+	e, err := Encode(&b, "a", 3, EncodeWithLF(), EncodeWithSinglePartAsMultiPart(), EncodeWithTrailerPart(),
+		EncodeWithTrailerTotal(), EncodeWithPartCrc32ForLastPart())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Write([]byte("!\"#"))
+	if err = e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expect := "=ybegin part=1 total=1 line=128 size=3 name=a\n=ypart begin=1 end=3\nKLM\n=yend size=3 part=1 total=1 pcrc32=c31bc297\n"
+	if b.String() != expect {
+		t.Errorf("expect %q but got %q", expect, b.String())
+	}
+}
+
+// An escape that does not fit in the line starts the next one, which is then line= long again.
+func TestEncoderEscapeWrap(t *testing.T) {
+	var b bytes.Buffer
+	e, err := Encode(&b, "a", 8, EncodeWithLF(), EncodeWithLineMax(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.Write([]byte("\x00\x00\x00\x13\x00\x00\x00\x00")); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expect := "=ybegin line=4 size=8 name=a\n***\n=}**\n**\n=yend size=8 crc32=4262323b\n"
+	if b.String() != expect {
+		t.Errorf("expect %q but got %q", expect, b.String())
+	}
+}
+
+// A part without a total writes part= but not total= on the =ybegin line.
+func TestEncoderPartWithoutTotal(t *testing.T) {
+	var b bytes.Buffer
+	e, err := Encode(&b, "a", 6, EncodeWithLF(), EncodeWithPart(2, 0, 3, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.Write([]byte("!\"#")); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expect := "=ybegin part=2 line=128 size=6 name=a\n=ypart begin=4 end=6\nKLM\n=yend size=3\n"
+	if b.String() != expect {
+		t.Errorf("expect %q but got %q", expect, b.String())
+	}
+
+	// with no end, the part runs to the end of the file, and may be the last one
+	b.Reset()
+	e, err = Encode(&b, "a", 3, EncodeWithLF(), EncodeWithPart(1, 0, 0, 0), EncodeWithPartCrc32ForLastPart())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.Write([]byte("!\"#")); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expect = "=ybegin part=1 line=128 size=3 name=a\n=ypart begin=1 end=3\nKLM\n=yend size=3 pcrc32=c31bc297\n"
+	if b.String() != expect {
+		t.Errorf("expect %q but got %q", expect, b.String())
+	}
+}
+
+func TestEncoderReset(t *testing.T) {
+	var a, b bytes.Buffer
+	e := new(Encoder)
+	for _, w := range []*bytes.Buffer{&a, &b} {
+		if err := e.Reset(w, "a", 3, EncodeWithLF(), EncodeWithSinglePartAsMultiPart()); err != nil {
+			t.Fatal(err)
+		}
+		e.Write([]byte("!\"#"))
+		if err := e.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := "=ybegin part=1 total=1 line=128 size=3 name=a\n=ypart begin=1 end=3\nKLM\n=yend size=3\n"
+	if a.String() != expect || b.String() != expect {
+		t.Errorf("expect %q but got %q and %q", expect, a.String(), b.String())
+	}
+
+	// options of the previous file are not kept
+	b.Reset()
+	if err := e.Reset(&b, "b", 0, EncodeWithLF()); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expect = "=ybegin line=128 size=0 name=b\n\n=yend size=0 crc32=00000000\n"
+	if b.String() != expect {
+		t.Errorf("expect %q but got %q", expect, b.String())
+	}
+}
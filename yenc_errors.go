@@ -1,9 +1,21 @@
 package yenc
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrInvalidFormat = errors.New("not a valid yEncode formatted data stream")
 var ErrDataCorruption = errors.New("data corruption detected")
 var ErrRejectPrefixData = errors.New("yEncode not strated at the beginning of the data stream")
 var ErrBufferTooSmall = errors.New("buffer too small")
 var ErrWrtingTooMuch = errors.New("written data exceeds indicated size")
+var ErrPartMismatch = errors.New("part does not belong to the file being joined")
+var ErrUnsafeName = errors.New("unsafe file name")
+var ErrInvalidReplacement = errors.New("unsafe replacement character")
+var ErrCharset = errors.New("invalid character for charset")
+var ErrInvalidOffset = errors.New("invalid offset")
+var ErrInvalidLineLength = errors.New("line length must be positive")
+
+// Also matches ErrInvalidFormat.
+var ErrNoBinary = fmt.Errorf("no yEnc, uuencode or xxencode data found: %w", ErrInvalidFormat)
@@ -2,13 +2,27 @@ package yenc
 
 // yEncode header information
 type Header struct {
-	Name  string // Name of the final output file
+	Name  string // Name of the final output file, converted to UTF-8
 	Size  uint64 // Final, overall file size (of all parts decoded)
 	Part  uint64 // Part number (starts from 1)
 	Total uint64 // Total number of parts. Optional even for multipart.
 	Line  uint64 // Average line length
 	Begin uint64 // Part begin offset (0-indexed). Note the begin keyword in the =ypart line is 1-indexed.
 	End   uint64 // Part end offset (0-indexed, exclusive)
+
+	// The name= value as written in the =ybegin line, before charset and encoded-word decoding.
+	RawName string
+}
+
+// yEncode trailer information, as seen in the =yend line
+type Trailer struct {
+	Size      uint64 // Size of the decoded data of this part (or the whole file if single-part)
+	Part      uint64 // Part number. Optional.
+	Total     uint64 // Total number of parts. Optional.
+	PCRC32    uint32 // CRC32 of the decoded data of this part. Only valid if HasPCRC32 is true.
+	CRC32     uint32 // CRC32 of the whole decoded file. Only valid if HasCRC32 is true.
+	HasPCRC32 bool
+	HasCRC32  bool
 }
 
 // Max number of bytes per line (ends in LF and includes the LF) when decoding yEnc data stream. Default is 4096 which
@@ -0,0 +1,273 @@
+package yenc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A file of a FS and the source of its parts.
+type FSFile struct {
+	Name    string // Base name the file is listed under, valid per fs.ValidPath
+	Size    int64  // Decoded size. If negative, the first part is fetched to find it when the file is listed.
+	ModTime time.Time
+	Source  SegmentSource
+}
+
+// A read-only, flat file system of files fetched from segment sources, e.g. the files of an NZB (see nzb.NZB.FS) or
+// of a directory of .ntx articles (see DirFS). Open returns an fs.File that also implements io.ReaderAt and io.Seeker,
+// reading through a MultipartFile of its own, so that standard tools such as fs.WalkDir, http.FS or archive/zip work
+// directly on posts.
+type FS struct {
+	options []MultipartOption
+
+	mu    sync.Mutex
+	files map[string]*FSFile
+	names []string // Sorted
+}
+
+// File system of the given files. Files whose name is invalid or already taken are left out. The options apply to
+// every file opened.
+func NewFS(files []FSFile, options ...MultipartOption) *FS {
+	f := &FS{options: options, files: make(map[string]*FSFile, len(files))}
+	for i := range files {
+		file := files[i]
+		if !fs.ValidPath(file.Name) || file.Name == "." || strings.Contains(file.Name, "/") {
+			continue
+		}
+		if _, ok := f.files[file.Name]; ok {
+			continue
+		}
+		f.files[file.Name] = &file
+		f.names = append(f.names, file.Name)
+	}
+	sort.Strings(f.names)
+	return f
+}
+
+func (f *FS) Open(name string) (file fs.File, err error) {
+	if name == "." {
+		file = &fsDir{fs: f}
+		return
+	}
+	var entry *FSFile
+	if entry, err = f.entry("open", name); err != nil {
+		return
+	}
+	var m *MultipartFile
+	if m, err = NewMultipartFile(context.Background(), entry.Source, f.options...); err != nil {
+		err = &fs.PathError{Op: "open", Path: name, Err: err}
+		return
+	}
+	f.mu.Lock()
+	entry.Size = m.Size()
+	info := fsFileInfo{entry.Name, entry.Size, entry.ModTime}
+	f.mu.Unlock()
+	file = &fsFile{MultipartFile: m, info: info}
+	return
+}
+
+func (f *FS) Stat(name string) (info fs.FileInfo, err error) {
+	if name == "." {
+		info = fsDirInfo{}
+		return
+	}
+	var entry *FSFile
+	if entry, err = f.entry("stat", name); err != nil {
+		return
+	}
+	f.mu.Lock()
+	known := entry.Size >= 0
+	info = fsFileInfo{entry.Name, entry.Size, entry.ModTime}
+	f.mu.Unlock()
+	if known {
+		return
+	}
+	var file fs.File
+	if file, err = f.Open(name); err != nil {
+		err.(*fs.PathError).Op = "stat"
+		return
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+func (f *FS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	if name != "." {
+		if _, err = f.entry("readdir", name); err == nil {
+			err = &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+		}
+		return
+	}
+	for _, n := range f.names {
+		entries = append(entries, fsDirEntry{fs: f, name: n})
+	}
+	return
+}
+
+func (f *FS) entry(op, name string) (entry *FSFile, err error) {
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+		return
+	}
+	var ok bool
+	if entry, ok = f.files[name]; !ok {
+		err = &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return
+}
+
+// File system of the files whose parts are stored in the .ntx files of dir, one article body each, as in the fixture
+// directory. Parts are grouped into files by the name and size of their =ybegin line and ordered by offset, and files
+// that are not yEnc encoded are ignored. Only the headers are read until a file is opened.
+func DirFS(dir string, options ...DecodeOption) (f *FS, err error) {
+	var paths []string
+	if paths, err = filepath.Glob(filepath.Join(dir, "*.ntx")); err != nil {
+		return
+	}
+	type key struct {
+		name string
+		size uint64
+	}
+	type part struct {
+		path  string
+		begin uint64
+	}
+	var (
+		keys    []key
+		headers = make(map[key]*Header)
+		parts   = make(map[key][]part)
+		modTime = make(map[key]time.Time)
+	)
+	for _, path := range paths {
+		var (
+			h  *Header
+			fi os.FileInfo
+		)
+		if h, fi, err = readHeader(path, options); err != nil {
+			if errors.Is(err, ErrInvalidFormat) || errors.Is(err, ErrRejectPrefixData) || err == io.EOF {
+				err = nil
+				continue
+			}
+			return
+		}
+		k := key{h.Name, h.Size}
+		if _, ok := headers[k]; !ok {
+			keys = append(keys, k)
+			headers[k] = h
+		}
+		parts[k] = append(parts[k], part{path, h.Begin})
+		if fi.ModTime().After(modTime[k]) {
+			modTime[k] = fi.ModTime()
+		}
+	}
+	var files []FSFile
+	for _, k := range keys {
+		sort.SliceStable(parts[k], func(i, j int) bool { return parts[k][i].begin < parts[k][j].begin })
+		var paths []string
+		for _, p := range parts[k] {
+			paths = append(paths, p.path)
+		}
+		name, _ := SanitizeName(k.name)
+		files = append(files, FSFile{Name: name, Size: int64(k.size), ModTime: modTime[k], Source: FileSource(paths, options...)})
+	}
+	f = NewFS(files)
+	return
+}
+
+func readHeader(path string, options []DecodeOption) (h *Header, fi os.FileInfo, err error) {
+	var file *os.File
+	if file, err = os.Open(path); err != nil {
+		return
+	}
+	defer file.Close()
+	if fi, err = file.Stat(); err != nil {
+		return
+	}
+	var d *Decoder
+	if d, err = Decode(file, options...); err != nil {
+		return
+	}
+	h = d.Header()
+	return
+}
+
+type fsFile struct {
+	*MultipartFile
+	info fsFileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+type fsFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i fsFileInfo) Name() string       { return i.name }
+func (i fsFileInfo) Size() int64        { return i.size }
+func (i fsFileInfo) Mode() fs.FileMode  { return 0444 }
+func (i fsFileInfo) ModTime() time.Time { return i.modTime }
+func (i fsFileInfo) IsDir() bool        { return false }
+func (i fsFileInfo) Sys() any           { return nil }
+
+type fsDirInfo struct{}
+
+func (fsDirInfo) Name() string       { return "." }
+func (fsDirInfo) Size() int64        { return 0 }
+func (fsDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (fsDirInfo) ModTime() time.Time { return time.Time{} }
+func (fsDirInfo) IsDir() bool        { return true }
+func (fsDirInfo) Sys() any           { return nil }
+
+type fsDirEntry struct {
+	fs   *FS
+	name string
+}
+
+func (e fsDirEntry) Name() string               { return e.name }
+func (e fsDirEntry) IsDir() bool                { return false }
+func (e fsDirEntry) Type() fs.FileMode          { return 0 }
+func (e fsDirEntry) Info() (fs.FileInfo, error) { return e.fs.Stat(e.name) }
+
+// The root directory of a FS.
+type fsDir struct {
+	fs     *FS
+	offset int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return fsDirInfo{}, nil
+}
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+func (d *fsDir) Close() error {
+	return nil
+}
+
+func (d *fsDir) ReadDir(n int) (entries []fs.DirEntry, err error) {
+	all, _ := d.fs.ReadDir(".")
+	rest := all[d.offset:]
+	if n > 0 && len(rest) > n {
+		rest = rest[:n]
+	}
+	d.offset += len(rest)
+	entries = rest
+	if n > 0 && len(entries) == 0 {
+		err = io.EOF
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDirFS(t *testing.T) {
+	f, err := DirFS("fixture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{
+		"260731a73db67e8095a5eaf0b64b9d3db0117cdb",
+		"CamelsystemPowerpost-raw.bin",
+		"JBinUp-raw.bin",
+		"YencPowerPost-raw.bin",
+		"encode-raw.bin",
+		"ngPost-raw.bin",
+		"yEncBinPoster-raw.bin",
+		"yenc32-raw.bin",
+	}
+	if err = fstest.TestFS(f, names...); err != nil {
+		t.Fatal(err)
+	}
+	err = fs.WalkDir(f, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		raw, err := os.ReadFile("fixture/" + path)
+		if os.IsNotExist(err) {
+			raw, err = os.ReadFile("fixture/" + path + "-raw.bin")
+		}
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() != int64(len(raw)) {
+			t.Errorf("expect %s to have size %d but got %d", path, len(raw), info.Size())
+		}
+		b, err := fs.ReadFile(f, path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(b, raw) {
+			t.Errorf("%s data mismatch", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// random access through the opened file
+	file, err := f.Open("ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	b := make([]byte, 10)
+	if _, err = file.(io.ReaderAt).ReadAt(b, 4672); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.(io.Seeker).Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Open("missing.bin"); !os.IsNotExist(err) {
+		t.Errorf("expect a not exist error but got %v", err)
+	}
+}
@@ -0,0 +1,399 @@
+package yenc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// A yes/no quirk of a Fingerprint, which may not be known.
+type Trait int8
+
+const (
+	TraitUnknown Trait = iota
+	TraitNo
+	TraitYes
+)
+
+// Escapes of a Fingerprint not known for a position of the line.
+const UnknownEscapes = "?"
+
+// Encoding style of a posting tool, as recognised by Identify.
+//
+// Layouts are the keywords of the =ybegin, =ypart and =yend lines in order, e.g.
+// "=ybegin part total line size name =ypart begin end =yend size pcrc32", empty if unknown.
+type Fingerprint struct {
+	Tool    string
+	Aliases []string // Tools that encode identically, e.g. rebrands of the same code
+	Agent   string   // Found in the User-Agent, X-Newsposter or X-Newsreader headers or the Message-ID, if set
+
+	SinglePart string // Layout of a single-part file
+	Part       string // Layout of a part of a multi-part file, except the last
+	LastPart   string // Layout of the last part of a multi-part file
+
+	// Which of TAB, space and dot are escaped at the start, middle and end of lines, or UnknownEscapes.
+	Escapes [3]string
+
+	LongLines Trait // Lines ending with an escape run to line=+1 instead of breaking early
+	UpperHex  Trait // CRC32 values are upper case
+	NameSpace Trait // The name= value is followed by a space
+}
+
+// Fingerprints of the posting tools in the fixture corpus, tried in order by Identify.
+var Fingerprints = []*Fingerprint{
+	{
+		Tool:      "ngPost",
+		Aliases:   []string{"gopkg.in/yenc.v0"},
+		Agent:     "ngPost",
+		Part:      "=ybegin part total line size name =ypart begin end =yend size pcrc32",
+		LastPart:  "=ybegin part total line size name =ypart begin end =yend size pcrc32",
+		Escapes:   [3]string{UnknownEscapes, "", UnknownEscapes},
+		UpperHex:  TraitNo,
+		NameSpace: TraitNo,
+	},
+	{
+		Tool:       "Nyuu",
+		Agent:      "Nyuu",
+		SinglePart: "=ybegin line size crc32 name =yend size crc32",
+		Escapes:    [3]string{"\t .", "", "\t "},
+		LongLines:  TraitYes,
+		UpperHex:   TraitNo,
+		NameSpace:  TraitNo,
+	},
+	{
+		Tool:      "yEnc32",
+		Agent:     "yEnc32",
+		Part:      "=ybegin part total line size name =ypart begin end =yend size part pcrc32",
+		LastPart:  "=ybegin part total line size name =ypart begin end =yend size part pcrc32 crc32",
+		Escapes:   [3]string{UnknownEscapes, "\t.", UnknownEscapes},
+		UpperHex:  TraitYes,
+		NameSpace: TraitYes,
+	},
+	{
+		Tool:       "JBinUp",
+		Agent:      "JBinUp",
+		SinglePart: "=ybegin part total line size name =ypart begin end =yend size part pcrc32",
+		Escapes:    [3]string{UnknownEscapes, ".", UnknownEscapes},
+		LongLines:  TraitYes,
+		UpperHex:   TraitNo,
+		NameSpace:  TraitNo,
+	},
+	{
+		Tool:       "yEncBinPoster",
+		Agent:      "yEncBin",
+		SinglePart: "=ybegin part line size name =ypart begin end =yend size part pcrc32",
+		Escapes:    [3]string{UnknownEscapes, "\t", UnknownEscapes},
+		LongLines:  TraitYes,
+		UpperHex:   TraitYes,
+		NameSpace:  TraitNo,
+	},
+	{
+		Tool:       "YencPowerPost",
+		Aliases:    []string{"Camelsystem Powerpost"},
+		Agent:      "PowerPost",
+		SinglePart: "=ybegin part line size name =ypart begin end =yend size part pcrc32",
+		Escapes:    [3]string{UnknownEscapes, "", UnknownEscapes},
+		UpperHex:   TraitNo,
+		NameSpace:  TraitNo,
+	},
+}
+
+// Weights of the quirks compared by Identify. Others weigh 1.
+const (
+	identifyAgentWeight  = 5
+	identifyLayoutWeight = 3
+)
+
+// Options to encode parts the way the tool does, e.g. to re-post missing parts of a post in its original style. Only
+// what the Encoder supports is covered: line endings, line length, the case of CRC32 values and the escapes at the
+// edges of lines are not. A single part of a tool writing part= without total= is encoded as part 1 of an unknown
+// total, so pass EncodeWithPart after these options to encode a part of a multi-part file.
+func (f *Fingerprint) EncodeOptions() (options []EncodeOption) {
+	chars := append([]byte(nil), DefaultCriticalChars...)
+	if f.Escapes[1] != UnknownEscapes {
+		chars = append(chars, f.Escapes[1]...)
+	}
+	options = append(options, EncodeWithCriticalChars(chars))
+	switch {
+	case strings.HasPrefix(f.SinglePart, "=ybegin part total"):
+		options = append(options, EncodeWithSinglePartAsMultiPart())
+	case strings.HasPrefix(f.SinglePart, "=ybegin part"):
+		options = append(options, EncodeWithPart(1, 0, 0, 0))
+	}
+	for _, layout := range []string{f.SinglePart, f.Part, f.LastPart} {
+		if strings.Contains(layout, "=yend size part") {
+			options = append(options, EncodeWithTrailerPart())
+			break
+		}
+	}
+	// a single part encoded as a part is the last one for the Encoder
+	var pcrc32, crc32 bool
+	for _, layout := range []string{f.SinglePart, f.LastPart} {
+		_, trailer, _ := strings.Cut(layout, "=yend")
+		for _, key := range strings.Fields(trailer) {
+			pcrc32 = pcrc32 || key == "pcrc32"
+			crc32 = crc32 || key == "crc32"
+		}
+	}
+	if pcrc32 {
+		options = append(options, EncodeWithPartCrc32ForLastPart())
+	}
+	if crc32 {
+		options = append(options, EncodeWithFileCRC32ForLastPart())
+	}
+	return
+}
+
+// Identify the posting tool that most likely encoded an article body, from the quirks of its encoding and, if header
+// is not nil, the article headers. Fingerprints are ranked by the weight of the quirks that match less the weight of
+// those that do not. The confidence is the weighted share of the quirks compared that match the best one, split
+// between fingerprints that rank equally. Returns a nil fingerprint if no quirk could be compared, and
+// ErrInvalidFormat if the body has no =ybegin line.
+func Identify(body io.Reader, header textproto.MIMEHeader) (f *Fingerprint, confidence float64, err error) {
+	var q quirks
+	if err = q.read(body); err != nil {
+		return
+	}
+	q.header = header
+	var best, ties int
+	for _, fp := range Fingerprints {
+		score, total := q.match(fp)
+		if total == 0 {
+			continue
+		}
+		rank := 2*score - total
+		switch {
+		case f == nil || rank > best:
+			f, best, ties = fp, rank, 1
+			confidence = float64(score) / float64(total)
+		case rank == best:
+			ties++
+		}
+	}
+	if f != nil {
+		confidence /= float64(ties)
+	}
+	return
+}
+
+// Escape positions of the quirks and Fingerprint.Escapes.
+const (
+	lineStart = iota
+	lineMiddle
+	lineEnd
+)
+
+// The characters whose escaping tells tools apart.
+const identifyChars = "\t ."
+
+// Quirks seen in an article.
+type quirks struct {
+	header    textproto.MIMEHeader
+	layout    string
+	whole     bool // Single-part, or a part covering the whole file
+	last      bool // Last part of a multi-part file
+	escaped   [3][len(identifyChars)]int
+	raw       [3][len(identifyChars)]int
+	longLines Trait
+	upperHex  Trait
+	nameSpace Trait
+}
+
+func (q *quirks) read(body io.Reader) (err error) {
+	var (
+		br      = bufio.NewReader(body)
+		started bool
+		layout  []string
+		lineMax int
+		size    uint64
+		begin   uint64
+		end     uint64
+		short   bool // A data line shorter than line=, other than the last
+		pending int  // Length of the previous data line
+	)
+	for {
+		raw, rerr := br.ReadBytes('\n')
+		text, _ := splitEOL(raw)
+		switch {
+		case bytes.HasPrefix(text, []byte("=ybegin ")):
+			if started {
+				break
+			}
+			started = true
+			values := q.keywords(text[len("=ybegin "):], "=ybegin", &layout)
+			lineMax, _ = strconv.Atoi(values["line"])
+			size, _ = strconv.ParseUint(values["size"], 10, 64)
+			q.nameSpace = TraitNo
+			if bytes.HasSuffix(text, []byte(" ")) {
+				q.nameSpace = TraitYes
+			}
+			q.whole = values["part"] == ""
+			q.hex(values["crc32"])
+		case !started:
+		case bytes.HasPrefix(text, []byte("=ypart ")):
+			values := q.keywords(text[len("=ypart "):], "=ypart", &layout)
+			begin, _ = strconv.ParseUint(values["begin"], 10, 64)
+			end, _ = strconv.ParseUint(values["end"], 10, 64)
+			q.whole = begin <= 1 && end == size
+			q.last = !q.whole && end == size
+		case bytes.HasPrefix(text, []byte("=yend")):
+			values := q.keywords(bytes.TrimPrefix(text, []byte("=yend")), "=yend", &layout)
+			q.hex(values["pcrc32"])
+			q.hex(values["crc32"])
+			q.layout = strings.Join(layout, " ")
+			if short {
+				q.longLines = TraitNo
+			}
+			return
+		default:
+			if pending > 0 && pending < lineMax {
+				short = true
+			}
+			pending = len(text)
+			if lineMax > 0 && len(text) > lineMax {
+				q.longLines = TraitYes
+			}
+			q.data(text)
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			err = rerr
+			return
+		}
+	}
+	if !started {
+		err = fmt.Errorf("[yEnc] no =ybegin line: %w", ErrInvalidFormat)
+		return
+	}
+	q.layout = strings.Join(layout, " ")
+	return
+}
+
+// Append the line name and its keywords to layout, and return the values.
+func (q *quirks) keywords(line []byte, name string, layout *[]string) (values map[string]string) {
+	values = make(map[string]string)
+	*layout = append(*layout, name)
+	for _, kv := range strings.Fields(string(line)) {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		*layout = append(*layout, key)
+		values[key] = value
+		if key == "name" {
+			break
+		}
+	}
+	return
+}
+
+func (q *quirks) hex(value string) {
+	switch {
+	case strings.ContainsAny(value, "ABCDEF"):
+		q.upperHex = TraitYes
+	case strings.ContainsAny(value, "abcdef") && q.upperHex == TraitUnknown:
+		q.upperHex = TraitNo
+	}
+}
+
+func (q *quirks) data(line []byte) {
+	for i := 0; i < len(line); i++ {
+		pos, escaped, c := i, line[i] == '=' && i+1 < len(line), line[i]
+		if escaped {
+			i++
+			c = line[i] - 64
+		}
+		k := strings.IndexByte(identifyChars, c)
+		if k < 0 {
+			continue
+		}
+		at := lineMiddle
+		switch {
+		case pos == 0:
+			at = lineStart
+		case i == len(line)-1:
+			at = lineEnd
+		}
+		if escaped {
+			q.escaped[at][k]++
+		} else {
+			q.raw[at][k]++
+		}
+	}
+}
+
+// Weighted score of the quirks matching a fingerprint, out of the total weight of the quirks compared.
+func (q *quirks) match(f *Fingerprint) (score, total int) {
+	compare := func(weight int, ok bool) {
+		total += weight
+		if ok {
+			score += weight
+		}
+	}
+	if agent := q.agent(f); agent != TraitUnknown {
+		compare(identifyAgentWeight, agent == TraitYes)
+	}
+	expect := f.Part
+	switch {
+	case q.whole:
+		expect = f.SinglePart
+	case q.last:
+		expect = f.LastPart
+	}
+	if expect != "" {
+		compare(identifyLayoutWeight, expect == q.layout)
+	}
+	for _, t := range [][2]Trait{{q.longLines, f.LongLines}, {q.upperHex, f.UpperHex}, {q.nameSpace, f.NameSpace}} {
+		if t[0] != TraitUnknown && t[1] != TraitUnknown {
+			compare(1, t[0] == t[1])
+		}
+	}
+	for at, chars := range f.Escapes {
+		if chars == UnknownEscapes {
+			continue
+		}
+		seen, ok := false, true
+		for k := range identifyChars {
+			escaped, raw := q.escaped[at][k], q.raw[at][k]
+			if escaped == raw {
+				continue
+			}
+			seen = true
+			ok = ok && (escaped > raw) == (strings.IndexByte(chars, identifyChars[k]) >= 0)
+		}
+		if seen {
+			compare(1, ok)
+		}
+	}
+	return
+}
+
+// Whether the headers name the tool, TraitUnknown if they name no tool of Fingerprints.
+func (q *quirks) agent(f *Fingerprint) Trait {
+	if q.header == nil || f.Agent == "" {
+		return TraitUnknown
+	}
+	var values []string
+	for _, key := range []string{"User-Agent", "X-Newsposter", "X-Newsreader", "Message-ID"} {
+		values = append(values, q.header.Values(key)...)
+	}
+	named := TraitUnknown
+	for _, v := range values {
+		v = strings.ToLower(v)
+		if strings.Contains(v, strings.ToLower(f.Agent)) {
+			return TraitYes
+		}
+		for _, other := range Fingerprints {
+			if other.Agent != "" && strings.Contains(v, strings.ToLower(other.Agent)) {
+				named = TraitNo
+			}
+		}
+	}
+	return named
+}
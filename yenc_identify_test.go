@@ -0,0 +1,114 @@
+package yenc
+
+import (
+	"bytes"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIdentifyFixtures(t *testing.T) {
+	expect := map[string]string{
+		"260731a73db67e8095a5eaf0b64b9d3db0117cdb@nyuu": "Nyuu",
+		"CamelsystemPowerpost":                          "YencPowerPost",
+		"JBinUp":                                        "JBinUp",
+		"YencPowerPost":                                 "YencPowerPost",
+		"encode":                                        "ngPost",
+		"ngPost":                                        "ngPost",
+		"yEncBinPoster":                                 "yEncBinPoster",
+		"yenc32":                                        "yEnc32",
+	}
+	paths, err := filepath.Glob("fixture/*.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		base := strings.TrimSuffix(filepath.Base(path), ".ntx")
+		if i := strings.LastIndexByte(base, '-'); i >= 0 {
+			base = base[:i]
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fp, confidence, err := Identify(f, nil)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if fp == nil || fp.Tool != expect[base] || confidence < 0.99 {
+			t.Errorf("%s: expect %s but got %v with confidence %.2f", path, expect[base], fp, confidence)
+		}
+	}
+}
+
+func TestIdentifyHeader(t *testing.T) {
+	body, err := os.ReadFile("fixture/YencPowerPost-001.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := textproto.MIMEHeader{"X-Newsposter": {"Camelsystem Powerpost"}}
+	if fp, confidence, _ := Identify(bytes.NewReader(body), header); fp == nil || fp.Tool != "YencPowerPost" || confidence != 1 {
+		t.Errorf("expect YencPowerPost but got %v with confidence %.2f", fp, confidence)
+	}
+	// the header outweighs the layout, but not every quirk
+	header = textproto.MIMEHeader{"User-Agent": {"yEncBin Poster 1.0"}}
+	fp, confidence, _ := Identify(bytes.NewReader(body), header)
+	if fp == nil || fp.Tool != "yEncBinPoster" || confidence >= 1 {
+		t.Errorf("expect yEncBinPoster with less confidence but got %v with confidence %.2f", fp, confidence)
+	}
+	if _, _, err := Identify(strings.NewReader("no yEnc here\n"), nil); err == nil {
+		t.Error("expect an error without =ybegin line")
+	}
+}
+
+func TestIdentifyEncodeOptions(t *testing.T) {
+	data := bytes.Repeat([]byte("\x04\x00\x09\xdf\xe3"), 200) // encoded as ".*3=I=M" or ".*3\t=M"
+	tools := make(map[string]*Fingerprint)
+	for _, fp := range Fingerprints {
+		tools[fp.Tool] = fp
+	}
+	jbinup, yenc32 := tools["JBinUp"], tools["yEnc32"]
+	size := uint64(len(data))
+	encode := func(fp *Fingerprint, size uint64, options ...EncodeOption) *bytes.Buffer {
+		var b bytes.Buffer
+		e, err := Encode(&b, "file.bin", size, append(fp.EncodeOptions(), append(options, EncodeWithLF())...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Write(data)
+		if err = e.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return &b
+	}
+	if got, _, err := Identify(encode(jbinup, size), nil); err != nil || got != jbinup {
+		t.Errorf("expect JBinUp options to encode as JBinUp but got %v: %v", got, err)
+	}
+	for _, tool := range []string{"JBinUp", "yEncBinPoster", "YencPowerPost"} {
+		fp := tools[tool]
+		var q quirks
+		if err := q.read(encode(fp, size)); err != nil {
+			t.Fatal(err)
+		}
+		if q.layout != fp.SinglePart {
+			t.Errorf("expect a single %s part to have layout %q but got %q", tool, fp.SinglePart, q.layout)
+		}
+	}
+	// the case of CRC32 values and the space after the name are not covered, only the layout is
+	for _, c := range []struct {
+		part   uint64
+		layout string
+	}{{1, yenc32.Part}, {2, yenc32.LastPart}} {
+		var q quirks
+		if err := q.read(encode(yenc32, 2*size, EncodeWithPart(c.part, 2, (c.part-1)*size, c.part*size))); err != nil {
+			t.Fatal(err)
+		}
+		if q.layout != c.layout {
+			t.Errorf("expect part %d to have layout %q but got %q", c.part, c.layout, q.layout)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package yenc
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"sync"
+)
+
+// A byte range of the decoded file, from Begin (0-indexed) to End (exclusive) like the part offsets of Header.
+type Range struct {
+	Begin uint64
+	End   uint64
+}
+
+// Assembles a file from its parts, decoding each part into place at its offset. Parts can be joined in any order and
+// concurrently. Parts that fail to decode are written as far as they go and recorded as damaged, while parts never
+// joined are missing, so that the file can be repaired (e.g. with PAR2) from the list of bad ranges.
+type Joiner struct {
+	w       io.WriterAt
+	options []DecodeOption
+
+	mu       sync.Mutex
+	h        *Header
+	joined   []Range
+	damaged  []Range
+	crc      uint32 // CRC32 of the file given by a trailer
+	hasCRC32 bool
+
+	head       []byte // Copy of the first bytes of the file for headHash
+	headHash   hash.Hash
+	headHashed bool
+}
+
+func NewJoiner(w io.WriterAt, options ...DecodeOption) *Joiner {
+	return &Joiner{w: w, options: options}
+}
+
+// Decode a part and write its data at its offset. Returns ErrPartMismatch if the part does not belong to the same file
+// as the first part joined. If the part fails to decode after its header, whatever data was decoded is written, the
+// part range is recorded as damaged and the decoding error is returned.
+func (j *Joiner) Join(r io.Reader) (h *Header, err error) {
+	var d *Decoder
+	if d, err = Decode(r, j.options...); err != nil {
+		return
+	}
+	h = d.Header()
+	part := Range{h.Begin, h.End}
+	if h.Part == 0 {
+		part = Range{0, h.Size}
+	}
+	if err = j.check(h); err != nil {
+		return
+	}
+	var n int64
+	if n, err = io.Copy(&offsetWriter{w: j.w, offset: int64(part.Begin), j: j}, d); err == nil && uint64(n) != part.End-part.Begin {
+		err = fmt.Errorf("[yEnc] part %d has %d bytes but decoded %d bytes: %w", h.Part, part.End-part.Begin, n, ErrDataCorruption)
+	}
+	var werr *writeError
+	if errors.As(err, &werr) {
+		// not the fault of the part
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		j.damaged = addRange(j.damaged, part)
+		j.joined = subtractRange(j.joined, part)
+	} else {
+		j.joined = addRange(j.joined, part)
+		j.damaged = subtractRange(j.damaged, part)
+		if t := d.Trailer(); t != nil && !j.hasCRC32 {
+			if t.HasCRC32 {
+				j.crc, j.hasCRC32 = t.CRC32, true
+			} else if t.HasPCRC32 && part == (Range{0, h.Size}) {
+				j.crc, j.hasCRC32 = t.PCRC32, true
+			}
+		}
+	}
+	if j.headHash != nil && !j.headHashed {
+		head := j.head
+		if uint64(len(head)) > j.h.Size {
+			head = head[:j.h.Size]
+		}
+		if len(head) == 0 || (len(j.joined) > 0 && j.joined[0].Begin == 0 && j.joined[0].End >= uint64(len(head))) {
+			j.headHash.Write(head)
+			j.headHashed = true
+		}
+	}
+	return
+}
+
+// Feed the first n bytes of the file (or the whole file if shorter) to h once they have all been joined intact,
+// whatever the order of the parts, e.g. to compute the PAR2 16 KiB hash without reading the output again. Must be
+// called before joining.
+func (j *Joiner) HashHead(h hash.Hash, n int) {
+	j.headHash = h
+	j.head = make([]byte, n)
+}
+
+// Whether the head has been fed to the hash given to HashHead.
+func (j *Joiner) HeadHashed() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.headHashed
+}
+
+func (j *Joiner) check(h *Header) (err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.h == nil {
+		j.h = &Header{Name: h.Name, RawName: h.RawName, Size: h.Size, Total: h.Total}
+		return
+	}
+	if h.Name != j.h.Name || h.Size != j.h.Size {
+		err = fmt.Errorf("[yEnc] part of %s (%d bytes) does not belong to %s (%d bytes): %w", h.Name, h.Size, j.h.Name, j.h.Size, ErrPartMismatch)
+	}
+	return
+}
+
+// File name, size and total number of parts, as given by the first part joined. Nil until a part has been joined.
+func (j *Joiner) Header() *Header {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.h == nil {
+		return nil
+	}
+	h := *j.h
+	return &h
+}
+
+// Ranges of the file not covered by any part joined so far, damaged parts included.
+func (j *Joiner) Missing() []Range {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.h == nil {
+		return nil
+	}
+	missing := []Range{{0, j.h.Size}}
+	for _, r := range append(append([]Range(nil), j.joined...), j.damaged...) {
+		missing = subtractRange(missing, r)
+	}
+	return missing
+}
+
+// Ranges of the parts that failed to decode, e.g. because of a CRC mismatch or truncation.
+func (j *Joiner) Damaged() []Range {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]Range(nil), j.damaged...)
+}
+
+// Missing and damaged ranges, merged and ordered. Nil if the file is complete.
+func (j *Joiner) Bad() (bad []Range) {
+	for _, r := range append(j.Missing(), j.Damaged()...) {
+		bad = addRange(bad, r)
+	}
+	return
+}
+
+// CRC32 of the whole file as given by the crc32 value of the trailers of the parts joined intact, or the pcrc32 value
+// of a part covering the whole file. The Joiner does not check it, as the parts may be joined in any order.
+func (j *Joiner) FileCRC32() (crc uint32, ok bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.crc, j.hasCRC32
+}
+
+// Whether a part has been joined and the whole file has been decoded without errors.
+func (j *Joiner) Complete() bool {
+	return j.Header() != nil && len(j.Bad()) == 0
+}
+
+// Add a range to an ordered list of disjoint ranges, merging adjacent and overlapping ranges.
+func addRange(ranges []Range, r Range) []Range {
+	if r.End <= r.Begin {
+		return ranges
+	}
+	var merged []Range
+	for _, s := range ranges {
+		if s.End < r.Begin || s.Begin > r.End {
+			merged = append(merged, s)
+			continue
+		}
+		if s.Begin < r.Begin {
+			r.Begin = s.Begin
+		}
+		if s.End > r.End {
+			r.End = s.End
+		}
+	}
+	merged = append(merged, r)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Begin < merged[j].Begin })
+	return merged
+}
+
+// Remove a range from an ordered list of disjoint ranges.
+func subtractRange(ranges []Range, r Range) (result []Range) {
+	for _, s := range ranges {
+		if s.End <= r.Begin || s.Begin >= r.End {
+			result = append(result, s)
+			continue
+		}
+		if s.Begin < r.Begin {
+			result = append(result, Range{s.Begin, r.Begin})
+		}
+		if s.End > r.End {
+			result = append(result, Range{r.End, s.End})
+		}
+	}
+	return
+}
+
+// Writes sequentially to an io.WriterAt from an offset.
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+	j      *Joiner // Keeps a copy of the head if set
+}
+
+type writeError struct {
+	err error
+}
+
+func (e *writeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *writeError) Unwrap() error {
+	return e.err
+}
+
+func (o *offsetWriter) Write(b []byte) (n int, err error) {
+	if o.j != nil && o.offset < int64(len(o.j.head)) {
+		o.j.mu.Lock()
+		copy(o.j.head[o.offset:], b)
+		o.j.mu.Unlock()
+	}
+	n, err = o.w.WriteAt(b, o.offset)
+	o.offset += int64(n)
+	if err != nil {
+		err = &writeError{fmt.Errorf("[yEnc] failed to write decoded data: %w", err)}
+	}
+	return
+}
@@ -0,0 +1,107 @@
+package yenc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// An in-memory io.WriterAt.
+type memFile struct {
+	b []byte
+}
+
+func (m *memFile) WriteAt(b []byte, off int64) (int, error) {
+	if end := int(off) + len(b); end > len(m.b) {
+		m.b = append(m.b, make([]byte, end-len(m.b))...)
+	}
+	return copy(m.b[off:], b), nil
+}
+
+func TestJoiner(t *testing.T) {
+	raw, err := os.ReadFile("fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f memFile
+	j := NewJoiner(&f)
+	// join in reverse order, skipping part 3 and corrupting part 5
+	for part := 10; part >= 1; part-- {
+		if part == 3 {
+			continue
+		}
+		encoded, err := os.ReadFile(fmt.Sprintf("fixture/ngPost-%03d.ntx", part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part == 5 {
+			i := bytes.IndexByte(encoded, '\n') + 1
+			i += bytes.IndexByte(encoded[i:], '\n') + 10
+			encoded[i] ^= 0x01
+		}
+		h, err := j.Join(bytes.NewReader(encoded))
+		if part == 5 {
+			if err == nil {
+				t.Fatal("expect an error for the corrupted part")
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if h.Part != uint64(part) {
+			t.Errorf("expect part %d but got %d", part, h.Part)
+		}
+	}
+	if h := j.Header(); h.Name != "ngPost-raw.bin" || h.Size != uint64(len(raw)) || h.Total != 10 {
+		t.Errorf("unexpected header %+v", h)
+	}
+	if missing := j.Missing(); fmt.Sprint(missing) != "[{1024 1536}]" {
+		t.Errorf("unexpected missing ranges %v", missing)
+	}
+	if damaged := j.Damaged(); fmt.Sprint(damaged) != "[{2048 2560}]" {
+		t.Errorf("unexpected damaged ranges %v", damaged)
+	}
+	if bad := j.Bad(); fmt.Sprint(bad) != "[{1024 1536} {2048 2560}]" {
+		t.Errorf("unexpected bad ranges %v", bad)
+	}
+	if j.Complete() {
+		t.Error("expect the file to be incomplete")
+	}
+	if !bytes.Equal(f.b[:1024], raw[:1024]) || !bytes.Equal(f.b[2560:], raw[2560:]) {
+		t.Error("joined data mismatch")
+	}
+
+	// a repost of the missing and damaged parts completes the file
+	for _, part := range []int{3, 5} {
+		encoded, err := os.ReadFile(fmt.Sprintf("fixture/ngPost-%03d.ntx", part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = j.Join(bytes.NewReader(encoded)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !j.Complete() || !bytes.Equal(f.b, raw) {
+		t.Errorf("expect the file to be complete, bad ranges %v", j.Bad())
+	}
+
+	other, err := os.ReadFile("fixture/JBinUp-001.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = j.Join(bytes.NewReader(other)); !errors.Is(err, ErrPartMismatch) {
+		t.Errorf("expect ErrPartMismatch but got %v", err)
+	}
+	// ngPost writes no crc32 value, but the pcrc32 value of a single part is that of the file
+	if _, ok := j.FileCRC32(); ok {
+		t.Error("expect no file CRC32")
+	}
+	j = NewJoiner(&memFile{})
+	if _, err = j.Join(bytes.NewReader(other)); err != nil {
+		t.Fatal(err)
+	}
+	if crc, ok := j.FileCRC32(); !ok || crc != 0x5b0acdc1 {
+		t.Errorf("unexpected file CRC32 %08x %v", crc, ok)
+	}
+}
@@ -0,0 +1,480 @@
+package yenc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A deviation from the yEnc specification found by Lint, at a physical line of the input.
+type Finding struct {
+	Line     int // 1-indexed
+	Severity Severity
+	Check    string // One of the Lint* check names
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%d: %s: %s [%s]", f.Line, f.Severity, f.Message, f.Check)
+}
+
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota // Allowed but unusual, or an optional field left out
+	SeverityWarning                 // Decodes, but may break with some servers or decoders
+	SeverityError                   // Violates the specification, decoding fails or is wrong
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for _, v := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if string(text) == v.String() {
+			*s = v
+			return nil
+		}
+	}
+	return fmt.Errorf("[yEnc] unknown severity %q", text)
+}
+
+// Checks reported by Lint.
+const (
+	LintStructure         = "structure"          // Missing, repeated or misplaced =ybegin, =ypart or =yend lines
+	LintKeyword           = "keyword"            // Missing, invalid or unknown keyword values
+	LintKeywordOrder      = "keyword-order"      // Keywords not in the order of the specification
+	LintOptional          = "optional"           // Optional keywords left out
+	LintLineLength        = "line-length"        // Data lines longer than the line= value
+	LintUnescaped         = "unescaped"          // Critical characters left unescaped
+	LintUnnecessaryEscape = "unnecessary-escape" // Escaped characters that need no escaping
+	LintLeadingDot        = "leading-dot"        // Data lines starting with a dot neither escaped nor stuffed
+	LintEOL               = "eol"                // Mixed CRLF and LF line endings, or bare CR
+	LintWhitespace        = "whitespace"         // Trailing spaces or tabs, which servers may strip
+	LintBegin             = "begin"              // =ypart begin= given 0-indexed
+	LintMismatch          = "mismatch"           // Header and trailer disagreeing with each other or with the data
+)
+
+// Max number of findings reported per check, as an encoder bending the spec usually does so on every line. Further
+// occurrences are summed up in one last finding.
+var LintMaxPerCheck = 10
+
+// Check an article body (or a whole article, the headers being skipped) against the yEnc 1.3 specification, reading
+// r to the end. Unlike the Decoder, which stops at the first error, every deviation found is reported, ordered by
+// line. A failure to read r is reported as a LintStructure error.
+func Lint(r io.Reader) []Finding {
+	l := &linter{counts: make(map[string]int)}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			l.n++
+			l.line(line)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			l.report(SeverityError, LintStructure, "failed to read: %v", err)
+			break
+		}
+	}
+	l.finish()
+	return l.findings
+}
+
+type linter struct {
+	n        int // current line
+	findings []Finding
+	counts   map[string]int
+
+	state    int
+	prefix   int // Lines before =ybegin
+	ybegin   map[string]string
+	ypart    map[string]string
+	lineMax  int
+	eol      string // First line ending seen
+	mixedEOL bool
+	crc      uint32 // CRC32 of the data decoded so far
+	size     uint64 // Size of the data decoded so far
+}
+
+const (
+	lintHead = iota // before =ybegin
+	lintData        // between =ybegin and =yend
+	lintTail        // after =yend
+)
+
+func (l *linter) report(severity Severity, check, format string, args ...any) {
+	l.counts[check]++
+	if l.counts[check] > LintMaxPerCheck {
+		return
+	}
+	l.findings = append(l.findings, Finding{Line: l.n, Severity: severity, Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) line(raw []byte) {
+	text, eol := splitEOL(raw)
+	l.checkEOL(eol)
+	switch {
+	case bytes.HasPrefix(text, []byte("=ybegin ")):
+		l.ybeginLine(text)
+	case bytes.HasPrefix(text, []byte("=ypart ")):
+		l.ypartLine(text)
+	case bytes.HasPrefix(text, []byte("=yend")):
+		l.yendLine(text)
+	case l.state == lintHead:
+		l.prefix++
+	case l.state == lintData:
+		l.data(text)
+	default:
+		if len(bytes.TrimSpace(text)) > 0 {
+			l.report(SeverityInfo, LintStructure, "data after the =yend line")
+		}
+	}
+}
+
+func splitEOL(raw []byte) (text []byte, eol string) {
+	switch {
+	case bytes.HasSuffix(raw, []byte("\r\n")):
+		return raw[:len(raw)-2], "\r\n"
+	case bytes.HasSuffix(raw, []byte("\n")):
+		return raw[:len(raw)-1], "\n"
+	}
+	return raw, ""
+}
+
+func (l *linter) checkEOL(eol string) {
+	if eol == "" {
+		return
+	}
+	if l.eol == "" {
+		l.eol = eol
+	} else if eol != l.eol && !l.mixedEOL {
+		l.mixedEOL = true
+		l.report(SeverityWarning, LintEOL, "mixed CRLF and LF line endings, %q first then %q", l.eol, eol)
+	}
+}
+
+// Parse the keywords of a =ybegin, =ypart or =yend line, with name= reading to the end of the line.
+func (l *linter) keywords(line string, order []string) (values map[string]string) {
+	values = make(map[string]string)
+	var keys []string
+	for line != "" {
+		line = strings.TrimLeft(line, " ")
+		if line == "" {
+			break
+		}
+		kv := line
+		if strings.HasPrefix(line, "name=") {
+			line = ""
+		} else if i := strings.IndexByte(line, ' '); i >= 0 {
+			kv, line = line[:i], line[i+1:]
+		} else {
+			line = ""
+		}
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			l.report(SeverityError, LintKeyword, "invalid keyword argument %q", kv)
+			continue
+		}
+		if _, dup := values[key]; dup {
+			l.report(SeverityWarning, LintKeyword, "repeated keyword %s", key)
+		}
+		known := false
+		for _, k := range order {
+			known = known || k == key
+		}
+		if !known {
+			l.report(SeverityInfo, LintKeyword, "unknown keyword %s", key)
+		}
+		values[key] = value
+		keys = append(keys, key)
+	}
+	// keywords in the order of the specification, unknown ones aside
+	i := 0
+	for _, k := range keys {
+		j := indexOf(order, k)
+		if j < 0 {
+			continue
+		}
+		if j < i {
+			l.report(SeverityInfo, LintKeywordOrder, "keyword %s after %s, expected order is %s", k, order[i], strings.Join(order, " "))
+			break
+		}
+		i = j
+	}
+	return
+}
+
+func indexOf(list []string, s string) int {
+	for i, t := range list {
+		if t == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *linter) uint(values map[string]string, key, line string, required bool) (u uint64, ok bool) {
+	value, found := values[key]
+	if !found {
+		if required {
+			l.report(SeverityError, LintKeyword, "missing %s= on the %s line", key, line)
+		}
+		return
+	}
+	var err error
+	if u, err = strconv.ParseUint(value, 10, 64); err != nil {
+		l.report(SeverityError, LintKeyword, "invalid %s= value %q on the %s line", key, value, line)
+		return
+	}
+	ok = true
+	return
+}
+
+func (l *linter) trailingWhitespace(text []byte, line string) {
+	if n := len(text); n > 0 && (text[n-1] == ' ' || text[n-1] == '\t') {
+		l.report(SeverityInfo, LintWhitespace, "trailing whitespace on the %s line", line)
+	}
+}
+
+func (l *linter) ybeginLine(text []byte) {
+	if l.state != lintHead {
+		l.report(SeverityError, LintStructure, "repeated =ybegin line")
+		return
+	}
+	if l.prefix > 0 {
+		l.report(SeverityInfo, LintStructure, "%d lines before the =ybegin line", l.prefix)
+	}
+	l.state = lintData
+	l.trailingWhitespace(text, "=ybegin")
+	l.ybegin = l.keywords(string(text[len("=ybegin "):]), []string{"part", "total", "line", "size", "name"})
+	if line, ok := l.uint(l.ybegin, "line", "=ybegin", true); ok {
+		l.lineMax = int(line)
+	}
+	l.uint(l.ybegin, "size", "=ybegin", true)
+	part, hasPart := l.uint(l.ybegin, "part", "=ybegin", false)
+	total, hasTotal := l.uint(l.ybegin, "total", "=ybegin", false)
+	if hasPart && !hasTotal {
+		l.report(SeverityInfo, LintOptional, "missing total= for a multipart file (yEnc 1.2)")
+	}
+	if hasPart && hasTotal && (part == 0 || part > total) {
+		l.report(SeverityError, LintKeyword, "part %d out of range 1 to %d", part, total)
+	}
+	name, ok := l.ybegin["name"]
+	switch {
+	case !ok:
+		l.report(SeverityError, LintKeyword, "missing name= on the =ybegin line")
+	case strings.TrimSpace(name) == "":
+		l.report(SeverityError, LintKeyword, "empty name= value")
+	default:
+		for _, k := range []string{"part", "total", "line", "size"} {
+			if strings.Contains(name, " "+k+"=") {
+				l.report(SeverityError, LintKeywordOrder, "name= is not the last keyword, %s= is read as part of the name", k)
+			}
+		}
+	}
+}
+
+func (l *linter) ypartLine(text []byte) {
+	if l.state != lintData || l.ypart != nil || l.size > 0 {
+		l.report(SeverityError, LintStructure, "misplaced =ypart line")
+		return
+	}
+	if _, ok := l.ybegin["part"]; !ok {
+		l.report(SeverityWarning, LintStructure, "=ypart line without part= on the =ybegin line")
+	}
+	l.trailingWhitespace(text, "=ypart")
+	l.ypart = l.keywords(string(text[len("=ypart "):]), []string{"begin", "end"})
+	begin, hasBegin := l.uint(l.ypart, "begin", "=ypart", true)
+	end, hasEnd := l.uint(l.ypart, "end", "=ypart", true)
+	size, hasSize := parseUint(l.ybegin["size"])
+	if hasBegin && begin == 0 {
+		l.report(SeverityWarning, LintBegin, "begin=0 is 0-indexed, the first byte is begin=1")
+	}
+	if hasBegin && hasEnd && end+1 < begin {
+		l.report(SeverityError, LintKeyword, "end=%d before begin=%d", end, begin)
+	}
+	if hasEnd && hasSize && end > size {
+		l.report(SeverityError, LintMismatch, "end=%d beyond size=%d", end, size)
+	}
+}
+
+// Part of the file the data should cover: the =ypart range, or the whole file for a single-part file.
+func (l *linter) expectedSize() (size uint64, ok bool) {
+	if l.ypart != nil {
+		begin, hasBegin := parseUint(l.ypart["begin"])
+		end, hasEnd := parseUint(l.ypart["end"])
+		if !hasBegin || !hasEnd || end+1 < begin {
+			return
+		}
+		if begin == 0 {
+			// 0-indexed, as reported
+			return end - begin, true
+		}
+		return end - begin + 1, true
+	}
+	return parseUint(l.ybegin["size"])
+}
+
+func parseUint(s string) (u uint64, ok bool) {
+	var err error
+	u, err = strconv.ParseUint(s, 10, 64)
+	ok = err == nil
+	return
+}
+
+func (l *linter) data(text []byte) {
+	if _, ok := l.ybegin["part"]; ok && l.ypart == nil && l.size == 0 {
+		l.report(SeverityError, LintStructure, "missing =ypart line for a multipart file")
+		l.ypart = map[string]string{}
+	}
+	// the stuffing dot of NNTP is not part of the encoded line
+	stuffed := len(text) >= 2 && text[0] == '.' && text[1] == '.'
+	if stuffed {
+		text = text[1:]
+	}
+	if l.lineMax > 0 && len(text) > l.lineMax+1 {
+		// one more character is allowed for an escape sequence at the end of the line
+		l.report(SeverityWarning, LintLineLength, "line of %d characters exceeds line=%d", len(text), l.lineMax)
+	}
+	if len(text) > 0 {
+		switch text[0] {
+		case '.':
+			if !stuffed {
+				l.report(SeverityWarning, LintLeadingDot, "data line starts with a dot that is neither escaped nor stuffed")
+			}
+		case ' ', '\t':
+			l.report(SeverityWarning, LintWhitespace, "data line starts with whitespace, which servers may strip")
+		}
+		if c := text[len(text)-1]; c == ' ' || c == '\t' {
+			l.report(SeverityWarning, LintWhitespace, "data line ends with whitespace, which servers may strip")
+		}
+	}
+	decoded := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch c {
+		case 0:
+			l.report(SeverityError, LintUnescaped, "unescaped NUL at column %d", i+1)
+		case '\r':
+			l.report(SeverityError, LintEOL, "bare CR at column %d", i+1)
+		case '=':
+			if i+1 == len(text) {
+				l.report(SeverityError, LintUnescaped, "escape character at the end of the line")
+				continue
+			}
+			i++
+			c = text[i] - 64
+			switch c {
+			case 0, '\n', '\r', '=', '\t', ' ', '.':
+				// whitespace and dots only need escaping at the start or end of a line, but are commonly escaped
+				// everywhere
+			default:
+				l.report(SeverityInfo, LintUnnecessaryEscape, "unnecessary escape of %#02x at column %d", c, i)
+			}
+		}
+		decoded = append(decoded, c-42)
+	}
+	l.crc = crc32.Update(l.crc, crc32.IEEETable, decoded)
+	l.size += uint64(len(decoded))
+}
+
+func (l *linter) yendLine(text []byte) {
+	if l.state != lintData {
+		l.report(SeverityError, LintStructure, "misplaced =yend line")
+		return
+	}
+	l.state = lintTail
+	l.trailingWhitespace(text, "=yend")
+	yend := l.keywords(strings.TrimPrefix(string(text[len("=yend"):]), " "), []string{"size", "part", "total", "pcrc32", "crc32"})
+	size, hasSize := l.uint(yend, "size", "=yend", true)
+	if expect, ok := l.expectedSize(); hasSize && ok && size != expect {
+		l.report(SeverityError, LintMismatch, "=yend size=%d but the part has %d bytes", size, expect)
+	}
+	if hasSize && size != l.size {
+		l.report(SeverityError, LintMismatch, "=yend size=%d but %d bytes were decoded", size, l.size)
+	}
+	_, multipart := l.ybegin["part"]
+	for _, k := range []string{"part", "total"} {
+		if v, ok := l.uint(yend, k, "=yend", false); ok {
+			if h, ok := parseUint(l.ybegin[k]); !ok || h != v {
+				l.report(SeverityError, LintMismatch, "=yend %s=%d disagrees with =ybegin %s=%s", k, v, k, l.ybegin[k])
+			}
+		} else if multipart && k == "part" {
+			l.report(SeverityInfo, LintOptional, "missing part= on the =yend line of a multipart file")
+		}
+	}
+	l.checkCRC32(yend, "pcrc32", multipart)
+	if l.ypart == nil || l.isWholeFile() {
+		l.checkCRC32(yend, "crc32", !multipart)
+	} else if _, ok := yend["crc32"]; ok {
+		// the CRC32 of the whole file cannot be checked from one part
+		if _, err := strconv.ParseUint(yend["crc32"], 16, 32); err != nil {
+			l.report(SeverityError, LintKeyword, "invalid crc32= value %q", yend["crc32"])
+		}
+	}
+}
+
+func (l *linter) isWholeFile() bool {
+	size, ok := parseUint(l.ybegin["size"])
+	return ok && size == l.size
+}
+
+// Check a CRC32 keyword of the =yend line against the decoded data, reporting it missing if expected.
+func (l *linter) checkCRC32(yend map[string]string, key string, expected bool) {
+	value, ok := yend[key]
+	if !ok {
+		if expected {
+			l.report(SeverityInfo, LintOptional, "missing %s= on the =yend line", key)
+		}
+		return
+	}
+	crc, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		l.report(SeverityError, LintKeyword, "invalid %s= value %q", key, value)
+		return
+	}
+	if uint32(crc) != l.crc {
+		l.report(SeverityError, LintMismatch, "=yend %s=%s but the data has CRC32 %08x", key, value, l.crc)
+	}
+}
+
+// Report what is missing at the end of the input, and the findings left out by LintMaxPerCheck.
+func (l *linter) finish() {
+	switch l.state {
+	case lintHead:
+		l.report(SeverityError, LintStructure, "no =ybegin line")
+	case lintData:
+		l.report(SeverityError, LintStructure, "no =yend line")
+	}
+	var checks []string
+	for check := range l.counts {
+		checks = append(checks, check)
+	}
+	sort.Strings(checks)
+	for _, check := range checks {
+		if count := l.counts[check]; count > LintMaxPerCheck {
+			l.findings = append(l.findings, Finding{Line: l.n, Severity: SeverityInfo, Check: check, Message: fmt.Sprintf("%d more %s findings", count-LintMaxPerCheck, check)})
+		}
+	}
+}
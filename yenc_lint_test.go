@@ -0,0 +1,102 @@
+package yenc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintFixtures(t *testing.T) {
+	paths, err := filepath.Glob("fixture/*.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, finding := range Lint(f) {
+			if finding.Severity == SeverityError {
+				t.Errorf("%s:%s", path, finding)
+			}
+		}
+		f.Close()
+	}
+}
+
+func TestLint(t *testing.T) {
+	// "!\"#" encoded is "KLM", CRC32 c31bc297
+	article := strings.Join([]string{
+		"From: poster@example.com",
+		"",
+		"=ybegin size=6 line=4 part=1 name=file.bin",
+		"=ypart begin=0 end=3",
+		"KLM=n ",
+		".=L\r",
+		"KLM\x00KLMKLM",
+		"=yend size=4 part=2 pcrc32=00000000 total=3",
+		"",
+	}, "\n")
+	findings := Lint(strings.NewReader(article))
+	expect := []struct {
+		line  int
+		check string
+	}{
+		{3, LintStructure},    // 2 lines before =ybegin
+		{3, LintKeywordOrder}, // size before line
+		{3, LintOptional},     // no total
+		{4, LintBegin},        // 0-indexed
+		{5, LintLineLength},
+		{5, LintWhitespace},
+		{6, LintEOL}, // mixed CRLF and LF
+		{6, LintLeadingDot},
+		{6, LintUnnecessaryEscape},
+		{7, LintLineLength},
+		{7, LintUnescaped},    // NUL
+		{8, LintKeywordOrder}, // pcrc32 before total
+		{8, LintMismatch},     // size=4 but the part has 3 bytes
+		{8, LintMismatch},     // size=4 but 17 bytes decoded
+		{8, LintMismatch},     // part=2
+		{8, LintMismatch},     // total=3
+		{8, LintMismatch},     // pcrc32
+	}
+	if len(findings) != len(expect) {
+		for _, f := range findings {
+			t.Log(f)
+		}
+		t.Fatalf("expect %d findings but got %d", len(expect), len(findings))
+	}
+	for i, e := range expect {
+		if f := findings[i]; f.Line != e.line || f.Check != e.check {
+			t.Errorf("expect finding %d to be %s at line %d but got %s", i, e.check, e.line, f)
+		}
+	}
+	if findings := Lint(strings.NewReader("KLM\n")); len(findings) != 1 || findings[0].Check != LintStructure || findings[0].Severity != SeverityError {
+		t.Errorf("expect a missing =ybegin error but got %v", findings)
+	}
+}
+
+// The stuffing dot of a line starting with ".." is not data.
+func TestLintStuffedDot(t *testing.T) {
+	article := "=ybegin line=2 size=2 name=a\n..K\n=yend size=2 crc32=69dcc7a5\n"
+	if findings := Lint(strings.NewReader(article)); len(findings) != 0 {
+		t.Errorf("expect no finding but got %v", findings)
+	}
+}
+
+func TestLintMaxPerCheck(t *testing.T) {
+	article := "=ybegin line=1 size=60 name=a\n" + strings.Repeat("KLM\n", 20) + "=yend size=60 crc32=00000000\n"
+	var lineLength int
+	var summary string
+	for _, f := range Lint(strings.NewReader(article)) {
+		if f.Check == LintLineLength {
+			lineLength++
+			summary = f.Message
+		}
+	}
+	if lineLength != LintMaxPerCheck+1 || summary != "10 more line-length findings" {
+		t.Errorf("expect %d line-length findings and a summary but got %d ending with %q", LintMaxPerCheck, lineLength, summary)
+	}
+}
@@ -0,0 +1,188 @@
+package yenc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+)
+
+// A file found in an article by an Extractor. Its data is read through the embedded reader, and only until the next
+// call to Extractor.Next.
+type Attachment struct {
+	io.Reader
+	Name        string               // Filename of Content-Disposition, name of Content-Type, or of the embedded binary
+	ContentType string               // Media type, text/plain if not given
+	Encoding    string               // Content-Transfer-Encoding decoded, base64 or quoted-printable, or empty
+	Binary      AnyDecoder           // Decoder of the embedded yEnc, uuencode or xxencode data the Reader reads, if any
+	Header      textproto.MIMEHeader // Headers of the MIME part, or of the article if it is not multipart
+}
+
+// Walks the MIME tree of an article, multipart/mixed or any other multipart type nested to any depth, and yields the
+// files it holds: parts with a file name or a media type other than text, decoded from base64 or quoted-printable,
+// and every yEnc, uuencode or xxencode binary embedded in text parts, decoded with DecodeAny. Other text parts are
+// skipped. An article that is not MIME, such as a plain yEnc post, is a single text part.
+type Extractor struct {
+	header textproto.MIMEHeader
+	parts  []*multipart.Reader // Multipart bodies being walked, innermost last
+	body   io.Reader           // Body of an article that is not multipart, until yielded
+	text   *bufio.Reader       // Text part being searched for binaries
+	block  *binaryBlock        // Lines of the last binary found in text
+	part   Attachment          // Header, content type and encoding of text
+}
+
+// Read the headers of an article, then return an Extractor to walk its body.
+func NewExtractor(article io.Reader) (x *Extractor, err error) {
+	tr := textproto.NewReader(bufio.NewReader(article))
+	var h textproto.MIMEHeader
+	if h, err = tr.ReadMIMEHeader(); err != nil && !(err == io.EOF && len(h) > 0) {
+		err = fmt.Errorf("[yEnc] failed to read article headers: %v: %w", err, ErrInvalidFormat)
+		return
+	}
+	err = nil
+	x = &Extractor{header: h}
+	if boundary, ok := multipartBoundary(h); ok {
+		x.parts = []*multipart.Reader{multipart.NewReader(tr.R, boundary)}
+	} else {
+		x.body = tr.R
+	}
+	return
+}
+
+// Headers of the article.
+func (x *Extractor) Header() textproto.MIMEHeader {
+	return x.header
+}
+
+// Next attachment, or io.EOF after the last. The data of the previous attachment is skipped.
+func (x *Extractor) Next() (a *Attachment, err error) {
+	if x.text != nil {
+		if a, err = x.binary(); a != nil || err != nil {
+			return
+		}
+	}
+	if x.body != nil {
+		body := x.body
+		x.body = nil
+		if a, err = x.attachment(x.header, body); a != nil || err != nil {
+			return
+		}
+	}
+	for len(x.parts) > 0 {
+		var p *multipart.Part
+		if p, err = x.parts[len(x.parts)-1].NextRawPart(); err == io.EOF {
+			x.parts = x.parts[:len(x.parts)-1]
+			continue
+		} else if err != nil {
+			err = fmt.Errorf("[yEnc] failed to read MIME part: %v: %w", err, ErrInvalidFormat)
+			return
+		}
+		if boundary, ok := multipartBoundary(p.Header); ok {
+			x.parts = append(x.parts, multipart.NewReader(p, boundary))
+			continue
+		}
+		if a, err = x.attachment(p.Header, p); a != nil || err != nil {
+			return
+		}
+	}
+	err = io.EOF
+	return
+}
+
+func multipartBoundary(h textproto.MIMEHeader) (boundary string, ok bool) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return
+	}
+	boundary, ok = params["boundary"], params["boundary"] != ""
+	return
+}
+
+// Attachment of a part that is not multipart, or the first binary in it if it is text, nil if it has none.
+func (x *Extractor) attachment(h textproto.MIMEHeader, body io.Reader) (a *Attachment, err error) {
+	a = &Attachment{Reader: body, ContentType: "text/plain", Header: h}
+	if mediaType, params, perr := mime.ParseMediaType(h.Get("Content-Type")); perr == nil {
+		a.ContentType = mediaType
+		a.Name = params["name"]
+	}
+	if _, params, perr := mime.ParseMediaType(h.Get("Content-Disposition")); perr == nil && params["filename"] != "" {
+		a.Name = params["filename"]
+	}
+	a.Name = decodeName(a.Name, DefaultCharsets)
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		a.Reader, a.Encoding = base64.NewDecoder(base64.StdEncoding, a.Reader), "base64"
+	case "quoted-printable":
+		a.Reader, a.Encoding = quotedprintable.NewReader(a.Reader), "quoted-printable"
+	}
+	if a.Name != "" || !strings.HasPrefix(a.ContentType, "text/") {
+		return
+	}
+	x.text, x.block, x.part = bufio.NewReader(a.Reader), nil, *a
+	return x.binary()
+}
+
+// Next binary in the text part, or nil once there is none left.
+func (x *Extractor) binary() (a *Attachment, err error) {
+	if x.block != nil {
+		// skip the rest of the previous binary
+		if _, err = io.Copy(io.Discard, x.block); err != nil {
+			x.text = nil
+			return
+		}
+	}
+	x.block = &binaryBlock{r: x.text}
+	var d AnyDecoder
+	if d, err = DecodeAny(x.block); errors.Is(err, ErrNoBinary) {
+		x.text, err = nil, nil
+		return
+	} else if err != nil {
+		x.text = nil
+		return
+	}
+	a = &Attachment{}
+	*a = x.part
+	a.Reader, a.Binary, a.Name = d, d, d.FileName()
+	return
+}
+
+// Reads the lines of a text part up to the last line of the first binary in it, =yend or end, so that the decoder does
+// not read ahead into what follows.
+type binaryBlock struct {
+	r    *bufio.Reader
+	end  func(line []byte) bool // Whether a line is the last of the binary, once its first line was read
+	line []byte                 // Rest of the line being read
+	done bool
+}
+
+func (b *binaryBlock) Read(p []byte) (n int, err error) {
+	for len(b.line) == 0 {
+		if b.done {
+			return 0, io.EOF
+		}
+		if b.line, err = b.r.ReadBytes('\n'); err == io.EOF {
+			b.done, err = true, nil
+		} else if err != nil {
+			return
+		}
+		text := bytes.TrimRight(b.line, "\r\n")
+		switch {
+		case b.end != nil:
+			b.done = b.done || b.end(text)
+		case bytes.HasPrefix(text, ybegin):
+			b.end = func(line []byte) bool { return bytes.HasPrefix(line, yend) }
+		case isUUBegin(string(text)):
+			b.end = func(line []byte) bool { return string(line) == "end" }
+		}
+	}
+	n = copy(p, b.line)
+	b.line = b.line[n:]
+	return
+}
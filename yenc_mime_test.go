@@ -0,0 +1,177 @@
+package yenc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"testing"
+
+	"gopkg.in/yenc.v0/uu"
+)
+
+func TestExtractor(t *testing.T) {
+	ntx, err := os.ReadFile("fixture/yEncBinPoster-001.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile("fixture/yEncBinPoster-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	w, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain"}})
+	w.Write([]byte("See the attachments.\r\n"))
+	w, _ = mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=us-ascii"}})
+	w.Write(ntx)
+	// nested multipart with a base64 and a quoted-printable attachment
+	var nested bytes.Buffer
+	nw := multipart.NewWriter(&nested)
+	w, _ = nw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/octet-stream"},
+		"Content-Disposition":       {`attachment; filename="=?UTF-8?Q?caf=C3=A9.bin?="`},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	enc.Write(raw)
+	enc.Close()
+	w, _ = nw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; name=notes.txt"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte("caf\xc3\xa9 = coffee\n"))
+	qp.Close()
+	nw.Close()
+	w, _ = mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"multipart/mixed; boundary=" + nw.Boundary()}})
+	w.Write(nested.Bytes())
+	mw.Close()
+	article := "From: poster@example.com\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=" + mw.Boundary() + "\r\n\r\n" + body.String()
+
+	x, err := NewExtractor(bytes.NewReader([]byte(article)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []struct {
+		name     string
+		encoding string
+		binary   bool
+		data     []byte
+	}{
+		{"yEncBinPoster-raw.bin", "", true, raw},
+		{"café.bin", "base64", false, raw},
+		{"notes.txt", "quoted-printable", false, []byte("caf\xc3\xa9 = coffee\r\n")},
+	}
+	for i := 0; ; i++ {
+		a, err := x.Next()
+		if err == io.EOF {
+			if i != len(expect) {
+				t.Errorf("expect %d attachments but got %d", len(expect), i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(expect) {
+			t.Fatalf("unexpected attachment %s", a.Name)
+		}
+		data, err := io.ReadAll(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := expect[i]
+		if a.Name != e.name || a.Encoding != e.encoding || (a.Binary != nil) != e.binary || !bytes.Equal(data, e.data) {
+			t.Errorf("expect attachment %d to be %s (%s) but got %s (%s) of %d bytes", i, e.name, e.encoding, a.Name, a.Encoding, len(data))
+		}
+	}
+}
+
+func TestExtractorPlain(t *testing.T) {
+	ntx, err := os.ReadFile("fixture/yenc32-010.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := NewExtractor(bytes.NewReader(append([]byte("Subject: yenc32-raw.bin (10/10)\r\n\r\n"), ntx...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := x.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(a); a.Binary == nil || a.Binary.Format() != "yEnc" || a.Name != "yenc32-raw.bin" || len(data) != 74 {
+		t.Errorf("unexpected attachment %s of %d bytes", a.Name, len(data))
+	}
+	if x.Header().Get("Subject") == "" {
+		t.Error("expect the article headers")
+	}
+	if _, err = x.Next(); err != io.EOF {
+		t.Errorf("expect a single attachment but got %v", err)
+	}
+}
+
+// Every binary in a text part is yielded, with the text around and between them skipped.
+func TestExtractorTextBinaries(t *testing.T) {
+	ntx, err := os.ReadFile("fixture/yenc32-010.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	body.WriteString("Subject: three files\r\n\r\nHere they are:\r\n")
+	body.Write(ntx)
+	body.WriteString("and the notes, uuencoded\r\nend\r\n")
+	ue, err := uu.Encode(&body, "notes.txt", 0644, uu.EncodeWithEOL("\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ue.Write([]byte("hello\n"))
+	if err = ue.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ye, err := Encode(&body, "abc.bin", 3, EncodeWithEOL("\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ye.Write([]byte("abc"))
+	if err = ye.Close(); err != nil {
+		t.Fatal(err)
+	}
+	body.WriteString("-- \r\nsignature\r\n")
+
+	x, err := NewExtractor(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []struct {
+		name   string
+		format string
+		size   int // -1 to skip the data without reading it
+	}{
+		{"yenc32-raw.bin", "yEnc", 74},
+		{"notes.txt", "uuencode", -1},
+		{"abc.bin", "yEnc", 3},
+	}
+	for _, e := range expect {
+		a, err := x.Next()
+		if err != nil {
+			t.Fatalf("expect %s but got %v", e.name, err)
+		}
+		if a.Name != e.name || a.Binary == nil || a.Binary.Format() != e.format {
+			t.Errorf("expect %s (%s) but got %s", e.name, e.format, a.Name)
+		}
+		if e.size < 0 {
+			continue
+		}
+		if data, err := io.ReadAll(a); err != nil || len(data) != e.size {
+			t.Errorf("expect %d bytes of %s but got %d: %v", e.size, e.name, len(data), err)
+		}
+	}
+	if _, err = x.Next(); err != io.EOF {
+		t.Errorf("expect no more attachments but got %v", err)
+	}
+}
@@ -0,0 +1,367 @@
+package yenc
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"gopkg.in/option.v0"
+)
+
+// Random access to a multipart file whose parts are fetched lazily from a SegmentSource, e.g. to stream media or to
+// inspect an archive without downloading all of it. Offsets are mapped to parts by their =ypart begin and end values,
+// decoded parts are kept in a cache bounded in bytes, and the following parts are fetched ahead when reading
+// sequentially.
+//
+// ReadAt is safe for concurrent use, Read and Seek share the offset of the file. Close stops the parts being fetched
+// ahead.
+type MultipartFile struct {
+	src       SegmentSource
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	cacheSize int64
+	readahead int
+
+	mu       sync.Mutex
+	h        Header
+	ranges   []Range // Range of each part, zero until the part has been fetched
+	partSize uint64  // Size of the first part, to estimate the part of an offset
+	lru      *list.List
+	cached   map[int]*list.Element
+	size     int64 // Bytes cached
+	fetching map[int]*segmentFetch
+	last     int // Last part read, to detect sequential access
+	offset   int64
+}
+
+type cachedSegment struct {
+	i    int
+	data []byte
+}
+
+type segmentFetch struct {
+	done     chan struct{}
+	data     []byte
+	err      error
+	canceled bool // Canceled by the context of the read that started it
+}
+
+// Fetch the first part of the file from src to find its name and size. Parts are fetched with ctx, which can cancel
+// every fetch of the MultipartFile.
+func NewMultipartFile(ctx context.Context, src SegmentSource, options ...MultipartOption) (f *MultipartFile, err error) {
+	f = option.New(options,
+		MultipartWithCacheSize(DefaultCacheSize),
+		MultipartWithReadahead(DefaultReadahead))
+	f.src = src
+	f.ctx, f.cancel = context.WithCancel(ctx)
+	f.ranges = make([]Range, src.Count())
+	f.lru = list.New()
+	f.cached = make(map[int]*list.Element)
+	f.fetching = make(map[int]*segmentFetch)
+	f.last = -1
+	if len(f.ranges) == 0 {
+		f.cancel()
+		f = nil
+		err = fmt.Errorf("[yEnc] no part to read: %w", ErrInvalidFormat)
+		return
+	}
+	if _, err = f.segment(f.ctx, 0); err != nil {
+		f.cancel()
+		f = nil
+	}
+	return
+}
+
+// File name, size and total number of parts, as given by the first part.
+func (f *MultipartFile) Header() *Header {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h := f.h
+	return &h
+}
+
+// Size of the decoded file.
+func (f *MultipartFile) Size() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(f.h.Size)
+}
+
+func (f *MultipartFile) ReadAt(b []byte, off int64) (n int, err error) {
+	return f.ReadAtContext(context.Background(), b, off)
+}
+
+// ReadAt with the parts that are not cached fetched with ctx, in addition to the context of the file, so that
+// canceling ctx aborts this read only, e.g. when the client of an HTTP request goes away. A fetch shared with other
+// reads is started again for them if ctx cancels it. Parts fetched ahead are only bound to the context of the file.
+func (f *MultipartFile) ReadAtContext(ctx context.Context, b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		err = fmt.Errorf("[yEnc] read at negative offset %d: %w", off, ErrInvalidOffset)
+		return
+	}
+	size := f.Size()
+	for n < len(b) && off < size {
+		var (
+			i    int
+			data []byte
+		)
+		if i, data, err = f.locate(ctx, off); err != nil {
+			return
+		}
+		f.prefetch(i)
+		m := copy(b[n:], data[uint64(off)-f.rangeOf(i).Begin:])
+		n += m
+		off += int64(m)
+	}
+	if n < len(b) {
+		err = io.EOF
+	}
+	return
+}
+
+func (f *MultipartFile) Read(b []byte) (n int, err error) {
+	f.mu.Lock()
+	off := f.offset
+	f.mu.Unlock()
+	n, err = f.ReadAt(b, off)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	f.mu.Lock()
+	f.offset = off + int64(n)
+	f.mu.Unlock()
+	return
+}
+
+func (f *MultipartFile) Seek(offset int64, whence int) (abs int64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = f.offset + offset
+	case io.SeekEnd:
+		abs = int64(f.h.Size) + offset
+	default:
+		err = fmt.Errorf("[yEnc] invalid whence %d: %w", whence, ErrInvalidOffset)
+		return
+	}
+	if abs < 0 {
+		err = fmt.Errorf("[yEnc] seek to negative offset %d: %w", abs, ErrInvalidOffset)
+		abs = 0
+		return
+	}
+	f.offset = abs
+	return
+}
+
+// Cancel the parts being fetched ahead and wait for them. Reading after Close fails for parts not cached.
+func (f *MultipartFile) Close() error {
+	f.cancel()
+	f.wg.Wait()
+	return nil
+}
+
+func (f *MultipartFile) rangeOf(i int) Range {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ranges[i]
+}
+
+// Find the part holding the byte at off, starting from an estimate assuming parts of equal size and moving one part
+// at a time, fetching the parts whose range is not known yet.
+func (f *MultipartFile) locate(ctx context.Context, off int64) (i int, data []byte, err error) {
+	f.mu.Lock()
+	i = int(uint64(off) / f.partSize)
+	if i >= len(f.ranges) {
+		i = len(f.ranges) - 1
+	}
+	f.mu.Unlock()
+	direction := 0
+	for {
+		if data, err = f.segment(ctx, i); err != nil {
+			return
+		}
+		r := f.rangeOf(i)
+		step := 0
+		if uint64(off) < r.Begin {
+			step = -1
+		} else if uint64(off) >= r.End {
+			step = 1
+		} else {
+			return
+		}
+		if (direction != 0 && step != direction) || i+step < 0 || i+step >= len(f.ranges) {
+			err = fmt.Errorf("[yEnc] no part holds offset %d: %w", off, ErrDataCorruption)
+			return
+		}
+		direction = step
+		i += step
+	}
+}
+
+// Decoded data of part i, from the cache or fetched with ctx, waiting for a fetch in progress until ctx is done.
+func (f *MultipartFile) segment(ctx context.Context, i int) (data []byte, err error) {
+	for {
+		f.mu.Lock()
+		if e, ok := f.cached[i]; ok {
+			f.lru.MoveToFront(e)
+			data = e.Value.(*cachedSegment).data
+			f.mu.Unlock()
+			return
+		}
+		fetch, ok := f.fetching[i]
+		if !ok {
+			fetch = f.startFetch(i)
+		}
+		f.mu.Unlock()
+		if !ok {
+			fetchCtx, cancel := f.bind(ctx)
+			f.fetch(fetchCtx, i, fetch)
+			cancel()
+		}
+		select {
+		case <-fetch.done:
+		case <-ctx.Done():
+			err = fmt.Errorf("[yEnc] failed to fetch part %d: %w", i+1, ctx.Err())
+			return
+		}
+		// canceled by another read, not by the file
+		if fetch.canceled && ctx.Err() == nil && f.ctx.Err() == nil {
+			continue
+		}
+		data, err = fetch.data, fetch.err
+		return
+	}
+}
+
+// Context done when either ctx or the context of the file is.
+func (f *MultipartFile) bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if f.ctx.Err() != nil {
+		cancel()
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-f.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Register a fetch of part i. Must be called with mu held.
+func (f *MultipartFile) startFetch(i int) (fetch *segmentFetch) {
+	fetch = &segmentFetch{done: make(chan struct{})}
+	f.fetching[i] = fetch
+	return
+}
+
+func (f *MultipartFile) fetch(ctx context.Context, i int, fetch *segmentFetch) {
+	s, err := f.src.Fetch(ctx, i)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		err = f.check(i, s)
+	}
+	if err != nil {
+		fetch.err = fmt.Errorf("[yEnc] failed to fetch part %d: %w", i+1, err)
+		fetch.canceled = ctx.Err() != nil
+	} else {
+		fetch.data = s.Data
+		f.cache(i, s.Data)
+	}
+	delete(f.fetching, i)
+	close(fetch.done)
+}
+
+// Check that a part belongs to the file and record its range. Must be called with mu held.
+func (f *MultipartFile) check(i int, s *Segment) (err error) {
+	h := &s.Header
+	r := Range{h.Begin, h.End}
+	if h.Part == 0 {
+		r = Range{0, h.Size}
+	}
+	if f.partSize == 0 {
+		f.h = Header{Name: h.Name, RawName: h.RawName, Size: h.Size, Total: h.Total}
+		f.partSize = r.End - r.Begin
+		if f.partSize == 0 {
+			f.partSize = 1
+		}
+	} else if h.Name != f.h.Name || h.Size != f.h.Size {
+		err = fmt.Errorf("[yEnc] part of %s (%d bytes) does not belong to %s (%d bytes): %w", h.Name, h.Size, f.h.Name, f.h.Size, ErrPartMismatch)
+		return
+	}
+	if uint64(len(s.Data)) != r.End-r.Begin {
+		err = fmt.Errorf("[yEnc] part %d has %d bytes but decoded %d bytes: %w", h.Part, r.End-r.Begin, len(s.Data), ErrDataCorruption)
+		return
+	}
+	f.ranges[i] = r
+	return
+}
+
+// Add a part to the cache, evicting the least recently used parts beyond the cache size. The part added is kept even
+// if larger than the cache. Must be called with mu held.
+func (f *MultipartFile) cache(i int, data []byte) {
+	f.cached[i] = f.lru.PushFront(&cachedSegment{i: i, data: data})
+	f.size += int64(len(data))
+	for f.size > f.cacheSize && f.lru.Len() > 1 {
+		c := f.lru.Remove(f.lru.Back()).(*cachedSegment)
+		delete(f.cached, c.i)
+		f.size -= int64(len(c.data))
+	}
+}
+
+// Fetch the parts following part i in the background if it is read sequentially.
+func (f *MultipartFile) prefetch(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sequential := i == f.last || i == f.last+1
+	f.last = i
+	if !sequential || f.ctx.Err() != nil {
+		return
+	}
+	for j := i + 1; j <= i+f.readahead && j < len(f.ranges); j++ {
+		if _, ok := f.cached[j]; ok {
+			continue
+		}
+		if _, ok := f.fetching[j]; ok {
+			continue
+		}
+		fetch := f.startFetch(j)
+		f.wg.Add(1)
+		go func(j int) {
+			defer f.wg.Done()
+			f.fetch(f.ctx, j, fetch)
+		}(j)
+	}
+}
+
+// Default max number of bytes of decoded parts cached by a MultipartFile.
+var DefaultCacheSize int64 = 32 << 20
+
+// Default number of parts fetched ahead of sequential reads.
+var DefaultReadahead = 4
+
+type MultipartOption func(*MultipartFile)
+
+// Cache up to size bytes of decoded parts. At least the last part read is always cached. The cache should hold the
+// parts fetched ahead and the part being read, or they may be evicted before being read.
+func MultipartWithCacheSize(size int64) MultipartOption {
+	return func(f *MultipartFile) {
+		f.cacheSize = size
+	}
+}
+
+// Fetch up to n parts ahead of sequential reads. Zero disables readahead.
+func MultipartWithReadahead(n int) MultipartOption {
+	return func(f *MultipartFile) {
+		f.readahead = n
+	}
+}
@@ -0,0 +1,203 @@
+package yenc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+)
+
+func fixtureParts(prefix string, count int) (paths []string) {
+	for i := 1; i <= count; i++ {
+		paths = append(paths, fmt.Sprintf("fixture/%s-%03d.ntx", prefix, i))
+	}
+	return
+}
+
+// Counts the fetches of each part.
+type countingSource struct {
+	SegmentSource
+	mu      sync.Mutex
+	fetches map[int]int
+}
+
+func (c *countingSource) Fetch(ctx context.Context, i int) (*Segment, error) {
+	c.mu.Lock()
+	c.fetches[i]++
+	c.mu.Unlock()
+	return c.SegmentSource.Fetch(ctx, i)
+}
+
+func (c *countingSource) total() (n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, count := range c.fetches {
+		n += count
+	}
+	return
+}
+
+func TestMultipartFile(t *testing.T) {
+	raw, err := os.ReadFile("fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &countingSource{SegmentSource: FileSource(fixtureParts("ngPost", 10)), fetches: make(map[int]int)}
+	f, err := NewMultipartFile(context.Background(), src, MultipartWithReadahead(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Size() != int64(len(raw)) || f.Header().Name != "ngPost-raw.bin" || src.total() != 1 {
+		t.Fatalf("unexpected header %+v after %d fetches", f.Header(), src.total())
+	}
+	// reads within and across parts, at random offsets
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		off := rnd.Int63n(int64(len(raw)))
+		b := make([]byte, rnd.Intn(1500))
+		n, err := f.ReadAt(b, off)
+		if expect := int64(len(raw)) - off; int64(len(b)) > expect {
+			if n != int(expect) || err != io.EOF {
+				t.Fatalf("expect %d bytes and io.EOF at %d but got %d bytes and %v", expect, off, n, err)
+			}
+		} else if n != len(b) || err != nil {
+			t.Fatalf("expect %d bytes at %d but got %d bytes and %v", len(b), off, n, err)
+		}
+		if !bytes.Equal(b[:n], raw[off:off+int64(n)]) {
+			t.Fatalf("data mismatch at %d", off)
+		}
+	}
+	for i, count := range src.fetches {
+		if count != 1 {
+			t.Errorf("expect part %d to be fetched once with the default cache but got %d fetches", i+1, count)
+		}
+	}
+	// Seek and Read
+	if _, err = f.Seek(-100, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(f); err != nil || !bytes.Equal(b, raw[len(raw)-100:]) {
+		t.Errorf("unexpected tail read %d bytes: %v", len(b), err)
+	}
+	if _, err = f.Seek(-1, io.SeekStart); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("expect ErrInvalidOffset but got %v", err)
+	}
+}
+
+func TestMultipartFileCacheAndReadahead(t *testing.T) {
+	raw, err := os.ReadFile("fixture/yenc32-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts [][]byte
+	for _, path := range fixtureParts("yenc32", 10) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, b)
+	}
+	src := &countingSource{SegmentSource: MemorySource(parts), fetches: make(map[int]int)}
+	f, err := NewMultipartFile(context.Background(), src, MultipartWithCacheSize(1536), MultipartWithReadahead(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, raw) {
+		t.Error("sequential read mismatch")
+	}
+	f.Close()
+	if src.total() != 10 {
+		t.Errorf("expect each part to be fetched once by readahead but got %v", src.fetches)
+	}
+	// only three parts of 512 bytes (or less) fit in the cache
+	f.mu.Lock()
+	if f.lru.Len() > 3 || f.size > 1536 {
+		t.Errorf("expect the cache to be bounded but has %d parts of %d bytes", f.lru.Len(), f.size)
+	}
+	f.mu.Unlock()
+	if _, err = f.ReadAt(make([]byte, 10), 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("expect reading an evicted part after Close to fail but got %v", err)
+	}
+}
+
+func TestMultipartFileMismatch(t *testing.T) {
+	src := FileSource([]string{"fixture/ngPost-001.ntx", "fixture/yenc32-002.ntx"})
+	f, err := NewMultipartFile(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.ReadAt(make([]byte, 10), 600); !errors.Is(err, ErrPartMismatch) {
+		t.Errorf("expect ErrPartMismatch but got %v", err)
+	}
+}
+
+// Blocks the fetches of part 1 until unblocked or canceled.
+type blockingSource struct {
+	SegmentSource
+	started chan struct{}
+	block   chan struct{}
+}
+
+func (b *blockingSource) Fetch(ctx context.Context, i int) (*Segment, error) {
+	if i == 1 {
+		b.started <- struct{}{}
+		select {
+		case <-b.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return b.SegmentSource.Fetch(ctx, i)
+}
+
+func TestMultipartFileReadAtContext(t *testing.T) {
+	raw, err := os.ReadFile("fixture/ngPost-raw.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &blockingSource{SegmentSource: FileSource(fixtureParts("ngPost", 10)),
+		started: make(chan struct{}, 2), block: make(chan struct{})}
+	f, err := NewMultipartFile(context.Background(), src, MultipartWithReadahead(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	off := int64(f.partSize)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		_, err := f.ReadAtContext(ctx, make([]byte, 10), off)
+		canceled <- err
+	}()
+	<-src.started
+	b := make([]byte, 10)
+	read := make(chan error)
+	go func() {
+		_, err := f.ReadAtContext(context.Background(), b, off)
+		read <- err
+	}()
+	cancel()
+	if err = <-canceled; !errors.Is(err, context.Canceled) {
+		t.Errorf("expect the canceled read to fail with context.Canceled but got %v", err)
+	}
+	// the other read fetches the part again instead of failing with the canceled fetch
+	close(src.block)
+	if err = <-read; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, raw[off:off+10]) {
+		t.Error("read mismatch after a canceled fetch")
+	}
+}
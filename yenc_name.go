@@ -0,0 +1,144 @@
+package yenc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/option.v0"
+)
+
+// File name safe to create in an output directory, as rewritten by SanitizeName. Header.Name is taken from the
+// article as is and must not be used as a path.
+func (h *Header) SafeName() string {
+	name, _ := SanitizeName(h.Name)
+	return name
+}
+
+// Rewrite a file name given by an article into a base name that is safe to create on common filesystems:
+//
+//   - Directories are stripped, with both / and \ as separators, and so are Windows drive letters.
+//   - Control characters, invalid UTF-8 and characters reserved on Windows (<>:"|?*) are replaced.
+//   - Leading dots and spaces, and trailing dots and spaces, are trimmed, so that the name is neither hidden, "." nor
+//     "..".
+//   - Windows reserved device names such as CON, NUL or COM1 (with or without extension) are prefixed.
+//   - The name is shortened to MaxNameLength bytes, keeping its extension.
+//
+// A name left empty becomes UnnamedFile. With SanitizeWithReject, a name that would be rewritten is rejected with
+// ErrUnsafeName instead.
+func SanitizeName(name string, options ...SanitizeOption) (safe string, err error) {
+	s := option.New(options, SanitizeWithReplacement('_'))
+	if !isSafeReplacement(s.replacement) {
+		err = fmt.Errorf("[yEnc] replacement character %q: %w", s.replacement, ErrInvalidReplacement)
+		return
+	}
+	safe = name
+	// directories and drive letters
+	if i := strings.LastIndexAny(safe, `/\`); i >= 0 {
+		safe = safe[i+1:]
+	}
+	if len(safe) >= 2 && safe[1] == ':' && isASCIILetter(safe[0]) {
+		safe = safe[2:]
+	}
+	// characters
+	var b strings.Builder
+	for i, r := range safe {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(safe[i:]); size <= 1 {
+				b.WriteRune(s.replacement)
+				continue
+			}
+		}
+		if isUnsafeRune(r) {
+			b.WriteRune(s.replacement)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	safe = strings.TrimRight(strings.TrimLeft(b.String(), ". "), ". ")
+	if isReservedName(safe) {
+		safe = string(s.replacement) + safe
+	}
+	safe = truncateName(safe, MaxNameLength)
+	if safe == "" {
+		safe = UnnamedFile
+	}
+	if s.reject && safe != name {
+		err = fmt.Errorf("[yEnc] unsafe file name %#v: %w", name, ErrUnsafeName)
+		safe = ""
+	}
+	return
+}
+
+// Control characters and characters reserved on Windows.
+func isUnsafeRune(r rune) bool {
+	return r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0) || strings.ContainsRune(`<>:"|?*`, r)
+}
+
+// A replacement must not itself be replaced, nor separate directories or make a name hidden, "." or "..".
+func isSafeReplacement(r rune) bool {
+	return utf8.ValidRune(r) && r != utf8.RuneError && !isUnsafeRune(r) && !strings.ContainsRune(`/\. `, r)
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Device names reserved on Windows, whatever the extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+func isReservedName(name string) bool {
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	return reservedNames[strings.ToUpper(strings.TrimRight(name, " "))]
+}
+
+// Shorten a name to max bytes on a character boundary, keeping its extension if it is short enough.
+func truncateName(name string, max int) string {
+	if len(name) <= max {
+		return name
+	}
+	ext := path.Ext(name)
+	if len(ext) > max/2 {
+		ext = ""
+	}
+	base := name[:max-len(ext)]
+	for !utf8.ValidString(base) {
+		base = base[:len(base)-1]
+	}
+	return base + ext
+}
+
+// Max length in bytes of a sanitized name, the limit of most filesystems.
+var MaxNameLength = 255
+
+// Name given to files whose name is empty once sanitized.
+var UnnamedFile = "unnamed"
+
+type sanitizer struct {
+	replacement rune
+	reject      bool
+}
+
+type SanitizeOption func(*sanitizer)
+
+// Replace unsafe characters with r instead of '_'. SanitizeName fails with ErrInvalidReplacement if r is itself unsafe:
+// a control character, a character reserved on Windows, a directory separator, a dot or a space.
+func SanitizeWithReplacement(r rune) SanitizeOption {
+	return func(s *sanitizer) {
+		s.replacement = r
+	}
+}
+
+// Reject names that would be rewritten with ErrUnsafeName instead of rewriting them.
+func SanitizeWithReject() SanitizeOption {
+	return func(s *sanitizer) {
+		s.reject = true
+	}
+}
@@ -0,0 +1,61 @@
+package yenc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeName(t *testing.T) {
+	for _, c := range []struct {
+		name, expect string
+	}{
+		{"file.bin", "file.bin"},
+		{"Überweisung März.pdf", "Überweisung März.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{"/etc/passwd", "passwd"},
+		{`..\..\Windows\system.ini`, "system.ini"},
+		{`C:\Windows\win.ini`, "win.ini"},
+		{"C:win.ini", "win.ini"},
+		{"..", UnnamedFile},
+		{"", UnnamedFile},
+		{"dir/", UnnamedFile},
+		{".bashrc", "bashrc"},
+		{"name. ", "name"},
+		{"CON", "_CON"},
+		{"con.txt", "_con.txt"},
+		{"Com1.tar.gz", "_Com1.tar.gz"},
+		{"console.txt", "console.txt"},
+		{"nul\x00.bin", "nul_.bin"},
+		{"a\tb\r\nc.bin", "a_b__c.bin"},
+		{`what?<>|*".bin`, "what______.bin"},
+		{"latin1 \xe9t\xe9.txt", "latin1 _t_.txt"},
+		{strings.Repeat("é", 200) + ".mkv", strings.Repeat("é", 125) + ".mkv"},
+	} {
+		safe, err := SanitizeName(c.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if safe != c.expect {
+			t.Errorf("expect %#v to be sanitized as %#v but got %#v", c.name, c.expect, safe)
+		}
+		if h := (Header{Name: c.name}); h.SafeName() != c.expect {
+			t.Errorf("expect SafeName of %#v to be %#v but got %#v", c.name, c.expect, h.SafeName())
+		}
+	}
+	if safe, err := SanitizeName("file.bin", SanitizeWithReject()); err != nil || safe != "file.bin" {
+		t.Errorf("expect a safe name to be accepted but got %#v: %v", safe, err)
+	}
+	if _, err := SanitizeName("../file.bin", SanitizeWithReject()); !errors.Is(err, ErrUnsafeName) {
+		t.Errorf("expect ErrUnsafeName but got %v", err)
+	}
+	if safe, _ := SanitizeName("a?b", SanitizeWithReplacement('-')); safe != "a-b" {
+		t.Errorf("expect the replacement character to be used but got %#v", safe)
+	}
+	for _, r := range []rune{'/', '\\', '.', ' ', '\x00', '\n', '?', utf8.RuneError} {
+		if safe, err := SanitizeName("a?b", SanitizeWithReplacement(r)); !errors.Is(err, ErrInvalidReplacement) {
+			t.Errorf("expect ErrInvalidReplacement for %q but got %#v: %v", r, safe, err)
+		}
+	}
+}
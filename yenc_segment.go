@@ -0,0 +1,93 @@
+package yenc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A decoded part of a file.
+type Segment struct {
+	Header  Header
+	Trailer Trailer
+	Data    []byte // Decoded data, verified against the size and CRC32 values in the trailer
+}
+
+// Fetches the parts of a multipart file by index, e.g. from NNTP servers (see nntp.Pool.Source), local .ntx files or
+// memory. Index i is the (i+1)th part. Implementations must be safe for concurrent use.
+type SegmentSource interface {
+	// Number of parts of the file.
+	Count() int
+	// Fetch and decode the part at index i.
+	Fetch(ctx context.Context, i int) (*Segment, error)
+}
+
+// Decode a whole part, requiring the =yend line so that its size and CRC32 are verified.
+func DecodeSegment(r io.Reader, options ...DecodeOption) (s *Segment, err error) {
+	var d *Decoder
+	if d, err = Decode(r, options...); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = io.ReadAll(d); err != nil {
+		return
+	}
+	if d.Trailer() == nil {
+		err = fmt.Errorf("[yEnc] part has no =yend line: %w", ErrInvalidFormat)
+		return
+	}
+	s = &Segment{Header: *d.Header(), Trailer: *d.Trailer(), Data: data}
+	return
+}
+
+// Source of the parts given as yEnc encoded article bodies in memory, in order.
+func MemorySource(parts [][]byte, options ...DecodeOption) SegmentSource {
+	return &memorySource{parts: parts, options: options}
+}
+
+type memorySource struct {
+	parts   [][]byte
+	options []DecodeOption
+}
+
+func (m *memorySource) Count() int {
+	return len(m.parts)
+}
+
+func (m *memorySource) Fetch(ctx context.Context, i int) (s *Segment, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return DecodeSegment(bytes.NewReader(m.parts[i]), m.options...)
+}
+
+// Source of the parts stored as files holding one article body each (e.g. .ntx files), in order.
+func FileSource(paths []string, options ...DecodeOption) SegmentSource {
+	return &fileSource{paths: paths, options: options}
+}
+
+type fileSource struct {
+	paths   []string
+	options []DecodeOption
+}
+
+func (f *fileSource) Count() int {
+	return len(f.paths)
+}
+
+func (f *fileSource) Fetch(ctx context.Context, i int) (s *Segment, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	var file *os.File
+	if file, err = os.Open(f.paths[i]); err != nil {
+		return
+	}
+	defer file.Close()
+	if s, err = DecodeSegment(file, f.options...); err != nil {
+		err = fmt.Errorf("[yEnc] failed to decode %s: %w", f.paths[i], err)
+	}
+	return
+}
@@ -0,0 +1,163 @@
+package yenc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The information carried by the subject line of a binary post. The yEnc 1.3 recommendation is
+//
+//	[Comment1] "filename" yEnc (partnum/numparts) [size] [Comment2]
+//
+// while posting tools add their own file counters and separators, e.g. `[01/10] - "file.rar" yEnc (3/120)`.
+type Subject struct {
+	Prefix    string // Comment before the file counter and name, e.g. a collection title
+	Name      string // File name, without quotes
+	FileIndex uint64 // Index of the file in the post set (starts from 1). Zero if not present.
+	FileCount uint64 // Number of files in the post set. Zero if not present.
+	Part      uint64 // Part number. Some posters number from 0 for a description part.
+	Total     uint64 // Total number of parts. Zero if the subject has no part counter.
+	YEnc      bool   // The "yEnc" marker is present
+	Size      uint64 // File size following the part counter. Zero if not present.
+	Suffix    string // Comment after the part counter and size
+}
+
+var (
+	subjectCounter  = regexp.MustCompile(`[(\[]\s*(\d+)\s*/\s*(\d+)\s*[)\]]`)
+	subjectFileOf   = regexp.MustCompile(`(?i)\bfile\s+(\d+)\s+of\s+(\d+)\b`)
+	subjectYEnc     = regexp.MustCompile(`(?i)\byEnc\b`)
+	subjectQuoted   = regexp.MustCompile(`"([^"]+)"`)
+	subjectSize     = regexp.MustCompile(`^\s*(\d+)(\s|$)`)
+	subjectFileName = regexp.MustCompile(`[^\s"]+\.[0-9A-Za-z]{1,8}(\s|$)`)
+)
+
+// Parse a subject line into its parts, handling the conventions of common posting tools. The part counter is the
+// last counter of the subject, and a counter before the file name is taken as the file counter. ok is false if the
+// subject has neither a part counter nor the yEnc marker, i.e. it does not look like a binary post.
+func ParseSubject(line string) (s Subject, ok bool) {
+	line = strings.TrimSpace(line)
+	rest := line
+	// part counter
+	counters := subjectCounter.FindAllStringSubmatchIndex(rest, -1)
+	var after string
+	if len(counters) > 0 {
+		m := counters[len(counters)-1]
+		// a counter inside the quoted file name is part of the name
+		if q := subjectQuoted.FindStringIndex(rest); q == nil || m[0] >= q[1] || m[1] <= q[0] {
+			s.Part, _ = strconv.ParseUint(rest[m[2]:m[3]], 10, 64)
+			s.Total, _ = strconv.ParseUint(rest[m[4]:m[5]], 10, 64)
+			after = rest[m[1]:]
+			rest = rest[:m[0]]
+			ok = true
+		}
+	}
+	if m := subjectSize.FindStringSubmatchIndex(after); m != nil {
+		s.Size, _ = strconv.ParseUint(after[m[2]:m[3]], 10, 64)
+		after = after[m[1]:]
+	}
+	s.Suffix = strings.TrimSpace(after)
+	// yEnc marker, usually between the file name and the part counter
+	quoted := lastIndex(subjectQuoted.FindAllStringIndex(rest, -1))
+	markers := subjectYEnc.FindAllStringIndex(rest, -1)
+	for i := len(markers) - 1; i >= 0; i-- {
+		if m := markers[i]; quoted == nil || m[1] <= quoted[0] || m[0] >= quoted[1] {
+			s.YEnc = true
+			ok = true
+			rest = rest[:m[0]] + " " + rest[m[1]:]
+			break
+		}
+	}
+	// file name, quoted or the last token that looks like a file name
+	var prefix string
+	if m := subjectQuoted.FindAllStringSubmatchIndex(rest, -1); len(m) > 0 {
+		q := m[len(m)-1]
+		s.Name = strings.TrimSpace(rest[q[2]:q[3]])
+		prefix = rest[:q[0]]
+		if tail := strings.TrimSpace(rest[q[1]:]); tail != "" && tail != "-" {
+			s.Suffix = strings.TrimSpace(tail + " " + s.Suffix)
+		}
+	} else if m := subjectFileName.FindAllStringIndex(rest, -1); len(m) > 0 {
+		f := m[len(m)-1]
+		s.Name = strings.TrimSpace(rest[f[0]:f[1]])
+		prefix = rest[:f[0]]
+	} else {
+		prefix, s.Name = splitPrefix(rest)
+	}
+	// file counter before the file name
+	if m := subjectCounter.FindAllStringSubmatchIndex(prefix, -1); len(m) > 0 {
+		c := m[len(m)-1]
+		s.FileIndex, _ = strconv.ParseUint(prefix[c[2]:c[3]], 10, 64)
+		s.FileCount, _ = strconv.ParseUint(prefix[c[4]:c[5]], 10, 64)
+		prefix = prefix[:c[0]] + prefix[c[1]:]
+	} else if m := subjectFileOf.FindStringSubmatchIndex(prefix); m != nil {
+		s.FileIndex, _ = strconv.ParseUint(prefix[m[2]:m[3]], 10, 64)
+		s.FileCount, _ = strconv.ParseUint(prefix[m[4]:m[5]], 10, 64)
+		prefix = prefix[:m[0]] + prefix[m[1]:]
+	}
+	s.Prefix = trimSeparators(prefix)
+	return
+}
+
+func lastIndex(m [][]int) []int {
+	if len(m) == 0 {
+		return nil
+	}
+	return m[len(m)-1]
+}
+
+// Without a quoted name or a token with a file extension, the name is the text after the last " - " separator.
+func splitPrefix(rest string) (prefix, name string) {
+	rest = strings.TrimSpace(rest)
+	if i := strings.LastIndex(rest, " - "); i >= 0 {
+		return rest[:i], strings.TrimSpace(rest[i+3:])
+	}
+	return "", rest
+}
+
+func trimSeparators(s string) string {
+	s = strings.TrimSpace(s)
+	for {
+		t := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, "-"), "-"))
+		if t == s {
+			return s
+		}
+		s = t
+	}
+}
+
+// Format the subject the way yEnc 1.3 recommends, with the file counter Nyuu and ngPost add:
+//
+//	Prefix [03/10] - "Name" yEnc (Part/Total) Size Suffix
+//
+// Optional elements that are empty or zero are left out, except the part counter which formats as (1/1) if both Part
+// and Total are zero. The file index is zero padded to the width of the file count.
+func (s Subject) String() string {
+	var b strings.Builder
+	if s.Prefix != "" {
+		b.WriteString(s.Prefix)
+		b.WriteByte(' ')
+	}
+	if s.FileCount > 0 {
+		width := len(strconv.FormatUint(s.FileCount, 10))
+		fmt.Fprintf(&b, "[%0*d/%d] - ", width, s.FileIndex, s.FileCount)
+	}
+	fmt.Fprintf(&b, "\"%s\"", s.Name)
+	if s.YEnc {
+		b.WriteString(" yEnc")
+	}
+	part, total := s.Part, s.Total
+	if part == 0 && total == 0 {
+		part, total = 1, 1
+	}
+	fmt.Fprintf(&b, " (%d/%d)", part, total)
+	if s.Size > 0 {
+		fmt.Fprintf(&b, " %d", s.Size)
+	}
+	if s.Suffix != "" {
+		b.WriteByte(' ')
+		b.WriteString(s.Suffix)
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package yenc
+
+import "testing"
+
+func TestParseSubject(t *testing.T) {
+	for _, c := range []struct {
+		line   string
+		expect Subject
+	}{
+		// yEnc 1.3 recommendation
+		{`"encode-raw.bin" yEnc (1/10)`,
+			Subject{Name: "encode-raw.bin", Part: 1, Total: 10, YEnc: true}},
+		{`My Comment "encode-raw.bin" yEnc (1/10) 4682 more comment`,
+			Subject{Prefix: "My Comment", Name: "encode-raw.bin", Part: 1, Total: 10, YEnc: true, Size: 4682, Suffix: "more comment"}},
+		// Nyuu
+		{`[01/10] - "260731a73db67e8095a5eaf0b64b9d3db0117cdb" yEnc (3/120) 524288`,
+			Subject{Name: "260731a73db67e8095a5eaf0b64b9d3db0117cdb", FileIndex: 1, FileCount: 10, Part: 3, Total: 120, YEnc: true, Size: 524288}},
+		// ngPost
+		{`[1/2] "ngPost-raw.bin" yEnc (7/10)`,
+			Subject{Name: "ngPost-raw.bin", FileIndex: 1, FileCount: 2, Part: 7, Total: 10, YEnc: true}},
+		{`Collection - [02/15] - "ngPost-raw.bin" yEnc (10/10)`,
+			Subject{Prefix: "Collection", Name: "ngPost-raw.bin", FileIndex: 2, FileCount: 15, Part: 10, Total: 10, YEnc: true}},
+		// yenc32 and PowerPost, zero padded part numbers
+		{`yenc32 test - "yenc32-raw.bin" yEnc (01/10)`,
+			Subject{Prefix: "yenc32 test", Name: "yenc32-raw.bin", Part: 1, Total: 10, YEnc: true}},
+		{`(1/1) "YencPowerPost-raw.bin" - 4.57 kB - yEnc (1/1)`,
+			Subject{Name: "YencPowerPost-raw.bin", FileIndex: 1, FileCount: 1, Part: 1, Total: 1, YEnc: true, Suffix: "- 4.57 kB -"}},
+		// unquoted names
+		{`yEncBinPoster-raw.bin yEnc (1/1)`,
+			Subject{Name: "yEncBinPoster-raw.bin", Part: 1, Total: 1, YEnc: true}},
+		{`JBinUp-raw.bin (1/5)`,
+			Subject{Name: "JBinUp-raw.bin", Part: 1, Total: 5}},
+		{`Camelsystem post - File 3 of 4 - CamelsystemPowerpost-raw.bin yEnc [1/1]`,
+			Subject{Prefix: "Camelsystem post", Name: "CamelsystemPowerpost-raw.bin", FileIndex: 3, FileCount: 4, Part: 1, Total: 1, YEnc: true}},
+		// a counter inside the quoted name is not a part counter
+		{`"backup (1 of 2).rar" yEnc (2/3)`,
+			Subject{Name: "backup (1 of 2).rar", Part: 2, Total: 3, YEnc: true}},
+		{`"file [2/3].rar" yEnc`,
+			Subject{Name: "file [2/3].rar", YEnc: true}},
+		// description part numbered from 0
+		{`"info.nfo" yEnc (0/1)`,
+			Subject{Name: "info.nfo", Part: 0, Total: 1, YEnc: true}},
+	} {
+		s, ok := ParseSubject(c.line)
+		if !ok {
+			t.Errorf("%s: not recognized as binary post", c.line)
+		}
+		if s != c.expect {
+			t.Errorf("%s: expect\n%#v but got\n%#v", c.line, c.expect, s)
+		}
+	}
+}
+
+func TestParseSubjectNotBinary(t *testing.T) {
+	for _, line := range []string{"Re: looking for yenc32 documentation?", "Hello world", ""} {
+		if s, ok := ParseSubject(line); ok {
+			t.Errorf("%s: unexpectedly parsed as %#v", line, s)
+		}
+	}
+}
+
+func TestFormatSubject(t *testing.T) {
+	for _, c := range []struct {
+		s      Subject
+		expect string
+	}{
+		{Subject{Name: "file.bin", YEnc: true}, `"file.bin" yEnc (1/1)`},
+		{Subject{Name: "file.rar", FileIndex: 3, FileCount: 10, Part: 2, Total: 120, YEnc: true, Size: 1048576},
+			`[03/10] - "file.rar" yEnc (2/120) 1048576`},
+		{Subject{Prefix: "Holiday", Name: "file.bin", Part: 1, Total: 5, Suffix: "repost"}, `Holiday "file.bin" (1/5) repost`},
+	} {
+		if c.s.String() != c.expect {
+			t.Errorf("expect %s but got %s", c.expect, c.s.String())
+		}
+		s, ok := ParseSubject(c.s.String())
+		if ok && c.s.Total > 0 && s != c.s {
+			t.Errorf("%s: round trip mismatch %#v", c.expect, s)
+		}
+	}
+}
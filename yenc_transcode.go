@@ -0,0 +1,64 @@
+package yenc
+
+import (
+	"fmt"
+	"io"
+)
+
+// Re-encode one yEnc article from src into dst, e.g. with a different line length, EOL or critical characters, without
+// buffering its data. The header values of src are kept: the name as is, part, total, begin, end and line, unless
+// options set them, and the EOL defaults to CRLF. The trailer keeps the keywords of src and its pcrc32 and crc32
+// values, which are verified on the way through as Decode does. On error, dst may hold a partial article.
+//
+// Only a bare body is accepted, see TranscodeWithDecodeOptions to transcode e.g. an article with its headers.
+func Transcode(dst io.Writer, src io.Reader, options ...EncodeOption) (err error) {
+	return TranscodeWithDecodeOptions(dst, src, nil, options...)
+}
+
+// Transcode an article decoded with the given options, e.g. DecodeWithPrefixData to skip the headers of a fetched
+// article or DecodeWithCharsets for its name. The data before =ybegin is not written to dst.
+func TranscodeWithDecodeOptions(dst io.Writer, src io.Reader, decodeOptions []DecodeOption, options ...EncodeOption) (err error) {
+	var d *Decoder
+	if d, err = Decode(src, decodeOptions...); err != nil {
+		return
+	}
+	h := d.Header()
+	options = append([]EncodeOption{
+		EncodeWithEOL("\r\n"),
+		EncodeWithLineMax(h.Line),
+		EncodeWithNameCharset(rawName{h.Name, h.RawName}),
+		EncodeWithPart(h.Part, h.Total, h.Begin, h.End),
+	}, options...)
+	var e *Encoder
+	if e, err = Encode(dst, h.Name, h.Size, options...); err != nil {
+		return
+	}
+	if _, err = io.Copy(e, d); err != nil {
+		return
+	}
+	if e.trailer = d.Trailer(); e.trailer == nil {
+		err = fmt.Errorf("[yEnc] no =yend line: %w", ErrInvalidFormat)
+		return
+	}
+	return e.Close()
+}
+
+// Writes the raw name of a decoded header back as it was read.
+type rawName struct {
+	name, raw string
+}
+
+func (n rawName) Name() string {
+	return "raw"
+}
+
+func (n rawName) Decode(b []byte) (string, error) {
+	return UTF8.Decode(b)
+}
+
+func (n rawName) Encode(s string) ([]byte, error) {
+	if s == n.name {
+		return []byte(n.raw), nil
+	}
+	return []byte(s), nil
+}
@@ -0,0 +1,80 @@
+package yenc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTranscode(t *testing.T) {
+	for _, path := range []string{"fixture/yenc32-010.ntx", "fixture/YencPowerPost-001.ntx", "fixture/260731a73db67e8095a5eaf0b64b9d3db0117cdb@nyuu.ntx"} {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dst bytes.Buffer
+		if err = Transcode(&dst, bytes.NewReader(src), EncodeWithLF(), EncodeWithLineMax(64), EncodeWithCriticalChars(ExtendedCriticalChars)); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		for _, line := range strings.Split(dst.String(), "\n") {
+			if !strings.HasPrefix(line, "=y") && len(line) > 64 {
+				t.Fatalf("%s: expect lines of up to 64 characters but got %d", path, len(line))
+			}
+		}
+		h, tr, data := decodeAll(t, src)
+		th, ttr, tdata := decodeAll(t, dst.Bytes())
+		th.Line = h.Line
+		if *th != *h || *ttr != *tr || !bytes.Equal(tdata, data) {
+			t.Errorf("%s: expect the same header, trailer and data but got %+v %+v", path, th, ttr)
+		}
+	}
+}
+
+func TestTranscodeWithDecodeOptions(t *testing.T) {
+	body, err := os.ReadFile("fixture/yenc32-010.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := append([]byte("From: poster@example.com\r\nSubject: test\r\n\r\n"), body...)
+	if err = Transcode(io.Discard, bytes.NewReader(src)); !errors.Is(err, ErrRejectPrefixData) {
+		t.Errorf("expect ErrRejectPrefixData for an article with headers but got %v", err)
+	}
+	var dst bytes.Buffer
+	if err = TranscodeWithDecodeOptions(&dst, bytes.NewReader(src), []DecodeOption{DecodeWithPrefixData()}); err != nil {
+		t.Fatal(err)
+	}
+	h, tr, data := decodeAll(t, body)
+	th, ttr, tdata := decodeAll(t, dst.Bytes())
+	if *th != *h || *ttr != *tr || !bytes.Equal(tdata, data) {
+		t.Errorf("expect the same header, trailer and data but got %+v %+v", th, ttr)
+	}
+}
+
+func TestTranscodeCorrupt(t *testing.T) {
+	src, err := os.ReadFile("fixture/yenc32-010.ntx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src = bytes.Replace(src, []byte("crc32=5B0ACDC1"), []byte("crc32=5B0ACDC2"), 1)
+	if err = Transcode(io.Discard, bytes.NewReader(src)); err != nil {
+		t.Errorf("expect the crc32 of a part to be copied as is but got %v", err)
+	}
+	src = bytes.Replace(src, []byte("pcrc32=3CED5D52"), []byte("pcrc32=3CED5D53"), 1)
+	if err = Transcode(io.Discard, bytes.NewReader(src)); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expect a pcrc32 mismatch but got %v", err)
+	}
+}
+
+func decodeAll(t *testing.T, article []byte) (h *Header, tr *Trailer, data []byte) {
+	d, err := Decode(bytes.NewReader(article))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err = io.ReadAll(d); err != nil {
+		t.Fatal(err)
+	}
+	return d.Header(), d.Trailer(), data
+}